@@ -0,0 +1,242 @@
+package main
+
+// This file contains the commands running the stages of the toolchain: asm,
+// vm, jack and build.
+
+import (
+	"fmt"
+	"log"
+	"nand2tetris/vm-translator/translator"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func runAsm(args []string) error {
+	fs, verbose := newFlagSet("asm")
+	var outPath string
+	stringFlag(fs, &outPath, "out", "o", "", "Machine language output")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleArg(fs)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(inPath) != ".asm" {
+		return usagef("Hack assembly file (.asm) expected")
+	}
+	if len(outPath) == 0 {
+		outPath = strings.TrimSuffix(inPath, ".asm") + ".hack"
+	}
+
+	asm, err := os.ReadFile(inPath)
+	if err != nil {
+		return err
+	}
+	code, err := assemble(asm, *verbose)
+	if err != nil {
+		return fmt.Errorf("%s: %w", inPath, err)
+	}
+	if err := writeHack(outPath, code, *verbose); err != nil {
+		return err
+	}
+
+	log.Printf("[i] Assembly output to %q successful", outPath)
+	return nil
+}
+
+func runVM(args []string) error {
+	fs, verbose := newFlagSet("vm")
+	var outPath string
+	var annotate, checked, prune bool
+	stringFlag(fs, &outPath, "out", "o", "", "Assembly output")
+	boolFlag(fs, &annotate, "annotate", "a", "Annotate output with VM source")
+	boolFlag(fs, &checked, "checked", "c", "Guard the stack and pointers at run time")
+	boolFlag(fs, &prune, "prune", "p", "Drop the functions no call reaches")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleArg(fs)
+	if err != nil {
+		return err
+	}
+	inPaths, base, isDir, err := resolveSources(filepath.Clean(inPath), ".vm")
+	if err != nil {
+		return err
+	}
+	if len(outPath) == 0 {
+		outPath = base + ".asm"
+	}
+
+	files, err := readFiles(inPaths)
+	if err != nil {
+		return err
+	}
+	asm, err := translateVM(files, translator.Options{Annotate: annotate, Bootstrap: isDir, Checked: checked, Prune: prune}, *verbose)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(outPath, asm, *verbose); err != nil {
+		return err
+	}
+
+	log.Printf("[i] Translator output %q successful", outPath)
+	return nil
+}
+
+func runJack(args []string) error {
+	fs, verbose := newFlagSet("jack")
+	var outDir string
+	var noCheck bool
+	stringFlag(fs, &outDir, "out", "o", "", "Directory of the VM output")
+	boolFlag(fs, &noCheck, "no-check", "n", "Skips the semantic checks")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleArg(fs)
+	if err != nil {
+		return err
+	}
+	inPaths, _, _, err := resolveSources(filepath.Clean(inPath), ".jack")
+	if err != nil {
+		return err
+	}
+	if len(outDir) == 0 {
+		outDir = filepath.Dir(inPaths[0])
+	}
+
+	files, err := compileJack(inPaths, noCheck, *verbose)
+	if err != nil {
+		return err
+	}
+	for name, code := range files {
+		if err := writeFile(filepath.Join(outDir, name), code, *verbose); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[i] %d class(es) compiled to %q", len(files), outDir)
+	return nil
+}
+
+func runBuild(args []string) error {
+	fs, verbose := newFlagSet("build")
+	var outPath string
+	var noCheck, keep, checked, prune bool
+	stringFlag(fs, &outPath, "out", "o", "", "Machine language output")
+	boolFlag(fs, &noCheck, "no-check", "n", "Skips the semantic checks")
+	boolFlag(fs, &keep, "keep", "k", "Keeps the intermediate files")
+	boolFlag(fs, &checked, "checked", "c", "Guard the stack and pointers at run time")
+	boolFlag(fs, &prune, "prune", "p", "Drop the functions no call reaches")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleArg(fs)
+	if err != nil {
+		return err
+	}
+	inPath = filepath.Clean(inPath)
+	info, err := os.Stat(inPath)
+	if err != nil {
+		return err
+	}
+
+	var jackPaths, vmPaths []string
+	var asm []byte
+	base := strings.TrimSuffix(inPath, filepath.Ext(inPath))
+
+	switch {
+	case info.IsDir():
+		jackPaths, vmPaths = listFiles(inPath, ".jack"), listFiles(inPath, ".vm")
+		if len(jackPaths) == 0 && len(vmPaths) == 0 {
+			return fmt.Errorf("no Jack (.jack) or VM (.vm) files found in %q", inPath)
+		}
+		base = filepath.Join(inPath, filepath.Base(inPath))
+	case filepath.Ext(inPath) == ".jack":
+		jackPaths = []string{inPath}
+	case filepath.Ext(inPath) == ".vm":
+		vmPaths = []string{inPath}
+	case filepath.Ext(inPath) == ".asm":
+		if asm, err = os.ReadFile(inPath); err != nil {
+			return err
+		}
+	default:
+		return usagef("Jack (.jack), VM (.vm) or assembly (.asm) file, or directory expected")
+	}
+	if len(outPath) == 0 {
+		outPath = base + ".hack"
+	}
+
+	vmFiles, err := readFiles(vmPaths)
+	if err != nil {
+		return err
+	}
+
+	if len(jackPaths) != 0 {
+		compiled, err := compileJack(jackPaths, noCheck, *verbose)
+		if err != nil {
+			return err
+		}
+		for name, code := range compiled {
+			vmFiles[name] = code
+			if keep {
+				path := filepath.Join(filepath.Dir(jackPaths[0]), name)
+				if err := writeFile(path, code, *verbose); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if asm == nil {
+		asm, err = translateVM(vmFiles, translator.Options{Bootstrap: info.IsDir(), Checked: checked, Prune: prune}, *verbose)
+		if err != nil {
+			return err
+		}
+		if keep {
+			if err := writeFile(base+".asm", asm, *verbose); err != nil {
+				return err
+			}
+		}
+	}
+
+	code, err := assemble(asm, *verbose)
+	if err != nil {
+		return err
+	}
+	if err := writeHack(outPath, code, *verbose); err != nil {
+		return err
+	}
+
+	log.Printf("[i] Build output to %q successful", outPath)
+	return nil
+}
+
+// Returns the files with extension ext of inPath, a file or a directory, and
+// the output path without extension: "Xxx" for "Xxx.ext", and "Xxx/Xxx" for
+// a directory "Xxx"
+func resolveSources(inPath string, ext string) ([]string, string, bool, error) {
+	info, err := os.Stat(inPath)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	if !info.IsDir() {
+		if filepath.Ext(inPath) != ext {
+			return nil, "", false, usagef("%s file or directory expected", ext)
+		}
+		return []string{inPath}, strings.TrimSuffix(inPath, ext), false, nil
+	}
+
+	inPaths := listFiles(inPath, ext)
+	if len(inPaths) == 0 {
+		return nil, "", false, fmt.Errorf("no %s files found in %q", ext, inPath)
+	}
+
+	return inPaths, filepath.Join(inPath, filepath.Base(inPath)), true, nil
+}
@@ -0,0 +1,203 @@
+package main
+
+// This file defines the help messages and exit codes
+
+const (
+	exitOK    = 0 // Success, or help shown
+	exitError = 1 // Invalid input, or failed tests
+	exitUsage = 2 // Invalid flags or arguments
+
+	defaultCycles = 1000000 // Instructions run by "n2t run" before giving up
+
+	helpMsg = `Nand2Tetris Toolchain
+Usage:
+	n2t COMMAND [-h/--help] [FLAGS] ARGS
+
+Commands:
+	asm                  Assembles Hack assembly (.asm) to machine language
+	                     (.hack).
+	vm                   Translates Hack VM code (.vm) to assembly (.asm).
+	jack                 Compiles Jack classes (.jack) to VM code (.vm).
+	build                Takes Jack classes, VM code or assembly through every
+	                     stage down to machine language (.hack).
+	run                  Runs a Hack program (.hack or .asm) on the emulated
+	                     computer, and prints its RAM.
+	test                 Runs test scripts (.tst) of HDL chips, and test specs
+	                     (.json) of Hack programs.
+
+	Run "n2t COMMAND --help" for the flags and arguments of a command.
+
+Flags:
+	-h/--help            Shows the help message of n2t, or of a command, and
+	                     exits.
+	-v/--verbose         Logs every stage. Taken by every command.
+	                     (Default: off)
+	-o/--out PATH        Output file, or directory for "jack". Taken by the
+	                     commands writing files. (Default: next to the input,
+	                     as the single-stage tools do)
+
+Description:
+	n2t drives the Go Nand2Tetris tools: the assembler (project 6), the VM
+	translator (projects 7 and 8), the Jack compiler (projects 10 and 11), the
+	Hack computer emulator and the HDL test script runner.
+
+	Exits with status 0 on success, 1 if the input has errors or tests fail,
+	and 2 if the command, flags or arguments are invalid.
+`
+
+	asmHelpMsg = `Usage:
+	n2t asm [-h/--help] [-v/--verbose] [-o/--out FILE] ASSEMBLY
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-v/--verbose         Logs every stage. (Default: off)
+	-o/--out FILE        Machine language output. (Default: "Xxx.hack" next to
+	                     "Xxx.asm")
+
+Positional Argument:
+	ASSEMBLY             File containing Hack assembly code (.asm). Required.
+`
+
+	vmHelpMsg = `Usage:
+	n2t vm [-h/--help] [-v/--verbose] [-a/--annotate] [-c/--checked]
+	       [-p/--prune] [-o/--out FILE] BYTECODE
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-v/--verbose         Logs every stage. (Default: off)
+	-a/--annotate        Precedes every translated block with a comment holding
+	                     its VM instruction and source position. (Default: off)
+	-c/--checked         Guards every instruction at run time: popping below
+	                     the stack, pushing into the heap, or accessing this or
+	                     that with a null pointer halts with an error code in
+	                     RAM[254] and the VM line in RAM[255]. (Default: off)
+	-p/--prune           Drops the functions no call can reach from Sys.init,
+	                     or from the first VM instruction of a file, and lists
+	                     them. (Default: off)
+	-o/--out FILE        Assembly output. (Default: "Xxx.asm" next to "Xxx.vm",
+	                     or "Xxx/Xxx.asm" for a directory "Xxx")
+
+Positional Argument:
+	BYTECODE             File containing Hack VM code (.vm), or a directory of
+	                     such files. A directory is a whole program, started by
+	                     bootstrap code calling Sys.init. Required.
+`
+
+	jackHelpMsg = `Usage:
+	n2t jack [-h/--help] [-v/--verbose] [-n/--no-check] [-o/--out DIR] SOURCE
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-v/--verbose         Logs every stage. (Default: off)
+	-n/--no-check        Skips the semantic checks. (Default: off)
+	-o/--out DIR         Directory of the VM output. (Default: the directory of
+	                     the classes)
+
+Positional Argument:
+	SOURCE               File containing a Jack class (.jack), or a directory of
+	                     such files, checked together. Required.
+`
+
+	buildHelpMsg = `Usage:
+	n2t build [-h/--help] [-v/--verbose] [-n/--no-check] [-c/--checked]
+	          [-p/--prune] [-k/--keep] [-o/--out FILE] SOURCE
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-v/--verbose         Logs every stage. (Default: off)
+	-n/--no-check        Skips the semantic checks of Jack classes.
+	                     (Default: off)
+	-c/--checked         Guards every VM instruction at run time: popping
+	                     below the stack, pushing into the heap, or accessing
+	                     this or that with a null pointer halts with an error
+	                     code in RAM[254] and the VM line in RAM[255].
+	                     (Default: off)
+	-p/--prune           Drops the functions no call can reach from Sys.init,
+	                     or from the first instruction of a VM file, and lists
+	                     them. (Default: off)
+	-k/--keep            Keeps the intermediate files, the VM code (.vm) of
+	                     every Jack class and the assembly (.asm), written where
+	                     "n2t jack" and "n2t vm" write them. (Default: off)
+	-o/--out FILE        Machine language output. (Default: "Xxx.hack" next to
+	                     "Xxx.jack", "Xxx.vm" or "Xxx.asm", or "Xxx/Xxx.hack"
+	                     for a directory "Xxx")
+
+Positional Argument:
+	SOURCE               Jack class (.jack), VM code (.vm) or assembly (.asm)
+	                     file, or a directory of Jack classes and VM files.
+	                     The VM files of a directory, e.g. the OS classes, are
+	                     translated along with the compiled classes; those of
+	                     the same name as a Jack class are replaced by it. A
+	                     directory is a whole program, started by Sys.init.
+	                     Required.
+`
+
+	runHelpMsg = `Usage:
+	n2t run [-h/--help] [-v/--verbose] [-c/--cycles N] [-r/--ram ADR=VAL,...]
+	        [-b/--keyboard KEY] [-d/--dump ADRS] PROGRAM
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-v/--verbose         Logs every stage. (Default: off)
+	-c/--cycles N        Instructions run at most, 0 for no limit.
+	                     (Default: 1000000)
+	-r/--ram ADR=VAL,... Comma-separated RAM values set before running, e.g.
+	                     "0=6,1=7". (Default: none)
+	-b/--keyboard KEY    Code of the key held down while running. (Default: 0,
+	                     no key)
+	-d/--dump ADRS       Comma-separated addresses and ranges printed after
+	                     running, e.g. "0-2,256". (Default: "0-15")
+
+Positional Argument:
+	PROGRAM              Hack program, in machine language (.hack) or assembly
+	                     (.asm). Required.
+
+Description:
+	Runs until the program halts, at the loop ending Hack programs
+	("(END) @END 0;JMP") or past its last instruction, or for the given number
+	of cycles. Values are printed signed.
+`
+
+	testHelpMsg = `Usage:
+	n2t test [-h/--help] [-v/--verbose] [-p/--path DIRS] SCRIPT...
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-v/--verbose         Logs the messages of "echo" commands. (Default: off)
+	-p/--path DIRS       Comma-separated directories searched for the parts of
+	                     the tested chips, after the directory of each script,
+	                     e.g. "../project2,../project1". Parts not found fall back
+	                     to the built-in chips. (Default: none)
+
+Positional Argument:
+	SCRIPT               Test script (.tst) of an HDL chip in the Nand2Tetris
+	                     format, or test spec (.json) of a Hack program. At
+	                     least one is required.
+
+Description:
+	Writes each script's output file (.out) next to it, and compares it with
+	the script's comparison file (.cmp). Exits with status 1 if any script
+	fails.
+
+	A test spec runs a Hack program, in assembly (.asm) or machine language
+	(.hack), once per case, from the RAM values and with the keys of the case.
+	Each case runs until the program halts, at the loop ending Hack programs,
+	or for its cycles, then checks the RAM and screen words expected:
+
+	{
+	  "program": "Mult.asm",
+	  "cycles": 20000,
+	  "cases": [
+	    {"name": "6*7", "ram": {"0": 6, "1": 7}, "halts": true,
+	     "expect": {"ram": {"2": 42}}},
+	    {"name": "black", "keyboard": [{"cycle": 0, "key": 75}],
+	     "expect": {"screen": [{"from": 0, "to": 8191, "value": -1}]}}
+	  ]
+	}
+
+	"cycles" can be set per case, and "halts" fails a case that doesn't halt.
+	Screen words are counted from 0, the first one at RAM[16384], and "to"
+	defaults to "from". The keyboard, RAM[24576], is only set by "keyboard".
+	Specs are read as JSON only: there is no YAML support.
+`
+)
@@ -0,0 +1,19 @@
+module nand2tetris/n2t
+
+go 1.18
+
+require (
+	nand2tetris/hack-assembler v0.0.0
+	nand2tetris/hack-emulator v0.0.0
+	nand2tetris/hdl-tools v0.0.0
+	nand2tetris/jack-compiler v0.0.0
+	nand2tetris/vm-translator v0.0.0
+)
+
+replace (
+	nand2tetris/hack-assembler => ../part1/project6/hack-assembler
+	nand2tetris/hack-emulator => ../part1/project5/hack-emulator
+	nand2tetris/hdl-tools => ../part1/hdl-tools
+	nand2tetris/jack-compiler => ../part2/project10/jack-compiler
+	nand2tetris/vm-translator => ../part2/project7/vm-translator
+)
@@ -0,0 +1,305 @@
+// Package hacktest runs declarative tests of Hack programs: JSON specs giving
+// the RAM to start from, the keys pressed, and the RAM and screen expected
+// once the program halts or has run for long enough. Specs are JSON only, as
+// the toolchain keeps to the standard library, which has no YAML decoder.
+//
+// A spec looks like:
+//
+//	{
+//	  "program": "Mult.asm",
+//	  "cycles": 10000,
+//	  "cases": [
+//	    {"name": "6*7", "ram": {"0": 6, "1": 7}, "halts": true,
+//	     "expect": {"ram": {"2": 42}}},
+//	    {"name": "black", "keyboard": [{"cycle": 0, "key": 75}],
+//	     "expect": {"screen": [{"from": 0, "to": 8191, "value": -1}]}}
+//	  ]
+//	}
+package hacktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"nand2tetris/hack-assembler/assembler"
+	"nand2tetris/hack-emulator/cpu"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// A test spec: cases run on one program
+type Spec struct {
+	// The program, in assembly (.asm) or machine language (.hack), relative
+	// to the spec
+	Program string `json:"program"`
+	Cycles  int    `json:"cycles"` // Cycles run by the cases that don't set theirs
+	Cases   []Case `json:"cases"`
+
+	dir string // Directory of the spec
+}
+
+// A test case: the program is run from a fresh computer
+type Case struct {
+	Name     string         `json:"name"`
+	RAM      map[string]int `json:"ram"` // Values set before running, by address, the keyboard's excepted
+	Cycles   int            `json:"cycles"`
+	Keyboard []KeyEvent     `json:"keyboard"`
+	Halts    bool           `json:"halts"` // The program has to halt within the cycles
+	Expect   Expect         `json:"expect"`
+}
+
+// A key pressed, or released with key 0, at the given cycle
+type KeyEvent struct {
+	Cycle int `json:"cycle"`
+	Key   int `json:"key"`
+}
+
+// The words expected after running
+type Expect struct {
+	RAM    map[string]int `json:"ram"` // Values by address
+	Screen []ScreenRange  `json:"screen"`
+}
+
+// Words of the screen, counted from its first one at 16384, holding value
+type ScreenRange struct {
+	From  int  `json:"from"`
+	To    *int `json:"to"` // Last word, From if omitted
+	Value int  `json:"value"`
+}
+
+// Returns the last word of the range
+func (r ScreenRange) last() int {
+	if r.To == nil {
+		return r.From
+	}
+
+	return *r.To
+}
+
+// The outcome of a case
+type Result struct {
+	Case     string
+	Cycles   int // Cycles run
+	Halted   bool
+	Failures []string // One line per failed check, none if the case passed
+}
+
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+func (r Result) String() string {
+	if r.Passed() {
+		return fmt.Sprintf("%s: passed after %d cycle(s)", r.Case, r.Cycles)
+	}
+
+	return fmt.Sprintf("%s: failed after %d cycle(s): %s", r.Case, r.Cycles, r.Failures[0])
+}
+
+// Reads and checks the spec at path
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Spec
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	s.dir = filepath.Dir(path)
+
+	if err := s.check(); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return &s, nil
+}
+
+// Checks the addresses and values of the spec
+func (s *Spec) check() error {
+	if s.Program == "" {
+		return fmt.Errorf("no program")
+	}
+	if len(s.Cases) == 0 {
+		return fmt.Errorf("no case")
+	}
+
+	for i, c := range s.Cases {
+		if c.Name == "" {
+			return fmt.Errorf("case %d: no name", i+1)
+		}
+		if c.Cycles <= 0 && s.Cycles <= 0 {
+			return fmt.Errorf("%s: no cycle limit", c.Name)
+		}
+
+		for i, m := range []map[string]int{c.RAM, c.Expect.RAM} {
+			for adr, v := range m {
+				a, err := parseAddress(adr)
+				if err != nil {
+					return fmt.Errorf("%s: %s", c.Name, err)
+				}
+				// The keyboard is read-only for the program, as in the
+				// hardware: it has to be set with key events
+				if i == 0 && a == cpu.KbdAdr {
+					return fmt.Errorf("%s: RAM[%d] is the keyboard, set it with \"keyboard\"", c.Name, a)
+				}
+				if err := checkValue(v); err != nil {
+					return fmt.Errorf("%s: RAM[%s]: %s", c.Name, adr, err)
+				}
+			}
+		}
+		for _, e := range c.Keyboard {
+			if e.Cycle < 0 || e.Key < 0 || e.Key > 0xFFFF {
+				return fmt.Errorf("%s: invalid key %d at cycle %d", c.Name, e.Key, e.Cycle)
+			}
+		}
+		for _, r := range c.Expect.Screen {
+			if r.From < 0 || r.From > r.last() || r.last() >= cpu.KbdAdr-cpu.ScreenAdr {
+				return fmt.Errorf("%s: invalid screen range %d-%d", c.Name, r.From, r.last())
+			}
+			if err := checkValue(r.Value); err != nil {
+				return fmt.Errorf("%s: screen: %s", c.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseAddress(adr string) (uint16, error) {
+	a, err := strconv.ParseUint(adr, 10, 16)
+	if err != nil || a >= cpu.RAMSize {
+		return 0, fmt.Errorf("invalid address %q", adr)
+	}
+
+	return uint16(a), nil
+}
+
+// Values are signed or unsigned 16-bit words
+func checkValue(v int) error {
+	if v < -32768 || v > 65535 {
+		return fmt.Errorf("value %d out of 16 bits", v)
+	}
+
+	return nil
+}
+
+// Assembles or loads the program, and runs every case. Returns an error if
+// the program can't be loaded.
+func (s *Spec) Run() ([]Result, error) {
+	program, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, c := range s.Cases {
+		results = append(results, s.run(program, c))
+	}
+
+	return results, nil
+}
+
+// Returns the machine language of the program
+func (s *Spec) load() ([]uint16, error) {
+	path := filepath.Join(s.dir, s.Program)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filepath.Ext(path) {
+	case ".hack":
+		return cpu.Load(bytes.NewReader(data))
+	case ".asm":
+		code, err := assembler.Assemble(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		return cpu.Parse(code)
+	}
+
+	return nil, fmt.Errorf("%s: Hack program (.hack or .asm) expected", path)
+}
+
+// Runs a case on a fresh computer
+func (s *Spec) run(program []uint16, tc Case) Result {
+	res := Result{Case: tc.Name}
+
+	c, err := cpu.New(program)
+	if err != nil {
+		res.Failures = append(res.Failures, err.Error())
+		return res
+	}
+	for adr, v := range tc.RAM {
+		a, _ := parseAddress(adr)
+		c.Write(a, uint16(v))
+	}
+
+	cycles := tc.Cycles
+	if cycles <= 0 {
+		cycles = s.Cycles
+	}
+
+	events := append([]KeyEvent(nil), tc.Keyboard...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Cycle < events[j].Cycle
+	})
+
+	// Runs up to each key event, unless the program halts first
+	for _, e := range events {
+		if e.Cycle >= cycles {
+			break
+		}
+		if n := e.Cycle - c.Cycles; n > 0 {
+			if res.Halted = c.Run(n); res.Halted {
+				break
+			}
+		}
+		c.SetKey(uint16(e.Key))
+	}
+	if !res.Halted {
+		if n := cycles - c.Cycles; n > 0 {
+			res.Halted = c.Run(n)
+		} else {
+			res.Halted = c.Halted()
+		}
+	}
+	res.Cycles = c.Cycles
+
+	if tc.Halts && !res.Halted {
+		res.Failures = append(res.Failures, fmt.Sprintf("no halt within %d cycles", cycles))
+	}
+
+	adrs := make([]string, 0, len(tc.Expect.RAM))
+	for adr := range tc.Expect.RAM {
+		adrs = append(adrs, adr)
+	}
+	sort.Slice(adrs, func(i, j int) bool {
+		a, _ := parseAddress(adrs[i])
+		b, _ := parseAddress(adrs[j])
+		return a < b
+	})
+	for _, adr := range adrs {
+		a, _ := parseAddress(adr)
+		if got, want := c.Read(a), uint16(tc.Expect.RAM[adr]); got != want {
+			res.Failures = append(res.Failures, fmt.Sprintf("RAM[%d] = %d, want %d", a, int16(got), int16(want)))
+		}
+	}
+
+	for _, r := range tc.Expect.Screen {
+		for i := r.From; i <= r.last(); i++ {
+			if got, want := c.Read(uint16(cpu.ScreenAdr+i)), uint16(r.Value); got != want {
+				res.Failures = append(res.Failures, fmt.Sprintf("screen word %d (RAM[%d]) = %d, want %d", i, cpu.ScreenAdr+i, int16(got), int16(want)))
+				break
+			}
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,94 @@
+package hacktest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// The project 4 programs pass their specs
+func TestProject4(t *testing.T) {
+	for _, name := range []string{"Mult", "Fill"} {
+		t.Run(name, func(t *testing.T) {
+			Test(t, filepath.Join("..", "..", "part1", "project4", name+".json"))
+		})
+	}
+}
+
+func writeSpec(t *testing.T, spec string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	src := "@R0\nD=M\n@R1\nM=D\n(END)\n@END\n0;JMP\n"
+	if err := os.WriteFile(filepath.Join(dir, "Copy.asm"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "Copy.json")
+	if err := os.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestRun(t *testing.T) {
+	path := writeSpec(t, `{"program": "Copy.asm", "cycles": 100, "cases": [
+		{"name": "copy", "ram": {"0": -5}, "halts": true, "expect": {"ram": {"1": -5}}},
+		{"name": "wrong", "ram": {"0": 3}, "expect": {"ram": {"1": 4, "0": 2}, "screen": [{"from": 2, "value": 1}]}},
+		{"name": "too short", "cycles": 3, "halts": true}
+	]}`)
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Result{
+		{Case: "copy", Cycles: 4, Halted: true},
+		{Case: "wrong", Cycles: 4, Halted: true, Failures: []string{
+			"RAM[0] = 3, want 2",
+			"RAM[1] = 3, want 4",
+			"screen word 2 (RAM[16386]) = 0, want 1",
+		}},
+		{Case: "too short", Cycles: 3, Failures: []string{"no halt within 3 cycles"}},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Run() = %#v, want %#v", results, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []string{
+		`{"program": "Copy.asm", "cycles": 10}`,
+		`{"cycles": 10, "cases": [{"name": "a"}]}`,
+		`{"program": "Copy.asm", "cases": [{"name": "a"}]}`,
+		`{"program": "Copy.asm", "cycles": 10, "cases": [{}]}`,
+		`{"program": "Copy.asm", "cycles": 10, "cases": [{"name": "a", "ram": {"x": 1}}]}`,
+		`{"program": "Copy.asm", "cycles": 10, "cases": [{"name": "a", "ram": {"24577": 1}}]}`,
+		`{"program": "Copy.asm", "cycles": 10, "cases": [{"name": "a", "expect": {"ram": {"0": 65536}}}]}`,
+		`{"program": "Copy.asm", "cycles": 10, "cases": [{"name": "a", "ram": {"24576": 1}}]}`,
+		`{"program": "Copy.asm", "cycles": 10, "cases": [{"name": "a", "expect": {"screen": [{"from": 8192}]}}]}`,
+		`{"program": "Copy.asm", "cycles": 10, "cases": [{"name": "a", "expect": {"screen": [{"from": 5, "to": 0}]}}]}`,
+		`{"program": "Copy.asm", "cycles": 10, "cases": [{"name": "a", "keyboard": [{"cycle": -1}]}]}`,
+		`{"program": "Copy.asm", "cycles": 10, "cases": [{"name": "a", "expected": {}}]}`,
+	}
+
+	for _, spec := range tests {
+		if _, err := Load(writeSpec(t, spec)); err == nil {
+			t.Errorf("Load(%s) returned no error", spec)
+		}
+	}
+
+	s, err := Load(writeSpec(t, `{"program": "Missing.asm", "cycles": 10, "cases": [{"name": "a"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Run(); err == nil {
+		t.Error("Run() of a missing program returned no error")
+	}
+}
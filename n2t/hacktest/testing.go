@@ -0,0 +1,33 @@
+package hacktest
+
+// This file contains the go test integration.
+
+import "testing"
+
+// Runs the spec at path as a subtest per case, failing them on every failed
+// check. Usable from any test, e.g.
+//
+//	func TestMult(t *testing.T) {
+//		hacktest.Test(t, "Mult.json")
+//	}
+func Test(t *testing.T, path string) {
+	t.Helper()
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, res := range results {
+		res := res
+		t.Run(res.Case, func(t *testing.T) {
+			for _, failure := range res.Failures {
+				t.Error(failure)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+// This file contains the command dispatch, and the flag and error handling
+// shared by the commands.
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+type command struct {
+	help string
+	run  func(args []string) error
+}
+
+var commands = map[string]command{
+	"asm":   {asmHelpMsg, runAsm},
+	"vm":    {vmHelpMsg, runVM},
+	"jack":  {jackHelpMsg, runJack},
+	"build": {buildHelpMsg, runBuild},
+	"run":   {runHelpMsg, runRun},
+	"test":  {testHelpMsg, runTest},
+}
+
+// Returned for invalid arguments, to exit with exitUsage after the help
+// message
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, a ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, a...)}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// Runs the command in args, and returns the exit code
+func run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, helpMsg)
+		return exitUsage
+	}
+
+	name := args[0]
+	if name == "-h" || name == "--help" || name == "help" {
+		fmt.Fprint(os.Stderr, helpMsg)
+		return exitOK
+	}
+
+	cmd, found := commands[name]
+	if !found {
+		log.Printf("[!] Error: unknown command %q", name)
+		fmt.Fprint(os.Stderr, helpMsg)
+		return exitUsage
+	}
+
+	err := cmd.run(args[1:])
+
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		fmt.Fprint(os.Stderr, cmd.help)
+		return exitOK
+	case errors.As(err, &usageErr):
+		log.Printf("[!] Error: %s", err)
+		fmt.Fprint(os.Stderr, cmd.help)
+		return exitUsage
+	}
+
+	log.Printf("[!] Error: %s", err)
+	return exitError
+}
+
+// Returns the flag set of a command, with its -v/--verbose flag
+func newFlagSet(name string) (*flag.FlagSet, *bool) {
+	fs := flag.NewFlagSet("n2t "+name, flag.ContinueOnError)
+	// Errors are reported with the help message of the command
+	fs.SetOutput(io.Discard)
+
+	verbose := new(bool)
+	boolFlag(fs, verbose, "verbose", "v", "Logs every stage")
+
+	return fs, verbose
+}
+
+// Parses the flags of a command. Returns flag.ErrHelp for -h/--help, or a
+// *usageError.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+
+	return &usageError{msg: err.Error()}
+}
+
+// Registers a flag under its long and short names
+func boolFlag(fs *flag.FlagSet, p *bool, long string, short string, usage string) {
+	fs.BoolVar(p, long, false, usage)
+	fs.BoolVar(p, short, false, usage)
+}
+
+func stringFlag(fs *flag.FlagSet, p *string, long string, short string, value string, usage string) {
+	fs.StringVar(p, long, value, usage)
+	fs.StringVar(p, short, value, usage)
+}
+
+func intFlag(fs *flag.FlagSet, p *int, long string, short string, value int, usage string) {
+	fs.IntVar(p, long, value, usage)
+	fs.IntVar(p, short, value, usage)
+}
+
+// Returns the single positional argument of a command
+func singleArg(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() != 1 {
+		return "", usagef("expected 1 argument, got %d", fs.NArg())
+	}
+
+	return fs.Arg(0), nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"nand2tetris/hack-emulator/cpu"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Copies the files of testdata/name to a temporary directory named name
+func copyTestdata(t *testing.T, name string) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), name)
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, _ := filepath.Glob(filepath.Join("testdata", name, "*"))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(path)), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func runHack(t *testing.T, path string, cycles int) *cpu.Computer {
+	t.Helper()
+
+	program, err := loadProgram(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := cpu.New(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Run(cycles)
+
+	return c
+}
+
+func TestBuild(t *testing.T) {
+	for _, flags := range [][]string{nil, {"--keep"}, {"--checked"}, {"--prune"}} {
+		dir := copyTestdata(t, "Fact")
+		keep := len(flags) != 0 && flags[0] == "--keep"
+
+		args := append(append([]string{"build"}, flags...), dir)
+		if code := run(args); code != exitOK {
+			t.Fatalf("%v exited with %d", args, code)
+		}
+
+		c := runHack(t, filepath.Join(dir, "Fact.hack"), 100000)
+		if c.RAM[8000] != 120 || c.RAM[8001] != 720 {
+			t.Errorf("%v: RAM[8000-8001] = %d %d, want 120 720", flags, c.RAM[8000], c.RAM[8001])
+		}
+		// No check of checked code fails
+		if c.RAM[254] != 0 {
+			t.Errorf("%v: trap %d at VM line %d", flags, c.RAM[254], c.RAM[255])
+		}
+
+		// The intermediate files are only written with --keep
+		for _, name := range []string{"Main.vm", "Math.vm", "Fact.asm"} {
+			_, err := os.Stat(filepath.Join(dir, name))
+			if kept := err == nil; kept != keep {
+				t.Errorf("keep %v: %s kept = %v", keep, name, kept)
+			}
+		}
+	}
+}
+
+// Runs the stages one by one, as build does
+func TestStages(t *testing.T) {
+	dir := copyTestdata(t, "Fact")
+
+	for _, args := range [][]string{
+		{"jack", dir},
+		{"vm", dir},
+		{"asm", "-o", filepath.Join(dir, "Out.hack"), filepath.Join(dir, "Fact.asm")},
+	} {
+		if code := run(args); code != exitOK {
+			t.Fatalf("%v exited with %d", args, code)
+		}
+	}
+
+	c := runHack(t, filepath.Join(dir, "Out.hack"), 100000)
+	if c.RAM[8000] != 120 {
+		t.Errorf("RAM[8000] = %d, want 120", c.RAM[8000])
+	}
+}
+
+// Prunes a function no call reaches
+func TestVMPrune(t *testing.T) {
+	dir := copyTestdata(t, "Fact")
+	unused := "function Sys.unused 0\npush constant 1\nreturn\n"
+	if err := os.WriteFile(filepath.Join(dir, "Unused.vm"), []byte(unused), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"jack", dir}); code != exitOK {
+		t.Fatalf("jack exited with %d", code)
+	}
+
+	for _, prune := range []bool{false, true} {
+		args := []string{"vm", dir}
+		if prune {
+			args = []string{"vm", "--prune", dir}
+		}
+		if code := run(args); code != exitOK {
+			t.Fatalf("%v exited with %d", args, code)
+		}
+
+		asm, err := os.ReadFile(filepath.Join(dir, "Fact.asm"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kept := strings.Contains(string(asm), "(Sys.unused)"); kept == prune {
+			t.Errorf("prune %v: Sys.unused kept = %v", prune, kept)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	dir := copyTestdata(t, "Fact")
+	bad := filepath.Join(dir, "Bad.asm")
+	os.WriteFile(bad, []byte("D=D*A\n"), 0644)
+	failing := filepath.Join(dir, "Failing.json")
+	os.WriteFile(failing, []byte(`{"program": "Bad.asm", "cycles": 1, "cases": [{"name": "a"}]}`), 0644)
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, exitUsage},
+		{[]string{"--help"}, exitOK},
+		{[]string{"bogus"}, exitUsage},
+		{[]string{"asm", "-h"}, exitOK},
+		{[]string{"asm"}, exitUsage},
+		{[]string{"asm", "--bogus", bad}, exitUsage},
+		{[]string{"asm", filepath.Join(dir, "Main.jack")}, exitUsage},
+		{[]string{"asm", bad}, exitError},
+		{[]string{"asm", filepath.Join(dir, "Missing.asm")}, exitError},
+		{[]string{"run", "--cycles", "x", bad}, exitUsage},
+		{[]string{"run", "--ram", "0=x", bad}, exitUsage},
+		{[]string{"test"}, exitUsage},
+		{[]string{"test", "../part1/project4/Mult.json"}, exitOK},
+		{[]string{"test", failing}, exitError},
+	}
+
+	for _, tt := range tests {
+		if got := run(tt.args); got != tt.want {
+			t.Errorf("%v exited with %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestParseAddresses(t *testing.T) {
+	got, err := parseAddresses("0-2,256,,7-7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 5 || got[2] != 2 || got[3] != 256 || got[4] != 7 {
+		t.Errorf("got %v", got)
+	}
+
+	for _, list := range []string{"2-1", "x", "0-24577"} {
+		if _, err := parseAddresses(list); err == nil {
+			t.Errorf("parseAddresses(%q) succeeded", list)
+		}
+	}
+}
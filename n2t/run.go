@@ -0,0 +1,222 @@
+package main
+
+// This file contains the commands running programs and tests: run and test.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"nand2tetris/hack-emulator/cpu"
+	"nand2tetris/hdl-tools/tst"
+	"nand2tetris/n2t/hacktest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func runRun(args []string) error {
+	fs, verbose := newFlagSet("run")
+	var ram, dump string
+	var cycles, key int
+	intFlag(fs, &cycles, "cycles", "c", defaultCycles, "Instructions run at most")
+	stringFlag(fs, &ram, "ram", "r", "", "RAM values set before running")
+	intFlag(fs, &key, "keyboard", "b", 0, "Key held down")
+	stringFlag(fs, &dump, "dump", "d", "0-15", "Addresses printed after running")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleArg(fs)
+	if err != nil {
+		return err
+	}
+	dumped, err := parseAddresses(dump)
+	if err != nil {
+		return err
+	}
+	values, err := parseRAM(ram)
+	if err != nil {
+		return err
+	}
+
+	program, err := loadProgram(inPath, *verbose)
+	if err != nil {
+		return err
+	}
+	c, err := cpu.New(program)
+	if err != nil {
+		return err
+	}
+	for adr, v := range values {
+		c.Write(adr, v)
+	}
+	c.SetKey(uint16(key))
+
+	if c.Run(cycles) {
+		log.Printf("[i] Halted after %d cycle(s)", c.Cycles)
+	} else {
+		log.Printf("[i] Stopped after %d cycle(s), without halting", c.Cycles)
+	}
+
+	for _, adr := range dumped {
+		fmt.Printf("RAM[%d] = %d\n", adr, int16(c.Read(adr)))
+	}
+
+	return nil
+}
+
+// Returns the machine language of a .hack file, or of a .asm file assembled
+func loadProgram(path string, verbose bool) ([]uint16, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filepath.Ext(path) {
+	case ".hack":
+		return cpu.Load(bytes.NewReader(data))
+	case ".asm":
+		code, err := assemble(data, verbose)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return cpu.Parse(code)
+	}
+
+	return nil, usagef("Hack program (.hack or .asm) expected")
+}
+
+// Parses RAM values given as "ADR=VAL,...". Values may be negative.
+func parseRAM(list string) (map[uint16]uint16, error) {
+	values := map[uint16]uint16{}
+
+	for _, item := range strings.Split(list, ",") {
+		if len(item) == 0 {
+			continue
+		}
+
+		adr, val, found := strings.Cut(item, "=")
+		a, err := strconv.ParseUint(adr, 10, 16)
+		if !found || err != nil || a >= cpu.KbdAdr {
+			return nil, usagef("invalid RAM value %q", item)
+		}
+		v, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || v < -32768 || v > 65535 {
+			return nil, usagef("invalid RAM value %q", item)
+		}
+		values[uint16(a)] = uint16(v)
+	}
+
+	return values, nil
+}
+
+// Parses addresses and ranges given as "0-2,256"
+func parseAddresses(list string) ([]uint16, error) {
+	var adrs []uint16
+
+	for _, item := range strings.Split(list, ",") {
+		if len(item) == 0 {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(item, "-")
+		if !isRange {
+			to = from
+		}
+		first, err1 := strconv.ParseUint(from, 10, 16)
+		last, err2 := strconv.ParseUint(to, 10, 16)
+		if err1 != nil || err2 != nil || first > last || last >= cpu.RAMSize {
+			return nil, usagef("invalid address or range %q", item)
+		}
+		for adr := first; adr <= last; adr++ {
+			adrs = append(adrs, uint16(adr))
+		}
+	}
+
+	return adrs, nil
+}
+
+func runTest(args []string) error {
+	fs, verbose := newFlagSet("test")
+	var path string
+	stringFlag(fs, &path, "path", "p", "", "Comma-separated part search path")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usagef("expected at least 1 test script")
+	}
+
+	var opts tst.Options
+	for _, dir := range strings.Split(path, ",") {
+		if len(dir) != 0 {
+			opts.SearchPath = append(opts.SearchPath, filepath.Clean(dir))
+		}
+	}
+	if *verbose {
+		opts.Echo = func(msg string) {
+			log.Printf("[i] %s", msg)
+		}
+	}
+
+	failed := 0
+	for _, script := range fs.Args() {
+		if filepath.Ext(script) == ".json" {
+			if !runSpec(script) {
+				failed++
+			}
+			continue
+		}
+
+		err := tst.Run(script, opts)
+
+		var mismatch *tst.Mismatch
+		switch {
+		case errors.As(err, &mismatch):
+			log.Printf("[!] %s failed: %s", script, err)
+			failed++
+		case err != nil:
+			log.Printf("[!] Error: %s", err)
+			failed++
+		default:
+			log.Printf("[i] %s passed", script)
+		}
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d script(s) failed", failed, fs.NArg())
+	}
+
+	return nil
+}
+
+// Runs the test spec of a Hack program at path, reporting every case. Returns
+// whether they all passed.
+func runSpec(path string) bool {
+	s, err := hacktest.Load(path)
+	if err != nil {
+		log.Printf("[!] Error: %s", err)
+		return false
+	}
+	results, err := s.Run()
+	if err != nil {
+		log.Printf("[!] Error: %s", err)
+		return false
+	}
+
+	passed := true
+	for _, res := range results {
+		if res.Passed() {
+			log.Printf("[i] %s: %s", path, res)
+			continue
+		}
+		for _, failure := range res.Failures {
+			log.Printf("[!] %s: %s failed: %s", path, res.Case, failure)
+		}
+		passed = false
+	}
+
+	return passed
+}
@@ -0,0 +1,185 @@
+package main
+
+// This file contains the stages of the toolchain, run in memory by the
+// commands: compiling Jack classes, translating VM code and assembling.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"nand2tetris/hack-assembler/assembler"
+	"nand2tetris/jack-compiler/ast"
+	"nand2tetris/jack-compiler/checker"
+	"nand2tetris/jack-compiler/compiler"
+	"nand2tetris/jack-compiler/parser"
+	"nand2tetris/vm-translator/constants"
+	"nand2tetris/vm-translator/translator"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Returns the files of dir with extension ext, sorted
+func listFiles(dir string, ext string) []string {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*"+ext))
+	sort.Strings(paths)
+
+	return paths
+}
+
+// Compiles Jack classes, and returns their VM code by file name, e.g.
+// "Main.vm". Unless noCheck, the classes are first checked together; a single
+// class is checked with the other classes of its directory.
+func compileJack(paths []string, noCheck bool, verbose bool) (map[string][]byte, error) {
+	classes := make([]*ast.Class, len(paths))
+	for i, path := range paths {
+		class, err := parser.ParseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		classes[i] = class
+	}
+
+	if !noCheck {
+		if verbose {
+			log.Printf("[i] Checking %d class(es)", len(classes))
+		}
+		if err := checkJack(paths, classes); err != nil {
+			return nil, err
+		}
+	}
+
+	out := map[string][]byte{}
+	for i, class := range classes {
+		var buf bytes.Buffer
+		if err := compiler.Compile(class, &buf); err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(paths[i]), ".jack") + ".vm"
+		out[name] = buf.Bytes()
+	}
+	if verbose {
+		log.Printf("[i] %d class(es) compiled", len(classes))
+	}
+
+	return out, nil
+}
+
+func checkJack(paths []string, classes []*ast.Class) error {
+	program := classes
+	single := len(paths) == 1
+	if single {
+		for _, path := range listFiles(filepath.Dir(paths[0]), ".jack") {
+			if path == paths[0] {
+				continue
+			}
+			// Classes which don't parse are left out
+			if class, err := parser.ParseFile(path); err == nil {
+				program = append(program, class)
+			}
+		}
+	}
+
+	problems := 0
+	for _, p := range checker.Check(program) {
+		if single && p.File != paths[0] {
+			continue
+		}
+		log.Printf("[!] %s", p)
+		problems++
+	}
+	if problems != 0 {
+		return fmt.Errorf("%d problem(s) found", problems)
+	}
+
+	return nil
+}
+
+// Translates VM files, by file name, to assembly with the options of opts,
+// e.g. the bootstrap code calling Sys.init of whole programs
+func translateVM(files map[string][]byte, opts translator.Options, verbose bool) ([]byte, error) {
+	readers := map[string]io.Reader{}
+	for name, code := range files {
+		readers[name] = bytes.NewReader(code)
+	}
+
+	var buf bytes.Buffer
+	var stats translator.Stats
+	var removed []translator.RemovedFunction
+	opts.Stats = &stats
+	opts.Removed = &removed
+	err := translator.Translate(readers, &buf, opts)
+
+	var syntaxErrs translator.SyntaxErrors
+	if errors.As(err, &syntaxErrs) {
+		for _, err := range syntaxErrs {
+			log.Printf("[!] %s", err)
+		}
+		return nil, fmt.Errorf("%d error(s) found in the VM code", len(syntaxErrs))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if verbose {
+		log.Printf("[i] %d VM file(s) translated: %d instructions, %d RAM variable(s)", len(files), stats.Instructions, len(stats.Variables))
+	}
+	if opts.Prune {
+		size := 0
+		for _, f := range removed {
+			log.Printf("[i] Removed %s (%s:%d), %d VM instruction(s)", f.Name, f.File, f.Line, f.Instructions)
+			size += f.Instructions
+		}
+		log.Printf("[i] %d unreachable function(s) removed, %d VM instruction(s)", len(removed), size)
+	}
+	if opts.Checked {
+		log.Printf("[i] Checks failing halt with their error code in RAM[%d] and VM line in RAM[%d]", constants.TrapCodeAdr, constants.TrapLineAdr)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Assembles Hack assembly to machine language
+func assemble(asm []byte, verbose bool) ([]string, error) {
+	code, err := assembler.Assemble(bytes.NewReader(asm))
+	if err != nil {
+		return nil, err
+	}
+	if verbose {
+		log.Printf("[i] %d instruction(s) assembled", len(code))
+	}
+
+	return code, nil
+}
+
+// Reads the files at paths, by file name
+func readFiles(paths []string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.Base(path)] = data
+	}
+
+	return files, nil
+}
+
+func writeFile(path string, data []byte, verbose bool) error {
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	if verbose {
+		log.Printf("[i] Wrote %q", path)
+	}
+
+	return nil
+}
+
+// Writes machine language, one instruction per line
+func writeHack(path string, code []string, verbose bool) error {
+	return writeFile(path, []byte(strings.Join(code, "\n")+"\n"), verbose)
+}
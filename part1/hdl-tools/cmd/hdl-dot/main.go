@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"nand2tetris/hdl-tools/diagram"
+	"nand2tetris/hdl-tools/hdl"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const helpMsg = `HDL Schematic Generator
+Usage:
+	hdl-dot [-h/--help] [-p/--path DIRS] [-s/--svg] [-o/--out DIR] HDL...
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-p/--path DIRS       Comma-separated directories searched for the parts of
+	                     the drawn chips, after the directory of each chip, e.g.
+	                     "../project2,../project1". Parts not found fall back to
+	                     the built-in chips. (Default: none)
+	-s/--svg             Writes SVG rather than DOT, by running Graphviz's "dot"
+	                     command, which must be installed. (Default: off)
+	-o/--out DIR         Directory the diagrams are written to. (Default: the
+	                     directory of each chip)
+
+Positional Argument:
+	HDL                  Chip file (.hdl), or a directory whose .hdl files are
+	                     all drawn. At least one is required.
+
+Description:
+	Draws the block diagram of every chip in the Graphviz DOT language: its
+	input pins on the left, its output pins on the right, and its parts in
+	between, with their inputs and outputs. Wires are labelled with the signal
+	they carry, and buses with their width, e.g. "aluOut /16". Slices of part
+	pins label the end of the wire at the part.
+
+	A chip "Xxx.hdl" is drawn to "Xxx.dot", or "Xxx.svg" with --svg.
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, helpMsg)
+		os.Exit(0)
+	}
+
+	var path, outDir string
+	var svg bool
+	flag.StringVar(&path, "path", "", "Comma-separated part search path")
+	flag.StringVar(&path, "p", "", "Comma-separated part search path")
+	flag.BoolVar(&svg, "svg", false, "Writes SVG with Graphviz")
+	flag.BoolVar(&svg, "s", false, "Writes SVG with Graphviz")
+	flag.StringVar(&outDir, "out", "", "Output directory")
+	flag.StringVar(&outDir, "o", "", "Output directory")
+
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+	}
+
+	var searchPath []string
+	for _, dir := range strings.Split(path, ",") {
+		if len(dir) != 0 {
+			searchPath = append(searchPath, filepath.Clean(dir))
+		}
+	}
+
+	var files []string
+	for _, arg := range flag.Args() {
+		files = append(files, resolvePaths(filepath.Clean(arg))...)
+	}
+
+	loaders := map[string]*hdl.Loader{}
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if loaders[dir] == nil {
+			loaders[dir] = hdl.NewLoader(append([]string{dir}, searchPath...)...)
+		}
+
+		chip, err := loaders[dir].LoadFile(file)
+		if err != nil {
+			log.Fatalf("[!] Error: %s", err)
+		}
+
+		var buf bytes.Buffer
+		if err := diagram.WriteDOT(&buf, loaders[dir], chip); err != nil {
+			log.Fatalf("[!] Error: %s", err)
+		}
+
+		ext := ".dot"
+		out := buf.Bytes()
+		if svg {
+			ext = ".svg"
+			if out, err = toSVG(out); err != nil {
+				log.Fatalf("[!] Error: Unable to run Graphviz on %q: %s", file, err)
+			}
+		}
+
+		outPath := strings.TrimSuffix(file, filepath.Ext(file)) + ext
+		if len(outDir) != 0 {
+			outPath = filepath.Join(outDir, filepath.Base(outPath))
+		}
+		if err := os.WriteFile(outPath, out, 0644); err != nil {
+			log.Fatalf("[!] Error: Unable to write %q: %s", outPath, err)
+		}
+
+		log.Printf("[i] Diagram of %s output to %q", chip.Name, outPath)
+	}
+}
+
+// Renders a DOT graph to SVG with Graphviz
+func toSVG(dot []byte) ([]byte, error) {
+	cmd := exec.Command("dot", "-Tsvg")
+	cmd.Stdin = bytes.NewReader(dot)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil && stderr.Len() != 0 {
+		return nil, fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, err
+}
+
+// Returns the .hdl files to draw for a command line argument
+func resolvePaths(path string) []string {
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.hdl"))
+	if err != nil || len(files) == 0 {
+		log.Fatalf("[!] Error: no .hdl files found in %q", path)
+	}
+
+	return files
+}
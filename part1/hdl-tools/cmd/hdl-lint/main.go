@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"nand2tetris/hdl-tools/hdl"
+	"nand2tetris/hdl-tools/lint"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const helpMsg = `HDL Linter
+Usage:
+	hdl-lint [-h/--help] [-p/--path DIRS] HDL...
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-p/--path DIRS       Comma-separated directories searched for the parts of
+	                     the checked chips, after the directory of each chip,
+	                     e.g. "../project2,../project1". Parts not found fall back
+	                     to the built-in chips. (Default: none)
+
+Positional Argument:
+	HDL                  Chip file (.hdl), or a directory whose .hdl files are
+	                     all checked. At least one is required.
+
+Description:
+	Checks chips written in the Nand2Tetris hardware description language for
+	wiring mistakes, without simulating them:
+	  - internal pins read but never driven, or never used
+	  - bus width mismatches, e.g. out[0..14] connected to a 16-bit input
+	  - output pins, or bits of them, never assigned
+	  - pins driven by more than one part
+	  - combinational loops not broken by a DFF or a memory chip
+	Every problem is reported with its file and line.
+
+	Exits with status 1 if any problem is found.
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, helpMsg)
+		os.Exit(0)
+	}
+
+	var path string
+	flag.StringVar(&path, "path", "", "Comma-separated part search path")
+	flag.StringVar(&path, "p", "", "Comma-separated part search path")
+
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+	}
+
+	var searchPath []string
+	for _, dir := range strings.Split(path, ",") {
+		if len(dir) != 0 {
+			searchPath = append(searchPath, filepath.Clean(dir))
+		}
+	}
+
+	var files []string
+	for _, arg := range flag.Args() {
+		files = append(files, resolvePaths(filepath.Clean(arg))...)
+	}
+
+	// One loader (and linter) per directory, as parts are looked up next to
+	// the chip using them first
+	linters := map[string]*lint.Linter{}
+	loaders := map[string]*hdl.Loader{}
+	count := 0
+
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if linters[dir] == nil {
+			loaders[dir] = hdl.NewLoader(append([]string{dir}, searchPath...)...)
+			linters[dir] = lint.New(loaders[dir])
+		}
+
+		chip, err := loaders[dir].LoadFile(file)
+		if err != nil {
+			log.Printf("[!] Error: %s", err)
+			count++
+			continue
+		}
+
+		for _, p := range linters[dir].Check(chip) {
+			log.Printf("[!] %s", p)
+			count++
+		}
+	}
+
+	if count != 0 {
+		log.Fatalf("[!] %d problem(s) found in %d chip(s)", count, len(files))
+	}
+	log.Printf("[i] No problems found in %d chip(s)", len(files))
+}
+
+// Returns the .hdl files to check for a command line argument
+func resolvePaths(path string) []string {
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.hdl"))
+	if err != nil || len(files) == 0 {
+		log.Fatalf("[!] Error: no .hdl files found in %q", path)
+	}
+
+	return files
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"nand2tetris/hdl-tools/hdl"
+	"nand2tetris/hdl-tools/stats"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const helpMsg = `HDL Gate Count and Critical Path Report
+Usage:
+	hdl-stats [-h/--help] [-p/--path DIRS] HDL...
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-p/--path DIRS       Comma-separated directories searched for the parts of
+	                     the measured chips, after the directory of each chip,
+	                     e.g. "../project2,../project1". Parts not found fall back
+	                     to the built-in chips. (Default: none)
+
+Positional Argument:
+	HDL                  Chip file (.hdl) to measure. At least one is required.
+	                     Give several to compare alternative designs.
+
+Description:
+	Expands every chip down to Nand gates and reports:
+	  - the total number of Nand gates
+	  - the built-in chips used (DFF, registers, memories), which count as no
+	    Nand gate
+	  - the longest combinational path, in Nand delays, and its ends: pins of
+	    the chip, or "(state)" for a path starting or ending at a clocked part
+	  - the same figures for every part of the chip
+	Constant inputs (true, false) never start a path. Reading a built-in memory
+	counts as no delay.
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, helpMsg)
+		os.Exit(0)
+	}
+
+	var path string
+	flag.StringVar(&path, "path", "", "Comma-separated part search path")
+	flag.StringVar(&path, "p", "", "Comma-separated part search path")
+
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+	}
+
+	var searchPath []string
+	for _, dir := range strings.Split(path, ",") {
+		if len(dir) != 0 {
+			searchPath = append(searchPath, filepath.Clean(dir))
+		}
+	}
+
+	for i, file := range flag.Args() {
+		file = filepath.Clean(file)
+
+		// A fresh loader per chip, so alternative designs of the same chip
+		// don't shadow each other
+		l := hdl.NewLoader(append([]string{filepath.Dir(file)}, searchPath...)...)
+		chip, err := l.LoadFile(file)
+		if err != nil {
+			log.Fatalf("[!] Error: %s", err)
+		}
+
+		s, err := stats.NewAnalyzer(l).Analyze(chip)
+		if err != nil {
+			log.Fatalf("[!] Error: %s", err)
+		}
+
+		if i != 0 {
+			fmt.Println()
+		}
+		printStats(file, s)
+	}
+}
+
+func printStats(file string, s *stats.Stats) {
+	fmt.Printf("%s (%s)\n", s.Chip.Name, file)
+	fmt.Printf("  Nand gates:     %d\n", s.Nands)
+	fmt.Printf("  Built-in chips: %s\n", builtinList(s.Builtins))
+	if s.From == "" {
+		fmt.Printf("  Critical path:  %d Nand delays\n", s.Depth)
+	} else {
+		fmt.Printf("  Critical path:  %d Nand delays, from %s to %s\n", s.Depth, s.From, s.To)
+	}
+
+	if len(s.Parts) == 0 {
+		return
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Part\tLine\tNand gates\tCritical path")
+	for _, p := range s.Parts {
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\n", p.Name, p.Line, p.Nands, p.Depth)
+	}
+	w.Flush()
+}
+
+// e.g. "DFF x16, RAM16K x1", or "none"
+func builtinList(builtins map[string]int) string {
+	if len(builtins) == 0 {
+		return "none"
+	}
+
+	var names []string
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []string
+	for _, name := range names {
+		out = append(out, fmt.Sprintf("%s x%d", name, builtins[name]))
+	}
+
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"nand2tetris/hdl-tools/tst"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const helpMsg = `HDL Test Script Runner
+Usage:
+	hdl-test [-h/--help] [-p/--path DIRS] SCRIPT...
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-p/--path DIRS       Comma-separated directories searched for the parts of
+	                     the tested chips, after the directory of each script,
+	                     e.g. "../project2,../project1". Parts not found fall back
+	                     to the built-in chips. (Default: none)
+
+Positional Argument:
+	SCRIPT               Test script (.tst) in the Nand2Tetris format. At least
+	                     one is required.
+
+Description:
+	Runs Nand2Tetris test scripts against the Go HDL simulator, without the
+	course's Java hardware simulator. Each script's output file (.out) is written
+	next to it, and every output line is compared with the script's comparison
+	file (.cmp). The first mismatching line is reported with the expected and
+	actual values, and the differing columns marked.
+
+	Exits with status 1 if any script fails.
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, helpMsg)
+		os.Exit(0)
+	}
+
+	var path string
+	flag.StringVar(&path, "path", "", "Comma-separated part search path")
+	flag.StringVar(&path, "p", "", "Comma-separated part search path")
+
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+	}
+
+	var opts tst.Options
+	for _, dir := range strings.Split(path, ",") {
+		if len(dir) != 0 {
+			opts.SearchPath = append(opts.SearchPath, filepath.Clean(dir))
+		}
+	}
+	opts.Echo = func(msg string) {
+		log.Printf("[i] %s", msg)
+	}
+
+	failed := 0
+	for _, script := range flag.Args() {
+		err := tst.Run(script, opts)
+
+		var mismatch *tst.Mismatch
+		switch {
+		case errors.As(err, &mismatch):
+			log.Printf("[!] %s failed: %s", script, err)
+			failed++
+		case err != nil:
+			log.Printf("[!] Error: %s", err)
+			failed++
+		default:
+			log.Printf("[i] %s passed", script)
+		}
+	}
+
+	if failed != 0 {
+		log.Fatalf("[!] %d of %d script(s) failed", failed, flag.NArg())
+	}
+}
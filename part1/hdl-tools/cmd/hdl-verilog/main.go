@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"nand2tetris/hdl-tools/hdl"
+	"nand2tetris/hdl-tools/verilog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const helpMsg = `HDL to Verilog Exporter
+Usage:
+	hdl-verilog [-h/--help] [-p/--path DIRS] [-r/--rom FILE] [-o/--out FILE] HDL
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-p/--path DIRS       Comma-separated directories searched for the parts of
+	                     the chip, after the directory of the chip, e.g.
+	                     "../project3,../project2,../project1". Parts not found
+	                     fall back to the built-in chips. (Default: none)
+	-r/--rom FILE        Memory file loaded into ROM32K with $readmemb, as
+	                     written by "hack-assembler --mem". The path is written
+	                     as is in the Verilog. (Default: ROM32K starts empty)
+	-o/--out FILE        Output file. (Default: the chip file with a ".v"
+	                     extension)
+
+Positional Argument:
+	HDL                  Chip file (.hdl) to export. Required.
+
+Description:
+	Translates a chip and every chip it uses into synthesisable Verilog, one
+	module per chip. The built-in chips (Nand, DFF, Bit, the registers, PC, the
+	RAMs, ROM32K, Screen and Keyboard) become behavioural modules. Modules of
+	chips holding state get a "clk" input, the clock the HDL leaves implicit.
+	Unconnected inputs are false. Names reserved in Verilog get a trailing "_".
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, helpMsg)
+		os.Exit(0)
+	}
+
+	var path, rom, outPath string
+	flag.StringVar(&path, "path", "", "Comma-separated part search path")
+	flag.StringVar(&path, "p", "", "Comma-separated part search path")
+	flag.StringVar(&rom, "rom", "", "Memory file loaded into ROM32K")
+	flag.StringVar(&rom, "r", "", "Memory file loaded into ROM32K")
+	flag.StringVar(&outPath, "out", "", "Output file")
+	flag.StringVar(&outPath, "o", "", "Output file")
+
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+	}
+
+	inPath := filepath.Clean(flag.Arg(0))
+	if len(outPath) == 0 {
+		outPath = strings.TrimSuffix(inPath, filepath.Ext(inPath)) + ".v"
+	}
+
+	searchPath := []string{filepath.Dir(inPath)}
+	for _, dir := range strings.Split(path, ",") {
+		if len(dir) != 0 {
+			searchPath = append(searchPath, filepath.Clean(dir))
+		}
+	}
+
+	l := hdl.NewLoader(searchPath...)
+	chip, err := l.LoadFile(inPath)
+	if err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
+
+	outFile, err := os.Create(outPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to create %q: %s", outPath, err)
+	}
+	defer outFile.Close()
+
+	if err := verilog.Export(outFile, l, chip, verilog.Options{RomFile: rom}); err != nil {
+		outFile.Close()
+		os.Remove(outPath)
+		log.Fatalf("[!] Error: %s", err)
+	}
+
+	log.Printf("[i] Verilog output to %q successful", outPath)
+}
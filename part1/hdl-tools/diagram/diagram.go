@@ -0,0 +1,181 @@
+// Package diagram draws the block diagram of an HDL chip, its parts and the
+// wires between them, in the Graphviz DOT language.
+package diagram
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"nand2tetris/hdl-tools/hdl"
+	"strconv"
+	"strings"
+)
+
+// Writes the diagram of chip to w. Parts are boxes with their inputs on the
+// left and outputs on the right. Wires are labelled with the signal they
+// carry, and buses with their width, e.g. "aluOut /16".
+func WriteDOT(w io.Writer, l *hdl.Loader, chip *hdl.Chip) error {
+	d := drawing{chip: chip, out: bufio.NewWriter(w)}
+
+	defs := make([]*hdl.Chip, len(chip.Parts))
+	for i, part := range chip.Parts {
+		def, err := l.Load(part.Name)
+		if err != nil {
+			return &hdl.Error{File: chip.File, Line: part.Line, Msg: err.Error()}
+		}
+		defs[i] = def
+	}
+
+	d.printf("digraph %s {\n", quote(chip.Name))
+	d.printf("    rankdir=LR;\n")
+	d.printf("    label=%s;\n", quote(chip.Name))
+	d.printf("    labelloc=t;\n")
+	d.printf("    node [fontname=Helvetica, fontsize=11];\n")
+	d.printf("    edge [fontname=Helvetica, fontsize=9];\n")
+
+	// The chip's pins, at the edges of the diagram
+	d.pins("source", "in", chip.In)
+	d.pins("sink", "out", chip.Out)
+
+	for i, part := range chip.Parts {
+		d.part(i, part, defs[i])
+	}
+
+	// Drivers of the internal pins, to draw wires from
+	drivers := map[string][]string{}
+	for i, part := range chip.Parts {
+		for _, conn := range part.Conns {
+			sig := conn.Signal
+			if defs[i].IsOutput(conn.Part.Name) && !sig.IsConst() && !chip.IsOutput(sig.Name) {
+				drivers[sig.Name] = append(drivers[sig.Name], partNode(i)+":"+port("o", conn.Part.Name)+":e")
+			}
+		}
+	}
+
+	consts := 0
+	for i, part := range chip.Parts {
+		def := defs[i]
+
+		for _, conn := range part.Conns {
+			pin, found := def.Pin(conn.Part.Name)
+			if !found {
+				return &hdl.Error{File: chip.File, Line: conn.Line, Msg: "chip " + def.Name + " has no pin called " + conn.Part.Name}
+			}
+			sig := conn.Signal
+			width := conn.Part.Width(pin.Width)
+
+			if def.IsOutput(pin.Name) {
+				// Wires to internal pins are drawn from their readers
+				if chip.IsOutput(sig.Name) {
+					d.wire(partNode(i)+":"+port("o", pin.Name)+":e", pinNode("out", sig.Name), sig, width, conn.Part, "taillabel")
+				}
+				continue
+			}
+
+			to := partNode(i) + ":" + port("i", pin.Name) + ":w"
+			switch {
+			case sig.IsConst():
+				node := "const" + strconv.Itoa(consts)
+				consts++
+				d.printf("    %s [label=%s, shape=plaintext, fontcolor=gray40];\n", node, quote(sig.Name))
+				d.wire(node, to, sig, width, conn.Part, "headlabel")
+
+			case chip.IsInput(sig.Name):
+				d.wire(pinNode("in", sig.Name), to, sig, width, conn.Part, "headlabel")
+
+			default:
+				if len(drivers[sig.Name]) == 0 {
+					// Drawn anyway, from nowhere, as hdl-lint would report it
+					node := "undriven_" + sig.Name
+					d.printf("    %s [label=%s, shape=plaintext, fontcolor=red];\n", node, quote(sig.Name+"?"))
+					drivers[sig.Name] = []string{node}
+				}
+				for _, from := range drivers[sig.Name] {
+					d.wire(from, to, sig, width, conn.Part, "headlabel")
+				}
+			}
+		}
+	}
+
+	d.printf("}\n")
+	return d.out.Flush()
+}
+
+type drawing struct {
+	chip *hdl.Chip
+	out  *bufio.Writer
+}
+
+func (d *drawing) printf(format string, a ...interface{}) {
+	fmt.Fprintf(d.out, format, a...)
+}
+
+// Draws the input or output pins of the chip, in the same rank
+func (d *drawing) pins(rank string, kind string, pins []hdl.Pin) {
+	if len(pins) == 0 {
+		return
+	}
+
+	d.printf("    {\n        rank=%s;\n", rank)
+	for _, p := range pins {
+		label := p.Name
+		if p.Width > 1 {
+			label += "[" + strconv.Itoa(p.Width) + "]"
+		}
+		d.printf("        %s [label=%s, shape=cds, style=filled, fillcolor=lightgray];\n", pinNode(kind, p.Name), quote(label))
+	}
+	d.printf("    }\n")
+}
+
+// Draws a part as a record: inputs, name and line, outputs
+func (d *drawing) part(i int, part hdl.Part, def *hdl.Chip) {
+	var in, out []string
+	for _, p := range def.In {
+		in = append(in, "<"+port("i", p.Name)+"> "+escape(p.Name))
+	}
+	for _, p := range def.Out {
+		out = append(out, "<"+port("o", p.Name)+"> "+escape(p.Name))
+	}
+
+	label := "{" + strings.Join(in, "|") + "}|" + escape(part.Name) + "\\nline " + strconv.Itoa(part.Line) + "|{" + strings.Join(out, "|") + "}"
+	d.printf("    %s [shape=record, label=%s];\n", partNode(i), quote(label))
+}
+
+// Draws a wire carrying sig, width bits wide. The slice of the part pin, if
+// any, labels the end of the wire at the part.
+func (d *drawing) wire(from string, to string, sig hdl.PinRef, width int, partPin hdl.PinRef, end string) {
+	label := sig.String()
+	attrs := ""
+	if width > 1 {
+		label += " /" + strconv.Itoa(width)
+		attrs = ", penwidth=2"
+	}
+	if partPin.IsSliced() {
+		attrs += ", " + end + "=" + quote(partPin.String())
+	}
+
+	d.printf("    %s -> %s [label=%s%s];\n", from, to, quote(label), attrs)
+}
+
+func partNode(i int) string {
+	return "part" + strconv.Itoa(i)
+}
+
+// HDL names are valid DOT identifiers, so they're used in node and port names
+func pinNode(kind string, name string) string {
+	return kind + "_" + name
+}
+
+func port(kind string, name string) string {
+	return kind + "_" + name
+}
+
+func quote(s string) string {
+	return "\"" + strings.ReplaceAll(s, "\"", "\\\"") + "\""
+}
+
+// Escapes the characters with a meaning in record labels
+func escape(s string) string {
+	r := strings.NewReplacer("{", "\\{", "}", "\\}", "|", "\\|", "<", "\\<", ">", "\\>")
+	return r.Replace(s)
+}
@@ -0,0 +1,63 @@
+package diagram
+
+import (
+	"bytes"
+	"flag"
+	"nand2tetris/hdl-tools/hdl"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata")
+
+func TestGolden(t *testing.T) {
+	for _, name := range []string{"Bus", "Xor"} {
+		l := hdl.NewLoader("testdata", "../../project1")
+		chip, err := l.Load(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		if err := WriteDOT(&out, l, chip); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join("testdata", name+".dot")
+
+		if *update {
+			if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != string(want) {
+			t.Errorf("%s: output differs from %s, run with -update to inspect", name, path)
+		}
+	}
+}
+
+func TestWriteDOTUnknownPart(t *testing.T) {
+	dir := t.TempDir()
+	src := "CHIP Unknown { IN a; OUT out;\nPARTS:\nFrobnicate(in=a, out=out); }"
+	if err := os.WriteFile(filepath.Join(dir, "Unknown.hdl"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := hdl.NewLoader(dir)
+	chip, err := l.Load("Unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = WriteDOT(&out, l, chip)
+	if e, ok := err.(*hdl.Error); !ok || e.Line != 3 {
+		t.Errorf("WriteDOT() = %v, want an error at line 3", err)
+	}
+}
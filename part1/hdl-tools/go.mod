@@ -0,0 +1,3 @@
+module nand2tetris/hdl-tools
+
+go 1.18
@@ -0,0 +1,242 @@
+package hdl
+
+// This file contains the flattening of a chip hierarchy into nand gates and
+// built-in chips. Every bit of every pin gets a wire, and connections merge
+// wires into nets.
+
+// Wires 0 and 1 always hold the constants false and true
+const (
+	wireFalse = 0
+	wireTrue  = 1
+)
+
+type builder struct {
+	loader  *Loader
+	parent  []int // Union-find forest of wires
+	nands   []nand
+	insts   []*instance
+	loading map[string]bool // Chips being instantiated, to catch recursion
+}
+
+func newBuilder(l *Loader) *builder {
+	return &builder{loader: l, parent: []int{wireFalse, wireTrue}, loading: map[string]bool{}}
+}
+
+// Returns width new wires
+func (b *builder) alloc(width int) []int {
+	bits := make([]int, width)
+	for i := range bits {
+		bits[i] = len(b.parent)
+		b.parent = append(b.parent, bits[i])
+	}
+
+	return bits
+}
+
+func (b *builder) find(w int) int {
+	for b.parent[w] != w {
+		b.parent[w] = b.parent[b.parent[w]]
+		w = b.parent[w]
+	}
+
+	return w
+}
+
+// Merge the nets of two wires. The constants always stay representatives.
+func (b *builder) union(x, y int) {
+	x, y = b.find(x), b.find(y)
+	if x == y {
+		return
+	}
+	if x <= wireTrue {
+		x, y = y, x
+	}
+
+	b.parent[x] = y
+}
+
+// Replace every wire in bits by the representative of its net
+func (b *builder) resolve(bits []int) {
+	for i, w := range bits {
+		bits[i] = b.find(w)
+	}
+}
+
+// Instantiate chip, whose pins are the wires in pins. Internal pins are added
+// to internal. from is the part of the simulated chip being expanded, nil
+// when instantiating the simulated chip itself.
+func (b *builder) instantiate(chip *Chip, pins map[string][]int, internal map[string][]int, from *origin) error {
+	if chip.Builtin != nil {
+		b.instantiateBuiltin(chip, pins, from)
+		return nil
+	}
+
+	if b.loading[chip.Name] {
+		return errorf(chip.File, chip.Line, "chip %s contains itself", chip.Name)
+	}
+	b.loading[chip.Name] = true
+	defer delete(b.loading, chip.Name)
+
+	// Bits of the chip's own outputs and internal pins already driven by a
+	// part
+	driven := map[string][]bool{}
+
+	for _, part := range chip.Parts {
+		def, err := b.loader.Load(part.Name)
+		if err != nil {
+			return errorf(chip.File, part.Line, "%s", err)
+		}
+
+		partPins := map[string][]int{}
+		for _, ps := range [][]Pin{def.In, def.Out} {
+			for _, p := range ps {
+				partPins[p.Name] = b.alloc(p.Width)
+			}
+		}
+
+		for _, conn := range part.Conns {
+			if err := b.connect(chip, def, conn, pins, internal, partPins, driven); err != nil {
+				return err
+			}
+		}
+
+		o := from
+		if o == nil {
+			o = &origin{part: part.Name, line: part.Line}
+		}
+
+		if err := b.instantiate(def, partPins, map[string][]int{}, o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Connect a pin of a part (of type def) to a signal of chip
+func (b *builder) connect(chip *Chip, def *Chip, conn Conn, pins, internal, partPins map[string][]int, driven map[string][]bool) error {
+	pin, found := def.Pin(conn.Part.Name)
+	if !found {
+		return errorf(chip.File, conn.Line, "chip %s has no pin called %s", def.Name, conn.Part.Name)
+	}
+	if conn.Part.IsSliced() && conn.Part.Hi >= pin.Width {
+		return errorf(chip.File, conn.Line, "%s is out of range, %s.%s has %d bit(s)", conn.Part, def.Name, pin.Name, pin.Width)
+	}
+
+	partBits := slice(partPins[pin.Name], conn.Part)
+	isOutput := def.IsOutput(pin.Name)
+	sig := conn.Signal
+
+	var sigBits []int
+	switch {
+	case sig.IsConst():
+		if isOutput {
+			return errorf(chip.File, conn.Line, "output %s can't be connected to %s", conn.Part, sig.Name)
+		}
+		if sig.IsSliced() {
+			return errorf(chip.File, conn.Line, "constant %s can't be sliced", sig.Name)
+		}
+
+		w := wireFalse
+		if sig.Name == True {
+			w = wireTrue
+		}
+		for _, bit := range partBits {
+			b.union(bit, w)
+		}
+		return nil
+
+	case chip.IsInput(sig.Name):
+		if isOutput {
+			return errorf(chip.File, conn.Line, "output %s can't drive input pin %s of %s", conn.Part, sig.Name, chip.Name)
+		}
+		p, _ := chip.Pin(sig.Name)
+		if sig.IsSliced() && sig.Hi >= p.Width {
+			return errorf(chip.File, conn.Line, "%s is out of range, %s has %d bit(s)", sig, sig.Name, p.Width)
+		}
+		sigBits = slice(pins[sig.Name], sig)
+
+	case chip.IsOutput(sig.Name):
+		if !isOutput {
+			return errorf(chip.File, conn.Line, "output pin %s of %s can't be used as an input", sig.Name, chip.Name)
+		}
+		p, _ := chip.Pin(sig.Name)
+		if sig.IsSliced() && sig.Hi >= p.Width {
+			return errorf(chip.File, conn.Line, "%s is out of range, %s has %d bit(s)", sig, sig.Name, p.Width)
+		}
+		sigBits = slice(pins[sig.Name], sig)
+
+	default:
+		if sig.IsSliced() {
+			return errorf(chip.File, conn.Line, "internal pin %s can't be sliced", sig.Name)
+		}
+		if _, found := internal[sig.Name]; !found {
+			internal[sig.Name] = b.alloc(len(partBits))
+		}
+		sigBits = internal[sig.Name]
+	}
+
+	if len(sigBits) != len(partBits) {
+		return errorf(chip.File, conn.Line, "width mismatch: %s has %d bit(s), %s has %d bit(s)", conn.Part, len(partBits), sig, len(sigBits))
+	}
+
+	if isOutput {
+		if driven[sig.Name] == nil {
+			width := len(pins[sig.Name])
+			if width == 0 {
+				width = len(internal[sig.Name])
+			}
+			driven[sig.Name] = make([]bool, width)
+		}
+		lo := 0
+		if sig.IsSliced() {
+			lo = sig.Lo
+		}
+		for i := range sigBits {
+			if driven[sig.Name][lo+i] {
+				return errorf(chip.File, conn.Line, "%s is driven by more than one part", sig)
+			}
+			driven[sig.Name][lo+i] = true
+		}
+	}
+
+	for i := range partBits {
+		b.union(partBits[i], sigBits[i])
+	}
+
+	return nil
+}
+
+// Add a built-in chip (or nand gate) connected to the wires in pins
+func (b *builder) instantiateBuiltin(chip *Chip, pins map[string][]int, from *origin) {
+	o := origin{part: chip.Name}
+	if from != nil {
+		o = *from
+	}
+
+	if chip.Builtin == builtinNand {
+		b.nands = append(b.nands, nand{a: pins["a"][0], b: pins["b"][0], out: pins["out"][0], origin: o})
+		return
+	}
+
+	inst := &instance{chip: chip, prim: chip.Builtin.new(), origin: o}
+	for _, p := range chip.Builtin.In {
+		inst.in = append(inst.in, pins[p.Name])
+	}
+	for _, p := range chip.Builtin.Out {
+		inst.out = append(inst.out, pins[p.Name])
+	}
+	inst.inVals = make([]uint16, len(inst.in))
+	inst.outVals = make([]uint16, len(inst.out))
+
+	b.insts = append(b.insts, inst)
+}
+
+// Returns the bits selected by ref
+func slice(bits []int, ref PinRef) []int {
+	if !ref.IsSliced() {
+		return bits
+	}
+
+	return bits[ref.Lo : ref.Hi+1]
+}
@@ -0,0 +1,241 @@
+package hdl
+
+// This file contains the chips implemented in Go: the Nand gate, the DFF, and
+// the course's memory chips, which would be far too slow to simulate gate by
+// gate.
+
+// Values are held in uint16, so no pin can be wider than this
+const MaxWidth = 16
+
+// Behaviour of a chip implemented in Go
+type Builtin struct {
+	In  []Pin
+	Out []Pin
+	// Inputs that only reach the outputs through the clock. Every other input
+	// affects the outputs immediately.
+	Clocked []string
+
+	new func() primitive
+}
+
+// Simulation of a built-in chip. Input and output values are given in the
+// order of the pin declarations.
+type primitive interface {
+	// Compute the outputs from the current inputs and state
+	eval(in []uint16, out []uint16)
+	// Rising clock edge: sample the clocked inputs
+	tick(in []uint16)
+	// Falling clock edge: commit the sampled values to the state
+	tock()
+	// The words of state, e.g. the contents of a RAM, or nil if none
+	memory() []uint16
+}
+
+// Whether the input pin called name only affects the outputs through the clock
+func (b *Builtin) IsClocked(name string) bool {
+	for _, c := range b.Clocked {
+		if c == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func pins(decl ...interface{}) []Pin {
+	var out []Pin
+	for i := 0; i < len(decl); i += 2 {
+		out = append(out, Pin{Name: decl[i].(string), Width: decl[i+1].(int)})
+	}
+
+	return out
+}
+
+var builtinNand = &Builtin{
+	In:  pins("a", 1, "b", 1),
+	Out: pins("out", 1),
+	new: func() primitive { return &nandGate{} },
+}
+
+var builtins = map[string]*Builtin{
+	"Nand": builtinNand,
+	"DFF": {
+		In: pins("in", 1), Out: pins("out", 1), Clocked: []string{"in"},
+		new: func() primitive { return &register{mem: make([]uint16, 1)} },
+	},
+	"Bit": {
+		In: pins("in", 1, "load", 1), Out: pins("out", 1), Clocked: []string{"in", "load"},
+		new: func() primitive { return &register{mem: make([]uint16, 1), loadable: true} },
+	},
+	"Register":  newRegister(),
+	"ARegister": newRegister(),
+	"DRegister": newRegister(),
+	"PC": {
+		In:      pins("in", 16, "load", 1, "inc", 1, "reset", 1),
+		Out:     pins("out", 16),
+		Clocked: []string{"in", "load", "inc", "reset"},
+		new:     func() primitive { return &counter{mem: make([]uint16, 1)} },
+	},
+	"RAM8":   newRAM(8, 3),
+	"RAM64":  newRAM(64, 6),
+	"RAM512": newRAM(512, 9),
+	"RAM4K":  newRAM(4096, 12),
+	"RAM16K": newRAM(16384, 14),
+	"Screen": newRAM(8192, 13),
+	"ROM32K": {
+		In: pins("address", 15), Out: pins("out", 16),
+		new: func() primitive { return &rom{mem: make([]uint16, 32768)} },
+	},
+	"Keyboard": {
+		Out: pins("out", 16),
+		new: func() primitive { return &rom{mem: make([]uint16, 1)} },
+	},
+}
+
+func newRegister() *Builtin {
+	return &Builtin{
+		In: pins("in", 16, "load", 1), Out: pins("out", 16), Clocked: []string{"in", "load"},
+		new: func() primitive { return &register{mem: make([]uint16, 1), loadable: true} },
+	}
+}
+
+func newRAM(size int, addressWidth int) *Builtin {
+	return &Builtin{
+		In:      pins("in", 16, "load", 1, "address", addressWidth),
+		Out:     pins("out", 16),
+		Clocked: []string{"in", "load"},
+		new:     func() primitive { return &ram{mem: make([]uint16, size)} },
+	}
+}
+
+// Returns the definition of the built-in chip called name
+func builtinChip(name string) (*Chip, bool) {
+	b, found := builtins[name]
+	if !found {
+		return nil, false
+	}
+
+	return &Chip{Name: name, In: b.In, Out: b.Out, Builtin: b}, true
+}
+
+type nandGate struct{}
+
+func (nandGate) eval(in []uint16, out []uint16) {
+	out[0] = ^(in[0] & in[1]) & 1
+}
+
+func (nandGate) tick([]uint16) {}
+func (nandGate) tock()         {}
+
+func (nandGate) memory() []uint16 {
+	return nil
+}
+
+// DFF, Bit and the 16-bit registers. Inputs: in[, load]
+type register struct {
+	mem      []uint16
+	loadable bool
+	next     uint16
+}
+
+func (r *register) eval(in []uint16, out []uint16) {
+	out[0] = r.mem[0]
+}
+
+func (r *register) tick(in []uint16) {
+	r.next = r.mem[0]
+	if !r.loadable || in[1] == 1 {
+		r.next = in[0]
+	}
+}
+
+func (r *register) tock() {
+	r.mem[0] = r.next
+}
+
+func (r *register) memory() []uint16 {
+	return r.mem
+}
+
+// The program counter. Inputs: in, load, inc, reset
+type counter struct {
+	mem  []uint16
+	next uint16
+}
+
+func (c *counter) eval(in []uint16, out []uint16) {
+	out[0] = c.mem[0]
+}
+
+func (c *counter) tick(in []uint16) {
+	switch {
+	case in[3] == 1:
+		c.next = 0
+	case in[1] == 1:
+		c.next = in[0]
+	case in[2] == 1:
+		c.next = c.mem[0] + 1
+	default:
+		c.next = c.mem[0]
+	}
+}
+
+func (c *counter) tock() {
+	c.mem[0] = c.next
+}
+
+func (c *counter) memory() []uint16 {
+	return c.mem
+}
+
+// RAM chips and the screen. Inputs: in, load, address. Reading is immediate,
+// writing happens on the clock.
+type ram struct {
+	mem     []uint16
+	write   bool
+	address uint16
+	value   uint16
+}
+
+func (r *ram) eval(in []uint16, out []uint16) {
+	out[0] = r.mem[in[2]]
+}
+
+func (r *ram) tick(in []uint16) {
+	r.write = in[1] == 1
+	r.address = in[2]
+	r.value = in[0]
+}
+
+func (r *ram) tock() {
+	if r.write {
+		r.mem[r.address] = r.value
+		r.write = false
+	}
+}
+
+func (r *ram) memory() []uint16 {
+	return r.mem
+}
+
+// ROM32K (input: address) and the keyboard (no input), whose contents are only
+// changed from outside the chip
+type rom struct {
+	mem []uint16
+}
+
+func (r *rom) eval(in []uint16, out []uint16) {
+	if len(in) == 0 {
+		out[0] = r.mem[0]
+		return
+	}
+
+	out[0] = r.mem[in[0]]
+}
+
+func (r *rom) tick([]uint16) {}
+func (r *rom) tock()         {}
+
+func (r *rom) memory() []uint16 {
+	return r.mem
+}
@@ -0,0 +1,30 @@
+package hdl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// An error in a chip definition, located by file and line. File is the chip
+// name for built-in chips.
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+func errorf(file string, line int, format string, a ...interface{}) *Error {
+	return &Error{File: file, Line: line, Msg: fmt.Sprintf(format, a...)}
+}
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
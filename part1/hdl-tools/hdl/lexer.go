@@ -0,0 +1,93 @@
+package hdl
+
+// This file contains the HDL tokenizer.
+
+import (
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokSymbol // One of "{}()[],;=:" or ".."
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// Splits HDL source into tokens, dropping whitespace and comments ("//",
+// "/* */" and "/** */")
+func tokenize(file string, src string) ([]token, error) {
+	var out []token
+	line := 1
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+
+		switch {
+		case c == '\n':
+			line++
+			i++
+
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start := line
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i+1 >= len(runes) {
+				return nil, errorf(file, start, "unterminated comment")
+			}
+			i += 2
+
+		case c == '.' && i+1 < len(runes) && runes[i+1] == '.':
+			out = append(out, token{tokSymbol, "..", line})
+			i += 2
+
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			out = append(out, token{tokNumber, string(runes[i:j]), line})
+			i = j
+
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			out = append(out, token{tokIdent, string(runes[i:j]), line})
+			i = j
+
+		case c == '{' || c == '}' || c == '(' || c == ')' || c == '[' || c == ']' ||
+			c == ',' || c == ';' || c == '=' || c == ':':
+			out = append(out, token{tokSymbol, string(c), line})
+			i++
+
+		default:
+			return nil, errorf(file, line, "unexpected character %q", c)
+		}
+	}
+
+	out = append(out, token{tokEOF, "", line})
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package hdl
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Finds and parses chips by name. A chip "Xxx" is read from the first
+// "Xxx.hdl" found in the search path, and falls back to the built-in chip of
+// that name. Parsed chips are cached.
+type Loader struct {
+	SearchPath []string
+	chips      map[string]*Chip
+}
+
+func NewLoader(searchPath ...string) *Loader {
+	return &Loader{SearchPath: searchPath, chips: map[string]*Chip{}}
+}
+
+// Returns the chip called name
+func (l *Loader) Load(name string) (*Chip, error) {
+	if chip, found := l.chips[name]; found {
+		return chip, nil
+	}
+
+	for _, dir := range l.SearchPath {
+		path := filepath.Join(dir, name+".hdl")
+
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		return l.LoadFile(path)
+	}
+
+	if chip, found := builtinChip(name); found {
+		l.chips[name] = chip
+		return chip, nil
+	}
+
+	return nil, &Error{File: name, Msg: "chip not found in search path, and not a built-in chip"}
+}
+
+// Parses the .hdl file at path and caches it under its chip name, so that it
+// takes precedence over the search path
+func (l *Loader) LoadFile(path string) (*Chip, error) {
+	chip, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if want := trimExt(filepath.Base(path)); chip.Name != want {
+		return nil, errorf(path, chip.Line, "chip %q must be declared in %q, not %q", chip.Name, chip.Name+".hdl", want+".hdl")
+	}
+
+	l.chips[chip.Name] = chip
+	return chip, nil
+}
+
+func trimExt(name string) string {
+	return name[:len(name)-len(filepath.Ext(name))]
+}
@@ -0,0 +1,118 @@
+package hdl
+
+// This file contains the data models of a parsed chip.
+
+// A chip definition, parsed from a .hdl file or provided as a built-in
+type Chip struct {
+	Name  string
+	File  string // Empty for built-in chips
+	Line  int
+	In    []Pin
+	Out   []Pin
+	Parts []Part
+
+	// Set for chips implemented in Go rather than by their parts, either
+	// because they are primitives (e.g. Nand) or declared "BUILTIN" in HDL
+	Builtin *Builtin
+}
+
+// An input or output pin declaration, e.g. "a[16]"
+type Pin struct {
+	Name  string
+	Width int
+	Line  int
+}
+
+// A part of a chip, e.g. "Mux16(a=x, b=y, sel=s, out=o)"
+type Part struct {
+	Name  string // Name of the chip used as part
+	Line  int
+	Conns []Conn
+}
+
+// A single connection of a part, e.g. "out[0..14]=addressM". Part is the pin of
+// the part (left hand side), Signal is the pin of the enclosing chip, an
+// internal pin or a constant (right hand side).
+type Conn struct {
+	Part   PinRef
+	Signal PinRef
+	Line   int
+}
+
+// A reference to a pin, optionally sliced, e.g. "a", "a[3]" or "a[0..7]"
+type PinRef struct {
+	Name string
+	Lo   int // -1 if not sliced
+	Hi   int // -1 if not sliced
+}
+
+// Names of the constant signals
+const (
+	True  = "true"
+	False = "false"
+)
+
+// Whether the reference is the constant "true" or "false"
+func (ref PinRef) IsConst() bool {
+	return ref.Name == True || ref.Name == False
+}
+
+// Whether the reference selects part of a bus
+func (ref PinRef) IsSliced() bool {
+	return ref.Lo != -1
+}
+
+// Returns the number of bits referenced, given the width of the whole pin
+func (ref PinRef) Width(pinWidth int) int {
+	if !ref.IsSliced() {
+		return pinWidth
+	}
+
+	return ref.Hi - ref.Lo + 1
+}
+
+func (ref PinRef) String() string {
+	switch {
+	case !ref.IsSliced():
+		return ref.Name
+	case ref.Lo == ref.Hi:
+		return ref.Name + "[" + itoa(ref.Lo) + "]"
+	default:
+		return ref.Name + "[" + itoa(ref.Lo) + ".." + itoa(ref.Hi) + "]"
+	}
+}
+
+// Returns the declared input or output pin called name
+func (chip *Chip) Pin(name string) (Pin, bool) {
+	for _, pins := range [][]Pin{chip.In, chip.Out} {
+		for _, p := range pins {
+			if p.Name == name {
+				return p, true
+			}
+		}
+	}
+
+	return Pin{}, false
+}
+
+// Whether name is one of the chip's input pins
+func (chip *Chip) IsInput(name string) bool {
+	for _, p := range chip.In {
+		if p.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Whether name is one of the chip's output pins
+func (chip *Chip) IsOutput(name string) bool {
+	for _, p := range chip.Out {
+		if p.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
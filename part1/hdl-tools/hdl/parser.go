@@ -0,0 +1,309 @@
+package hdl
+
+// This file contains the HDL parser.
+
+import (
+	"os"
+	"strconv"
+)
+
+// Reads and parses a single .hdl file
+func ParseFile(path string) (*Chip, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(path, string(src))
+}
+
+// Parses the source of a single chip. file is only used in error messages.
+func Parse(file string, src string) (*Chip, error) {
+	tokens, err := tokenize(file, src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{file: file, tokens: tokens}
+	return p.chip()
+}
+
+type parser struct {
+	file   string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// Consumes the next token if its text is s
+func (p *parser) accept(s string) bool {
+	if t := p.peek(); t.kind != tokEOF && t.text == s {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.unexpected(strconv.Quote(s))
+	}
+
+	return nil
+}
+
+func (p *parser) ident(what string) (token, error) {
+	t := p.peek()
+	if t.kind != tokIdent {
+		return t, p.unexpected(what)
+	}
+
+	return p.next(), nil
+}
+
+func (p *parser) number() (int, error) {
+	t := p.peek()
+	if t.kind != tokNumber {
+		return 0, p.unexpected("a number")
+	}
+	p.next()
+
+	return strconv.Atoi(t.text)
+}
+
+func (p *parser) unexpected(want string) error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return errorf(p.file, t.line, "expected %s, got end of file", want)
+	}
+
+	return errorf(p.file, t.line, "expected %s, got %q", want, t.text)
+}
+
+// CHIP Name { [IN pins;] [OUT pins;] PARTS: parts | BUILTIN Name; [CLOCKED pins;] }
+func (p *parser) chip() (*Chip, error) {
+	if err := p.expect("CHIP"); err != nil {
+		return nil, err
+	}
+
+	name, err := p.ident("chip name")
+	if err != nil {
+		return nil, err
+	}
+	chip := &Chip{Name: name.text, File: p.file, Line: name.line}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	if p.accept("IN") {
+		if chip.In, err = p.pinDecls(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.accept("OUT") {
+		if chip.Out, err = p.pinDecls(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case p.accept("PARTS"):
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		for p.peek().kind == tokIdent {
+			part, err := p.part()
+			if err != nil {
+				return nil, err
+			}
+			chip.Parts = append(chip.Parts, part)
+		}
+
+	case p.accept("BUILTIN"):
+		if err := p.builtin(chip); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, p.unexpected(`"PARTS:" or "BUILTIN"`)
+	}
+
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorf(p.file, t.line, "unexpected %q after end of chip", t.text)
+	}
+
+	return chip, nil
+}
+
+// Name[width], Name, ... ;
+func (p *parser) pinDecls() ([]Pin, error) {
+	var pins []Pin
+
+	for {
+		name, err := p.ident("pin name")
+		if err != nil {
+			return nil, err
+		}
+		pin := Pin{Name: name.text, Width: 1, Line: name.line}
+
+		if p.accept("[") {
+			if pin.Width, err = p.number(); err != nil {
+				return nil, err
+			}
+			if pin.Width < 1 || pin.Width > MaxWidth {
+				return nil, errorf(p.file, name.line, "width of %q must be 1-%d", pin.Name, MaxWidth)
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		}
+
+		pins = append(pins, pin)
+
+		if p.accept(";") {
+			return pins, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Name(conn, conn, ...);
+func (p *parser) part() (Part, error) {
+	name := p.next()
+	part := Part{Name: name.text, Line: name.line}
+
+	if err := p.expect("("); err != nil {
+		return part, err
+	}
+
+	for {
+		line := p.peek().line
+
+		partPin, err := p.pinRef()
+		if err != nil {
+			return part, err
+		}
+		if err := p.expect("="); err != nil {
+			return part, err
+		}
+		signal, err := p.pinRef()
+		if err != nil {
+			return part, err
+		}
+
+		part.Conns = append(part.Conns, Conn{Part: partPin, Signal: signal, Line: line})
+
+		if p.accept(")") {
+			break
+		}
+		if err := p.expect(","); err != nil {
+			return part, err
+		}
+	}
+
+	return part, p.expect(";")
+}
+
+// Name, Name[i] or Name[i..j]
+func (p *parser) pinRef() (PinRef, error) {
+	name, err := p.ident("pin name")
+	if err != nil {
+		return PinRef{}, err
+	}
+	ref := PinRef{Name: name.text, Lo: -1, Hi: -1}
+
+	if !p.accept("[") {
+		return ref, nil
+	}
+
+	if ref.Lo, err = p.number(); err != nil {
+		return ref, err
+	}
+	ref.Hi = ref.Lo
+
+	if p.accept("..") {
+		if ref.Hi, err = p.number(); err != nil {
+			return ref, err
+		}
+		if ref.Hi < ref.Lo {
+			return ref, errorf(p.file, name.line, "invalid slice %s", ref)
+		}
+	}
+
+	return ref, p.expect("]")
+}
+
+// BUILTIN Name; [CLOCKED pin, pin, ...;]
+// The clocked pins are taken from the Go implementation, so they are only
+// checked for syntax.
+func (p *parser) builtin(chip *Chip) error {
+	name, err := p.ident("built-in chip name")
+	if err != nil {
+		return err
+	}
+	if err := p.expect(";"); err != nil {
+		return err
+	}
+
+	b, found := builtins[name.text]
+	if !found {
+		return errorf(p.file, name.line, "unknown built-in chip %q", name.text)
+	}
+	if !samePins(chip.In, b.In) || !samePins(chip.Out, b.Out) {
+		return errorf(p.file, name.line, "pins of %s don't match the built-in chip %s", chip.Name, name.text)
+	}
+	chip.Builtin = b
+
+	if p.accept("CLOCKED") {
+		for {
+			if _, err := p.ident("pin name"); err != nil {
+				return err
+			}
+			if p.accept(";") {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Whether two pin lists declare the same names and widths, in the same order
+func samePins(x []Pin, y []Pin) bool {
+	if len(x) != len(y) {
+		return false
+	}
+
+	for i := range x {
+		if x[i].Name != y[i].Name || x[i].Width != y[i].Width {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,103 @@
+package hdl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `// A comment
+/** A doc
+  * comment */
+CHIP Test {
+    IN a[16], b, // trailing comment
+       sel[2];
+    OUT out[16], zr;
+
+    PARTS:
+    /* inline */ Foo(in=a[0..14], x=true, y=b, out[3]=w, out=out);
+    Bar(in[0]=false, out=zr);
+}`
+
+	chip, err := Parse("Test.hdl", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Chip{
+		Name: "Test",
+		File: "Test.hdl",
+		Line: 4,
+		In:   []Pin{{"a", 16, 5}, {"b", 1, 5}, {"sel", 2, 6}},
+		Out:  []Pin{{"out", 16, 7}, {"zr", 1, 7}},
+		Parts: []Part{
+			{Name: "Foo", Line: 10, Conns: []Conn{
+				{PinRef{"in", -1, -1}, PinRef{"a", 0, 14}, 10},
+				{PinRef{"x", -1, -1}, PinRef{"true", -1, -1}, 10},
+				{PinRef{"y", -1, -1}, PinRef{"b", -1, -1}, 10},
+				{PinRef{"out", 3, 3}, PinRef{"w", -1, -1}, 10},
+				{PinRef{"out", -1, -1}, PinRef{"out", -1, -1}, 10},
+			}},
+			{Name: "Bar", Line: 11, Conns: []Conn{
+				{PinRef{"in", 0, 0}, PinRef{"false", -1, -1}, 11},
+				{PinRef{"out", -1, -1}, PinRef{"zr", -1, -1}, 11},
+			}},
+		},
+	}
+
+	if !reflect.DeepEqual(chip, want) {
+		t.Errorf("Parse returned\n%+v\nwant\n%+v", chip, want)
+	}
+}
+
+func TestParseBuiltin(t *testing.T) {
+	src := `CHIP DFF {
+    IN in;
+    OUT out;
+    BUILTIN DFF;
+    CLOCKED in;
+}`
+
+	chip, err := Parse("DFF.hdl", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if chip.Builtin != builtins["DFF"] {
+		t.Errorf("chip.Builtin = %v, want the DFF built-in", chip.Builtin)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"CHIP {", `Bad.hdl:1: expected chip name, got "{"`},
+		{"CHIP X {\n IN a[0];", `Bad.hdl:2: width of "a" must be 1-16`},
+		{"CHIP X {\n IN a[17];", `Bad.hdl:2: width of "a" must be 1-16`},
+		{"CHIP X {\n IN a;\n OUT b;\n}", `Bad.hdl:4: expected "PARTS:" or "BUILTIN", got "}"`},
+		{"CHIP X {\n PARTS:\n Not(in=a out=b);\n}", `Bad.hdl:3: expected ",", got "out"`},
+		{"CHIP X {\n PARTS:\n Not(in=a[3..1], out=b);\n}", `Bad.hdl:3: invalid slice a[3..1]`},
+		{"CHIP X {\n PARTS:\n Not(in=a, out=b)\n}", `Bad.hdl:4: expected ";", got "}"`},
+		{"CHIP X {\n PARTS:\n}\n}", `Bad.hdl:4: unexpected "}" after end of chip`},
+		{"CHIP X {\n IN a;\n OUT out;\n BUILTIN Foo;\n}", `Bad.hdl:4: unknown built-in chip "Foo"`},
+		{"CHIP X {\n IN a;\n OUT out;\n BUILTIN DFF;\n}", `Bad.hdl:4: pins of X don't match the built-in chip DFF`},
+		{"CHIP X {\n /* never closed", `Bad.hdl:2: unterminated comment`},
+		{"CHIP X {\n IN a#;", `Bad.hdl:2: unexpected character '#'`},
+		{"CHIP X {", `Bad.hdl:1: expected "PARTS:" or "BUILTIN", got end of file`},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse("Bad.hdl", tt.src)
+		if err == nil {
+			t.Errorf("Parse(%q) returned no error, want %q", tt.src, tt.want)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) = %q, want %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package hdl
+
+// This file contains the simulator. A chip is flattened down to Nand gates and
+// built-in chips connected by single-bit wires, which are then evaluated in
+// dependency order.
+
+import (
+	"sort"
+	"strings"
+)
+
+// A running simulation of a chip
+type Simulator struct {
+	Chip *Chip
+
+	wires    []bool
+	nands    []nand
+	insts    []*instance      // Built-in chips other than Nand
+	order    []step           // Evaluation order of nands and insts
+	pins     map[string][]int // Wires of the chip's own pins
+	internal map[string][]int // Wires of the chip's internal pins
+}
+
+type nand struct {
+	a, b, out int
+	origin    origin
+}
+
+// A built-in chip inside the simulated chip
+type instance struct {
+	chip    *Chip
+	prim    primitive
+	in      [][]int // Wires of every input, in the built-in's declaration order
+	out     [][]int // Wires of every output, in the built-in's declaration order
+	inVals  []uint16
+	outVals []uint16
+	origin  origin
+}
+
+// Where an element of the flattened chip comes from: the part of the simulated
+// chip that contains it, directly or not
+type origin struct {
+	part string
+	line int
+}
+
+// One evaluation step: a nand gate if inst is nil, a built-in chip otherwise
+type step struct {
+	nand int
+	inst *instance
+}
+
+// Builds a simulator for chip, loading its parts through l. Fails if a part
+// can't be found, a connection is invalid, or the chip contains a
+// combinational loop.
+func NewSimulator(l *Loader, chip *Chip) (*Simulator, error) {
+	b := newBuilder(l)
+
+	sim := &Simulator{Chip: chip, pins: map[string][]int{}, internal: map[string][]int{}}
+	for _, pins := range [][]Pin{chip.In, chip.Out} {
+		for _, p := range pins {
+			sim.pins[p.Name] = b.alloc(p.Width)
+		}
+	}
+
+	if err := b.instantiate(chip, sim.pins, sim.internal, nil); err != nil {
+		return nil, err
+	}
+
+	// Replace every wire by the representative of its net
+	for _, wires := range []map[string][]int{sim.pins, sim.internal} {
+		for _, bits := range wires {
+			b.resolve(bits)
+		}
+	}
+	for i := range b.nands {
+		g := &b.nands[i]
+		g.a, g.b, g.out = b.find(g.a), b.find(g.b), b.find(g.out)
+	}
+	for _, inst := range b.insts {
+		for _, bits := range inst.in {
+			b.resolve(bits)
+		}
+		for _, bits := range inst.out {
+			b.resolve(bits)
+		}
+	}
+
+	sim.wires = make([]bool, len(b.parent))
+	sim.wires[wireTrue] = true
+	sim.nands = b.nands
+	sim.insts = b.insts
+
+	if err := sim.sort(); err != nil {
+		return nil, err
+	}
+
+	sim.Eval()
+	return sim, nil
+}
+
+// Load the chip called name and build a simulator for it
+func Simulate(l *Loader, name string) (*Simulator, error) {
+	chip, err := l.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSimulator(l, chip)
+}
+
+// Orders the nands and built-in chips so every element comes after the
+// elements driving its (non-clocked) inputs
+func (sim *Simulator) sort() error {
+	n := len(sim.nands) + len(sim.insts)
+	driver := make([]int, len(sim.wires)) // Element driving each wire, -1 if none
+	for i := range driver {
+		driver[i] = -1
+	}
+
+	for i, g := range sim.nands {
+		driver[g.out] = i
+	}
+	for i, inst := range sim.insts {
+		for _, bits := range inst.out {
+			for _, w := range bits {
+				driver[w] = len(sim.nands) + i
+			}
+		}
+	}
+
+	users := make([][]int, n) // Elements reading the outputs of each element
+	waiting := make([]int, n) // Number of unordered drivers of each element
+	addDeps := func(user int, bits ...int) {
+		for _, w := range bits {
+			if d := driver[w]; d != -1 {
+				users[d] = append(users[d], user)
+				waiting[user]++
+			}
+		}
+	}
+
+	for i, g := range sim.nands {
+		addDeps(i, g.a, g.b)
+	}
+	for i, inst := range sim.insts {
+		for j, p := range inst.chip.Builtin.In {
+			if !inst.chip.Builtin.IsClocked(p.Name) {
+				addDeps(len(sim.nands)+i, inst.in[j]...)
+			}
+		}
+	}
+
+	var queue []int
+	for i := 0; i < n; i++ {
+		if waiting[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+
+	for len(queue) != 0 {
+		i := queue[0]
+		queue = queue[1:]
+
+		if i < len(sim.nands) {
+			sim.order = append(sim.order, step{nand: i})
+		} else {
+			sim.order = append(sim.order, step{inst: sim.insts[i-len(sim.nands)]})
+		}
+
+		for _, u := range users[i] {
+			waiting[u]--
+			if waiting[u] == 0 {
+				queue = append(queue, u)
+			}
+		}
+	}
+
+	if len(sim.order) == n {
+		return nil
+	}
+
+	// Whatever is still waiting is on, or behind, a loop. Report the parts of
+	// the simulated chip they come from.
+	seen := map[origin]bool{}
+	var parts []string
+	for i := 0; i < n; i++ {
+		if waiting[i] == 0 {
+			continue
+		}
+
+		o := sim.originOf(i)
+		if !seen[o] {
+			seen[o] = true
+			parts = append(parts, o.part+" (line "+itoa(o.line)+")")
+		}
+	}
+	sort.Strings(parts)
+
+	return errorf(sim.Chip.File, sim.Chip.Line, "combinational loop through %s", strings.Join(parts, ", "))
+}
+
+func (sim *Simulator) originOf(element int) origin {
+	if element < len(sim.nands) {
+		return sim.nands[element].origin
+	}
+
+	return sim.insts[element-len(sim.nands)].origin
+}
+
+// Recompute every combinational value from the inputs and the clocked state
+func (sim *Simulator) Eval() {
+	w := sim.wires
+
+	for _, s := range sim.order {
+		if s.inst == nil {
+			g := &sim.nands[s.nand]
+			w[g.out] = !(w[g.a] && w[g.b])
+			continue
+		}
+
+		inst := s.inst
+		for i, bits := range inst.in {
+			inst.inVals[i] = sim.read(bits)
+		}
+		inst.prim.eval(inst.inVals, inst.outVals)
+		for i, bits := range inst.out {
+			sim.write(bits, inst.outVals[i])
+		}
+	}
+}
+
+// Rising clock edge: every clocked chip samples its inputs. Outputs don't
+// change until Tock.
+func (sim *Simulator) Tick() {
+	sim.Eval()
+
+	for _, inst := range sim.insts {
+		for i, bits := range inst.in {
+			inst.inVals[i] = sim.read(bits)
+		}
+		inst.prim.tick(inst.inVals)
+	}
+}
+
+// Falling clock edge: every clocked chip commits the values sampled by Tick,
+// and the outputs are recomputed
+func (sim *Simulator) Tock() {
+	for _, inst := range sim.insts {
+		inst.prim.tock()
+	}
+
+	sim.Eval()
+}
+
+// Set an input pin of the chip. Call Eval to propagate the new value.
+func (sim *Simulator) Set(name string, value uint16) error {
+	if !sim.Chip.IsInput(name) {
+		return &Error{File: sim.Chip.Name, Msg: "no input pin called " + name}
+	}
+
+	sim.write(sim.pins[name], value)
+	return nil
+}
+
+// Returns the value of a pin of the chip: an input, an output or an internal
+// pin
+func (sim *Simulator) Get(name string) (uint16, error) {
+	bits, found := sim.pins[name]
+	if !found {
+		bits, found = sim.internal[name]
+	}
+	if !found {
+		return 0, &Error{File: sim.Chip.Name, Msg: "no pin called " + name}
+	}
+
+	return sim.read(bits), nil
+}
+
+// Returns the width of a pin of the chip, or 0 if there is no such pin
+func (sim *Simulator) Width(name string) int {
+	if bits, found := sim.pins[name]; found {
+		return len(bits)
+	}
+
+	return len(sim.internal[name])
+}
+
+// Returns the state of the first built-in part called chip, e.g. the contents
+// of "RAM16K" or the value of "ARegister". Writing to it changes the state.
+// Returns nil if there is no such part, or if it has no state.
+func (sim *Simulator) Memory(chip string) []uint16 {
+	for _, inst := range sim.insts {
+		if inst.chip.Name == chip {
+			return inst.prim.memory()
+		}
+	}
+
+	return nil
+}
+
+func (sim *Simulator) read(bits []int) uint16 {
+	var v uint16
+	for i, w := range bits {
+		if sim.wires[w] {
+			v |= 1 << i
+		}
+	}
+
+	return v
+}
+
+func (sim *Simulator) write(bits []int, v uint16) {
+	for i, w := range bits {
+		// The constant wires can't be overwritten
+		if w > wireTrue {
+			sim.wires[w] = v&(1<<i) != 0
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package hdl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The chips of projects 1 and 2 in this repository
+func repoLoader() *Loader {
+	return NewLoader("../../project2", "../../project1")
+}
+
+// Writes the chips in srcs (name -> source) to a temporary directory and
+// returns a loader for it, backed by the repository's chips
+func tempLoader(t *testing.T, srcs map[string]string) *Loader {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, src := range srcs {
+		if err := os.WriteFile(filepath.Join(dir, name+".hdl"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return NewLoader(dir, "../../project2", "../../project1")
+}
+
+func simulate(t *testing.T, l *Loader, name string) *Simulator {
+	t.Helper()
+
+	sim, err := Simulate(l, name)
+	if err != nil {
+		t.Fatalf("Simulate(%s) returned error: %s", name, err)
+	}
+
+	return sim
+}
+
+func TestSimulateCombinational(t *testing.T) {
+	tests := []struct {
+		chip string
+		in   map[string]uint16
+		want map[string]uint16
+	}{
+		{"Not", map[string]uint16{"in": 0}, map[string]uint16{"out": 1}},
+		{"Xor", map[string]uint16{"a": 1, "b": 1}, map[string]uint16{"out": 0}},
+		{"Xor", map[string]uint16{"a": 0, "b": 1}, map[string]uint16{"out": 1}},
+		{"And16", map[string]uint16{"a": 0xF0F0, "b": 0xFF00}, map[string]uint16{"out": 0xF000}},
+		{"Or8Way", map[string]uint16{"in": 0x10}, map[string]uint16{"out": 1}},
+		{"Mux8Way16", map[string]uint16{"a": 1, "f": 6, "sel": 5}, map[string]uint16{"out": 6}},
+		{"DMux8Way", map[string]uint16{"in": 1, "sel": 3}, map[string]uint16{"a": 0, "d": 1, "h": 0}},
+		{"Add16", map[string]uint16{"a": 0xFFFF, "b": 2}, map[string]uint16{"out": 1}},
+		{"Inc16", map[string]uint16{"in": 41}, map[string]uint16{"out": 42}},
+
+		// ALU: x=17, y=3
+		{"ALU", map[string]uint16{"x": 17, "y": 3, "zx": 1, "nx": 0, "zy": 1, "ny": 0, "f": 1, "no": 0},
+			map[string]uint16{"out": 0, "zr": 1, "ng": 0}},
+		{"ALU", map[string]uint16{"x": 17, "y": 3, "zx": 1, "nx": 1, "zy": 1, "ny": 0, "f": 1, "no": 0},
+			map[string]uint16{"out": 0xFFFF, "zr": 0, "ng": 1}},
+		{"ALU", map[string]uint16{"x": 17, "y": 3, "zx": 0, "nx": 0, "zy": 0, "ny": 0, "f": 1, "no": 0},
+			map[string]uint16{"out": 20, "zr": 0, "ng": 0}},
+		{"ALU", map[string]uint16{"x": 17, "y": 3, "zx": 0, "nx": 1, "zy": 0, "ny": 0, "f": 1, "no": 1},
+			map[string]uint16{"out": 14, "zr": 0, "ng": 0}},
+		{"ALU", map[string]uint16{"x": 17, "y": 3, "zx": 0, "nx": 0, "zy": 0, "ny": 0, "f": 0, "no": 0},
+			map[string]uint16{"out": 1, "zr": 0, "ng": 0}},
+	}
+
+	l := repoLoader()
+	for _, tt := range tests {
+		sim := simulate(t, l, tt.chip)
+
+		for pin, v := range tt.in {
+			if err := sim.Set(pin, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sim.Eval()
+
+		for pin, want := range tt.want {
+			got, err := sim.Get(pin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("%s%v: %s = %d, want %d", tt.chip, tt.in, pin, got, want)
+			}
+		}
+	}
+}
+
+func TestSimulatePC(t *testing.T) {
+	l := NewLoader("../../project3", "../../project2", "../../project1")
+	sim := simulate(t, l, "PC")
+
+	steps := []struct {
+		in, load, inc, reset uint16
+		want                 uint16
+	}{
+		{0, 0, 0, 0, 0},
+		{0, 0, 1, 0, 1},
+		{0, 0, 1, 0, 2},
+		{100, 1, 1, 0, 100},
+		{0, 0, 1, 0, 101},
+		{0, 0, 0, 0, 101},
+		{7, 1, 0, 1, 0},
+	}
+
+	for i, s := range steps {
+		sim.Set("in", s.in)
+		sim.Set("load", s.load)
+		sim.Set("inc", s.inc)
+		sim.Set("reset", s.reset)
+		sim.Tick()
+		sim.Tock()
+
+		if got, _ := sim.Get("out"); got != s.want {
+			t.Errorf("step %d: out = %d, want %d", i, got, s.want)
+		}
+	}
+}
+
+func TestSimulateTickTock(t *testing.T) {
+	l := NewLoader("../../project3", "../../project2", "../../project1")
+	sim := simulate(t, l, "Bit")
+
+	sim.Set("in", 1)
+	sim.Set("load", 1)
+	sim.Tick()
+	if got, _ := sim.Get("out"); got != 0 {
+		t.Errorf("out = %d after tick, want 0 until tock", got)
+	}
+
+	sim.Tock()
+	if got, _ := sim.Get("out"); got != 1 {
+		t.Errorf("out = %d after tock, want 1", got)
+	}
+}
+
+func TestSimulateComputer(t *testing.T) {
+	// The memory chips are left to the built-ins, as in the course's tools
+	l := NewLoader("../../project5", "../../project2", "../../project1")
+	sim := simulate(t, l, "Computer")
+
+	// Add.hack: RAM[0] = 2 + 3
+	copy(sim.Memory("ROM32K"), []uint16{2, 0xEC10, 3, 0xE090, 0, 0xE308})
+
+	sim.Set("reset", 1)
+	sim.Tick()
+	sim.Tock()
+	sim.Set("reset", 0)
+	for i := 0; i < 6; i++ {
+		sim.Tick()
+		sim.Tock()
+	}
+
+	if got := sim.Memory("RAM16K")[0]; got != 5 {
+		t.Errorf("RAM16K[0] = %d, want 5", got)
+	}
+	if got := sim.Memory("PC")[0]; got != 6 {
+		t.Errorf("PC[] = %d, want 6", got)
+	}
+}
+
+func TestSimulateErrors(t *testing.T) {
+	tests := []struct {
+		src  string // Source of chip "Test"
+		want string
+	}{
+		{"CHIP Test { IN a; OUT out; PARTS:\n Foo(in=a, out=out); }", "Test.hdl:2: Foo: chip not found"},
+		{"CHIP Test { IN a; OUT out; PARTS:\n Not(x=a, out=out); }", "Test.hdl:2: chip Not has no pin called x"},
+		{"CHIP Test { IN a[16]; OUT out; PARTS:\n Not(in=a, out=out); }", "Test.hdl:2: width mismatch: in has 1 bit(s), a has 16 bit(s)"},
+		{"CHIP Test { IN a[16]; OUT out; PARTS:\n Not(in=a[16], out=out); }", "Test.hdl:2: a[16] is out of range"},
+		{"CHIP Test { IN a; OUT out; PARTS:\n Not(in=a, out=a); }", "Test.hdl:2: output out can't drive input pin a"},
+		{"CHIP Test { IN a; OUT out; PARTS:\n Not(in=out, out=x); }", "Test.hdl:2: output pin out of Test can't be used as an input"},
+		{"CHIP Test { IN a; OUT out; PARTS:\n Not(in=a, out=true); }", "Test.hdl:2: output out can't be connected to true"},
+		{"CHIP Test { IN a; OUT out; PARTS:\n Not16(in=a, out=x);\n Not(in=x[0], out=out); }", "Test.hdl:2: width mismatch"},
+		{"CHIP Test { IN a[2]; OUT out; PARTS:\n Not16(in[0..1]=a, out=x);\n Not(in=x[0], out=out); }", "Test.hdl:3: internal pin x can't be sliced"},
+		{"CHIP Test { IN a; OUT out; PARTS:\n Not(in=a, out=out);\n Not(in=a, out=out); }", "Test.hdl:3: out is driven by more than one part"},
+		{"CHIP Test { IN a; OUT out; PARTS:\n Test(a=a, out=out); }", "chip Test contains itself"},
+		{"CHIP Test { IN a; OUT out; PARTS:\n Not(in=y, out=x);\n Not(in=x, out=y, out=out); }", "Test.hdl:1: combinational loop through Not (line 2), Not (line 3)"},
+	}
+
+	for _, tt := range tests {
+		l := tempLoader(t, map[string]string{"Test": tt.src})
+
+		_, err := Simulate(l, "Test")
+		if err == nil {
+			t.Errorf("Simulate(%q) returned no error, want %q", tt.src, tt.want)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Simulate(%q) = %q, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestSimulateClockedLoopIsFine(t *testing.T) {
+	// A toggle flip-flop: the loop goes through a DFF, so it's not
+	// combinational
+	l := tempLoader(t, map[string]string{
+		"Test": "CHIP Test { OUT out; PARTS:\n Not(in=q, out=d);\n DFF(in=d, out=q, out=out); }",
+	})
+	sim := simulate(t, l, "Test")
+
+	for i, want := range []uint16{1, 0, 1} {
+		sim.Tick()
+		sim.Tock()
+
+		if got, _ := sim.Get("out"); got != want {
+			t.Errorf("cycle %d: out = %d, want %d", i, got, want)
+		}
+	}
+}
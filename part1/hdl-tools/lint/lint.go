@@ -0,0 +1,231 @@
+// Package lint finds wiring mistakes in HDL chips without simulating them:
+// pins read but never driven, width mismatches, unassigned outputs, unused
+// internal pins and combinational loops.
+package lint
+
+import (
+	"fmt"
+	"nand2tetris/hdl-tools/hdl"
+	"sort"
+	"strings"
+)
+
+// A problem found in a chip
+type Problem struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Msg)
+}
+
+// Checks chips, looking up their parts with a loader. The combinational paths
+// through every part are computed once and cached.
+type Linter struct {
+	loader *hdl.Loader
+	paths  map[string]map[string][]string // Chip name -> input -> outputs it reaches
+}
+
+func New(l *hdl.Loader) *Linter {
+	return &Linter{loader: l, paths: map[string]map[string][]string{}}
+}
+
+// A use of an internal pin by a part
+type use struct {
+	conn  hdl.Conn
+	part  string
+	width int
+}
+
+// A combinational path from one signal to another, through a part
+type edge struct {
+	to   string
+	part int // Index in the chip's parts
+}
+
+// Returns the problems found in chip, ordered by line. Problems in the
+// chip's parts are only reported when checking the parts themselves.
+func (lt *Linter) Check(chip *hdl.Chip) []Problem {
+	if chip.Builtin != nil {
+		return nil
+	}
+
+	var problems []Problem
+	report := func(line int, format string, a ...interface{}) {
+		problems = append(problems, Problem{File: chip.File, Line: line, Msg: fmt.Sprintf(format, a...)})
+	}
+
+	drivers := map[string][]use{} // Internal pin -> parts driving it
+	readers := map[string][]use{} // Internal pin -> parts reading it
+	assigned := map[string][]bool{}
+	for _, p := range chip.Out {
+		assigned[p.Name] = make([]bool, p.Width)
+	}
+
+	// Set if a part can't be loaded. The pins it would drive can't be checked.
+	incomplete := false
+
+	for _, part := range chip.Parts {
+		def, err := lt.loader.Load(part.Name)
+		if err != nil {
+			report(part.Line, "%s", err)
+			incomplete = true
+			continue
+		}
+
+		for _, conn := range part.Conns {
+			pin, found := def.Pin(conn.Part.Name)
+			if !found {
+				report(conn.Line, "chip %s has no pin called %s", def.Name, conn.Part.Name)
+				continue
+			}
+			if conn.Part.IsSliced() && conn.Part.Hi >= pin.Width {
+				report(conn.Line, "%s is out of range, %s.%s has %d bit(s)", conn.Part, def.Name, pin.Name, pin.Width)
+				continue
+			}
+
+			width := conn.Part.Width(pin.Width)
+			isOutput := def.IsOutput(pin.Name)
+			sig := conn.Signal
+
+			switch {
+			case sig.IsConst():
+				if isOutput {
+					report(conn.Line, "output %s can't be connected to %s", conn.Part, sig.Name)
+				}
+
+			case chip.IsInput(sig.Name) || chip.IsOutput(sig.Name):
+				if isOutput && chip.IsInput(sig.Name) {
+					report(conn.Line, "output %s can't drive input pin %s of %s", conn.Part, sig.Name, chip.Name)
+					continue
+				}
+				if !isOutput && chip.IsOutput(sig.Name) {
+					report(conn.Line, "output pin %s of %s can't be used as an input", sig.Name, chip.Name)
+					continue
+				}
+
+				p, _ := chip.Pin(sig.Name)
+				if sig.IsSliced() && sig.Hi >= p.Width {
+					report(conn.Line, "%s is out of range, %s has %d bit(s)", sig, sig.Name, p.Width)
+					continue
+				}
+				if w := sig.Width(p.Width); w != width {
+					report(conn.Line, "width mismatch: %s has %d bit(s), %s has %d bit(s)", conn.Part, width, sig, w)
+				}
+
+				if isOutput {
+					bits := assigned[sig.Name]
+					lo := 0
+					if sig.IsSliced() {
+						lo = sig.Lo
+					}
+					for i := lo; i < lo+sig.Width(p.Width); i++ {
+						if bits[i] {
+							report(conn.Line, "%s is driven by more than one part", sig)
+							break
+						}
+						bits[i] = true
+					}
+				}
+
+			default:
+				if sig.IsSliced() {
+					report(conn.Line, "internal pin %s can't be sliced", sig.Name)
+					continue
+				}
+
+				u := use{conn: conn, part: part.Name, width: width}
+				if isOutput {
+					drivers[sig.Name] = append(drivers[sig.Name], u)
+				} else {
+					readers[sig.Name] = append(readers[sig.Name], u)
+				}
+			}
+		}
+	}
+
+	if incomplete {
+		sortProblems(problems)
+		return problems
+	}
+
+	for _, name := range internalPins(drivers, readers) {
+		ds, rs := drivers[name], readers[name]
+
+		switch {
+		case len(ds) == 0:
+			report(rs[0].conn.Line, "internal pin %s is read but never driven", name)
+			continue
+		case len(rs) == 0:
+			report(ds[0].conn.Line, "internal pin %s is never used", name)
+		}
+
+		for _, d := range ds[1:] {
+			report(d.conn.Line, "%s is driven by more than one part", name)
+		}
+		for _, r := range rs {
+			if r.width != ds[0].width {
+				report(r.conn.Line, "width mismatch: %s has %d bit(s), %s has %d bit(s)", r.conn.Part, r.width, name, ds[0].width)
+			}
+		}
+	}
+
+	for _, p := range chip.Out {
+		// Runs of unassigned bits, e.g. "out[8..15]"
+		var missing []string
+		bits := assigned[p.Name]
+		for lo := 0; lo < len(bits); lo++ {
+			if bits[lo] {
+				continue
+			}
+			hi := lo
+			for hi+1 < len(bits) && !bits[hi+1] {
+				hi++
+			}
+			missing = append(missing, hdl.PinRef{Name: p.Name, Lo: lo, Hi: hi}.String())
+			lo = hi
+		}
+
+		switch {
+		case len(missing) == 1 && missing[0] == (hdl.PinRef{Name: p.Name, Lo: 0, Hi: p.Width - 1}).String():
+			report(p.Line, "output pin %s is never assigned", p.Name)
+		case len(missing) != 0:
+			report(p.Line, "%s of output pin %s never assigned", strings.Join(missing, ", "), p.Name)
+		}
+	}
+
+	for _, loop := range loops(chip, lt.graph(chip)) {
+		var parts []string
+		for _, part := range loop {
+			parts = append(parts, fmt.Sprintf("%s (line %d)", part.Name, part.Line))
+		}
+		report(loop[0].Line, "combinational loop through %s", strings.Join(parts, ", "))
+	}
+
+	sortProblems(problems)
+	return problems
+}
+
+func sortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+}
+
+// Returns the names of the internal pins, sorted
+func internalPins(drivers, readers map[string][]use) []string {
+	var names []string
+	for name := range drivers {
+		names = append(names, name)
+	}
+	for name := range readers {
+		if _, found := drivers[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,208 @@
+package lint
+
+import (
+	"nand2tetris/hdl-tools/hdl"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes chips to a temporary directory and returns a loader for it
+func tempLoader(t *testing.T, chips map[string]string) *hdl.Loader {
+	dir := t.TempDir()
+	for name, src := range chips {
+		if err := os.WriteFile(filepath.Join(dir, name+".hdl"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return hdl.NewLoader(dir, "../../project1")
+}
+
+func check(t *testing.T, l *hdl.Loader, name string) []string {
+	chip, err := l.Load(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out []string
+	for _, p := range New(l).Check(chip) {
+		out = append(out, p.String()[len(chip.File)+1:])
+	}
+
+	return out
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			"Undriven",
+			`CHIP Undriven { IN a; OUT out;
+			PARTS:
+			And(a=a, b=x, out=out);
+			}`,
+			[]string{"3: internal pin x is read but never driven"},
+		},
+		{
+			"Width",
+			`CHIP Width { IN a[16]; OUT out[16];
+			PARTS:
+			Not16(in=a, out[0..14]=w);
+			Not16(in=w, out=out);
+			}`,
+			[]string{"4: width mismatch: in has 16 bit(s), w has 15 bit(s)"},
+		},
+		{
+			"PinWidth",
+			`CHIP PinWidth { IN a[16]; OUT out[16];
+			PARTS:
+			Not16(in=a[0..14], out=out);
+			Not(in=a, out=out[4..7]);
+			}`,
+			[]string{
+				"3: width mismatch: in has 16 bit(s), a[0..14] has 15 bit(s)",
+				"4: width mismatch: in has 1 bit(s), a has 16 bit(s)",
+				"4: width mismatch: out has 1 bit(s), out[4..7] has 4 bit(s)",
+				"4: out[4..7] is driven by more than one part",
+			},
+		},
+		{
+			"Unassigned",
+			`CHIP Unassigned { IN a[16]; OUT out[16], other;
+			PARTS:
+			Not16(in=a, out[0..7]=out[0..7], out[12]=out[12]);
+			}`,
+			[]string{
+				"1: out[8..11], out[13..15] of output pin out never assigned",
+				"1: output pin other is never assigned",
+			},
+		},
+		{
+			"Unused",
+			`CHIP Unused { IN a; OUT out;
+			PARTS:
+			Not(in=a, out=out, out=spare);
+			}`,
+			[]string{"3: internal pin spare is never used"},
+		},
+		{
+			"Twice",
+			`CHIP Twice { IN a; OUT out;
+			PARTS:
+			Not(in=a, out=out);
+			Not(in=a, out=out, out=x);
+			And(a=x, b=x, out=y);
+			Not(in=a, out=y);
+			}`,
+			[]string{
+				"4: out is driven by more than one part",
+				"5: internal pin y is never used",
+				"6: y is driven by more than one part",
+			},
+		},
+		{
+			"Loop",
+			`CHIP Loop { IN a; OUT out;
+			PARTS:
+			Not(in=y, out=x, out=out);
+			Nand(a=a, b=x, out=y);
+			}`,
+			[]string{"3: combinational loop through Not (line 3), Nand (line 4)"},
+		},
+		{
+			"SubLoop",
+			`CHIP SubLoop { IN a; OUT out;
+			PARTS:
+			Buf(in=x, out=y);
+			Or(a=a, b=y, out=x, out=out);
+			}`,
+			[]string{"3: combinational loop through Buf (line 3), Or (line 4)"},
+		},
+		{
+			"Clocked",
+			`CHIP Clocked { IN a; OUT out;
+			PARTS:
+			DFF(in=y, out=x);
+			Or(a=a, b=x, out=y, out=out);
+			Bit(in=z, load=true, out=w);
+			Not(in=w, out=z);
+			Buf(in=a, out=b);
+			And(a=b, b=w, out=c);
+			Not(in=c, out=d);
+			Or(a=d, b=false, out=e);
+			DFF(in=e, out=f);
+			Not(in=f, out=g);
+			Cut(in=g, out=b2);
+			And(a=b2, b=true, out=e2);
+			Cut(in=e2, out=g2);
+			Not(in=g2);
+			}`,
+			nil,
+		},
+	}
+
+	chips := map[string]string{
+		"Buf": `CHIP Buf { IN in; OUT out; PARTS: Not(in=in, out=n); Not(in=n, out=out); }`,
+		// Its input never reaches its output
+		"Cut": `CHIP Cut { IN in; OUT out; PARTS: DFF(in=in, out=out); }`,
+	}
+	for _, test := range tests {
+		chips[test.name] = test.src
+	}
+	l := tempLoader(t, chips)
+
+	for _, test := range tests {
+		got := check(t, l, test.name)
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("Check(%s) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCheckUnknownPart(t *testing.T) {
+	l := tempLoader(t, map[string]string{
+		"Unknown": `CHIP Unknown { IN a; OUT out;
+		PARTS:
+		Frobnicate(in=a, out=out);
+		Not(in=a, out=out, nope=a);
+		}`,
+	})
+
+	got := check(t, l, "Unknown")
+	if len(got) != 2 || !strings.HasPrefix(got[0], "3: ") || got[1] != "4: chip Not has no pin called nope" {
+		t.Errorf("Check(Unknown) = %q", got)
+	}
+}
+
+// The course chips are wired correctly
+func TestCheckRepoChips(t *testing.T) {
+	l := hdl.NewLoader("../../project5", "../../project2", "../../project1")
+
+	for _, dir := range []string{"../../project1", "../../project5"} {
+		files, err := filepath.Glob(filepath.Join(dir, "*.hdl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, file := range files {
+			chip, err := l.LoadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, p := range New(l).Check(chip) {
+				// Memory.hdl names the keyboard's (unused) load signal on
+				// purpose
+				if chip.Name == "Memory" && strings.Contains(p.Msg, "never used") {
+					continue
+				}
+				t.Errorf("%s", p)
+			}
+		}
+	}
+}
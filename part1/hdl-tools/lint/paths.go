@@ -0,0 +1,181 @@
+package lint
+
+// This file contains the analysis of combinational paths. A path goes from a
+// part's input to its outputs unless the input only reaches them through the
+// clock, as the inputs of DFF and the memory chips do.
+
+import (
+	"nand2tetris/hdl-tools/hdl"
+	"sort"
+)
+
+// Returns the combinational paths between the signals of chip: for each
+// signal, the signals it immediately affects and the parts in between
+func (lt *Linter) graph(chip *hdl.Chip) map[string][]edge {
+	g := map[string][]edge{}
+
+	for i, part := range chip.Parts {
+		def, err := lt.loader.Load(part.Name)
+		if err != nil {
+			continue
+		}
+		paths := lt.pathsOf(def)
+
+		for _, in := range part.Conns {
+			if in.Signal.IsConst() || !def.IsInput(in.Part.Name) {
+				continue
+			}
+
+			for _, out := range part.Conns {
+				if def.IsOutput(out.Part.Name) && contains(paths[in.Part.Name], out.Part.Name) {
+					g[in.Signal.Name] = append(g[in.Signal.Name], edge{to: out.Signal.Name, part: i})
+				}
+			}
+		}
+	}
+
+	return g
+}
+
+// Returns, for every input of chip, the outputs it reaches combinationally
+func (lt *Linter) pathsOf(chip *hdl.Chip) map[string][]string {
+	if paths, found := lt.paths[chip.Name]; found {
+		return paths
+	}
+
+	paths := map[string][]string{}
+	// Stops the recursion if the chip contains itself
+	lt.paths[chip.Name] = paths
+
+	if chip.Builtin != nil {
+		for _, in := range chip.Builtin.In {
+			if chip.Builtin.IsClocked(in.Name) {
+				continue
+			}
+			for _, out := range chip.Builtin.Out {
+				paths[in.Name] = append(paths[in.Name], out.Name)
+			}
+		}
+		return paths
+	}
+
+	g := lt.graph(chip)
+	for _, in := range chip.In {
+		seen := map[string]bool{in.Name: true}
+		stack := []string{in.Name}
+
+		for len(stack) != 0 {
+			sig := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			for _, e := range g[sig] {
+				if seen[e.to] {
+					continue
+				}
+				seen[e.to] = true
+				stack = append(stack, e.to)
+
+				if chip.IsOutput(e.to) {
+					paths[in.Name] = append(paths[in.Name], e.to)
+				}
+			}
+		}
+	}
+
+	return paths
+}
+
+// Returns the parts of chip on every combinational loop of its graph g, one
+// loop per strongly connected component, ordered by line
+func loops(chip *hdl.Chip, g map[string][]edge) [][]hdl.Part {
+	// Tarjan's algorithm
+	index := map[string]int{}
+	low := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var out [][]hdl.Part
+
+	var visit func(v string)
+	visit = func(v string) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, e := range g[v] {
+			if _, found := index[e.to]; !found {
+				visit(e.to)
+				low[v] = min(low[v], low[e.to])
+			} else if onStack[e.to] {
+				low[v] = min(low[v], index[e.to])
+			}
+		}
+
+		if low[v] != index[v] {
+			return
+		}
+
+		component := map[string]bool{}
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component[w] = true
+			if w == v {
+				break
+			}
+		}
+
+		// The parts on edges inside the component form the loop
+		seen := map[int]bool{}
+		for w := range component {
+			for _, e := range g[w] {
+				if component[e.to] {
+					seen[e.part] = true
+				}
+			}
+		}
+
+		var parts []hdl.Part
+		for i, part := range chip.Parts {
+			if seen[i] {
+				parts = append(parts, part)
+			}
+		}
+		if len(parts) != 0 {
+			out = append(out, parts)
+		}
+	}
+
+	var names []string
+	for v := range g {
+		names = append(names, v)
+	}
+	sort.Strings(names)
+	for _, v := range names {
+		if _, found := index[v]; !found {
+			visit(v)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i][0].Line < out[j][0].Line })
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,341 @@
+// Package stats measures HDL chips once expanded down to Nand gates: how many
+// gates they use, and how deep their longest combinational path is.
+package stats
+
+import (
+	"errors"
+	"nand2tetris/hdl-tools/hdl"
+	"sort"
+)
+
+// The end of a path inside a clocked part (a DFF, register or memory chip)
+// rather than at a pin of the chip
+const State = "(state)"
+
+// Measures of a chip, expanded down to Nand gates and built-in chips
+type Stats struct {
+	Chip  *hdl.Chip
+	Nands int
+	// Built-in chips other than Nand used anywhere in the hierarchy, e.g.
+	// {"DFF": 16}. They contribute no Nand gates.
+	Builtins map[string]int
+	// Longest combinational path, in Nand delays, and its ends: pins of the
+	// chip, or State
+	Depth    int
+	From, To string
+	// The chip's own parts, in declaration order
+	Parts []PartStats
+
+	// Nand delays from each input to each output it reaches, -1 if none
+	comb map[string]map[string]int
+	// Nand delays from internal state to each output, -1 if none
+	start map[string]int
+	// Nand delays from each input to internal state, -1 if none
+	end map[string]int
+	// Nand delays from internal state back to internal state, -1 if none
+	internal int
+}
+
+// Measures of one part of a chip
+type PartStats struct {
+	Name  string
+	Line  int
+	Nands int
+	Depth int
+}
+
+// Computes stats of chips, looking up their parts with a loader. Stats of
+// every chip are computed once and cached.
+type Analyzer struct {
+	loader *hdl.Loader
+	cache  map[string]*Stats
+	// Chips being analyzed, to catch recursion
+	loading map[string]bool
+}
+
+func NewAnalyzer(l *hdl.Loader) *Analyzer {
+	return &Analyzer{loader: l, cache: map[string]*Stats{}, loading: map[string]bool{}}
+}
+
+// Returns the stats of chip
+func (a *Analyzer) Analyze(chip *hdl.Chip) (*Stats, error) {
+	if s, found := a.cache[chip.Name]; found {
+		return s, nil
+	}
+
+	var s *Stats
+	var err error
+	if chip.Builtin != nil {
+		s = builtinStats(chip)
+	} else {
+		if a.loading[chip.Name] {
+			return nil, &hdl.Error{File: chip.File, Line: chip.Line, Msg: "chip " + chip.Name + " contains itself"}
+		}
+		a.loading[chip.Name] = true
+		s, err = a.analyzeParts(chip)
+		delete(a.loading, chip.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.Depth, s.From, s.To = -1, "", ""
+	for _, in := range chip.In {
+		for _, out := range chip.Out {
+			s.longest(s.comb[in.Name][out.Name], in.Name, out.Name)
+		}
+		s.longest(s.end[in.Name], in.Name, State)
+	}
+	for _, out := range chip.Out {
+		s.longest(s.start[out.Name], State, out.Name)
+	}
+	s.longest(s.internal, State, State)
+	if s.Depth == -1 {
+		s.Depth = 0
+	}
+
+	a.cache[chip.Name] = s
+	return s, nil
+}
+
+// Keeps the path from -> to if it's the longest found so far
+func (s *Stats) longest(depth int, from string, to string) {
+	if depth > s.Depth {
+		s.Depth, s.From, s.To = depth, from, to
+	}
+}
+
+// Built-in chips are a Nand gate, or a black box whose reads are immediate and
+// whose writes happen on the clock
+func builtinStats(chip *hdl.Chip) *Stats {
+	s := newStats(chip)
+
+	delay := 0
+	if chip.Name == "Nand" {
+		s.Nands = 1
+		delay = 1
+	} else {
+		s.Builtins[chip.Name] = 1
+		for _, out := range chip.Out {
+			s.start[out.Name] = 0
+		}
+	}
+
+	for _, in := range chip.In {
+		if chip.Builtin.IsClocked(in.Name) {
+			s.end[in.Name] = 0
+			continue
+		}
+		for _, out := range chip.Out {
+			s.comb[in.Name][out.Name] = delay
+		}
+		if len(chip.Builtin.Clocked) != 0 {
+			// e.g. the address of a RAM also selects the word written
+			s.end[in.Name] = 0
+		}
+	}
+
+	return s
+}
+
+func newStats(chip *hdl.Chip) *Stats {
+	s := &Stats{
+		Chip:     chip,
+		Builtins: map[string]int{},
+		comb:     map[string]map[string]int{},
+		start:    map[string]int{},
+		end:      map[string]int{},
+		internal: -1,
+	}
+
+	for _, in := range chip.In {
+		s.comb[in.Name] = map[string]int{}
+		s.end[in.Name] = -1
+		for _, out := range chip.Out {
+			s.comb[in.Name][out.Name] = -1
+		}
+	}
+	for _, out := range chip.Out {
+		s.start[out.Name] = -1
+	}
+
+	return s
+}
+
+// An edge of the signal graph of a chip: a path through a part
+type edge struct {
+	to    string
+	delay int
+}
+
+func (a *Analyzer) analyzeParts(chip *hdl.Chip) (*Stats, error) {
+	s := newStats(chip)
+
+	// Signals of the chip, and the combinational paths between them
+	graph := map[string][]edge{}
+	// Delays from the state of the parts to signals
+	starts := map[string]int{}
+	// Delays from signals to the state of the parts
+	ends := map[string]int{}
+
+	for _, part := range chip.Parts {
+		def, err := a.loader.Load(part.Name)
+		if err != nil {
+			return nil, &hdl.Error{File: chip.File, Line: part.Line, Msg: err.Error()}
+		}
+		ps, err := a.Analyze(def)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Nands += ps.Nands
+		s.internal = max(s.internal, ps.internal)
+		for name, n := range ps.Builtins {
+			s.Builtins[name] += n
+		}
+		s.Parts = append(s.Parts, PartStats{Name: part.Name, Line: part.Line, Nands: ps.Nands, Depth: ps.Depth})
+
+		for _, in := range part.Conns {
+			// Constants never switch, so no path starts from them
+			if !def.IsInput(in.Part.Name) || in.Signal.IsConst() {
+				continue
+			}
+
+			if d := ps.end[in.Part.Name]; d >= 0 {
+				ends[in.Signal.Name] = maxDelay(ends, in.Signal.Name, d)
+			}
+			for _, out := range part.Conns {
+				if !def.IsOutput(out.Part.Name) {
+					continue
+				}
+				if d := ps.comb[in.Part.Name][out.Part.Name]; d >= 0 {
+					graph[in.Signal.Name] = append(graph[in.Signal.Name], edge{to: out.Signal.Name, delay: d})
+				}
+			}
+		}
+
+		for _, out := range part.Conns {
+			if def.IsOutput(out.Part.Name) && ps.start[out.Part.Name] >= 0 {
+				starts[out.Signal.Name] = maxDelay(starts, out.Signal.Name, ps.start[out.Part.Name])
+			}
+		}
+	}
+
+	order, err := topoSort(graph)
+	if err != nil {
+		return nil, &hdl.Error{File: chip.File, Line: chip.Line, Msg: err.Error()}
+	}
+
+	for _, in := range chip.In {
+		arrival := propagate(order, graph, map[string]int{in.Name: 0})
+		for _, out := range chip.Out {
+			s.comb[in.Name][out.Name] = delayOf(arrival, out.Name)
+		}
+		s.end[in.Name] = endDelay(arrival, ends)
+	}
+
+	arrival := propagate(order, graph, starts)
+	for _, out := range chip.Out {
+		s.start[out.Name] = delayOf(arrival, out.Name)
+	}
+
+	s.internal = max(s.internal, endDelay(arrival, ends))
+
+	return s, nil
+}
+
+// Returns the longest arrival times of the signals reachable from the
+// sources, which map signals to their initial delays
+func propagate(order []string, graph map[string][]edge, sources map[string]int) map[string]int {
+	arrival := map[string]int{}
+	for sig, d := range sources {
+		arrival[sig] = d
+	}
+
+	for _, sig := range order {
+		d, reached := arrival[sig]
+		if !reached {
+			continue
+		}
+		for _, e := range graph[sig] {
+			arrival[e.to] = max(delayOf(arrival, e.to), d+e.delay)
+		}
+	}
+
+	return arrival
+}
+
+// Returns the longest path from the arrival times into state, or -1
+func endDelay(arrival map[string]int, ends map[string]int) int {
+	longest := -1
+	for sig, d := range ends {
+		if a, reached := arrival[sig]; reached {
+			longest = max(longest, a+d)
+		}
+	}
+
+	return longest
+}
+
+func delayOf(arrival map[string]int, sig string) int {
+	if d, found := arrival[sig]; found {
+		return d
+	}
+
+	return -1
+}
+
+func maxDelay(delays map[string]int, sig string, d int) int {
+	return max(delayOf(delays, sig), d)
+}
+
+// Orders the signals of the graph so every edge goes forward
+func topoSort(graph map[string][]edge) ([]string, error) {
+	indegree := map[string]int{}
+	for sig, edges := range graph {
+		if _, found := indegree[sig]; !found {
+			indegree[sig] = 0
+		}
+		for _, e := range edges {
+			indegree[e.to]++
+		}
+	}
+
+	var ready []string
+	for sig, n := range indegree {
+		if n == 0 {
+			ready = append(ready, sig)
+		}
+	}
+	sort.Strings(ready)
+
+	var order []string
+	for len(ready) != 0 {
+		sig := ready[0]
+		ready = ready[1:]
+		order = append(order, sig)
+
+		for _, e := range graph[sig] {
+			indegree[e.to]--
+			if indegree[e.to] == 0 {
+				ready = append(ready, e.to)
+			}
+		}
+	}
+
+	if len(order) != len(indegree) {
+		return nil, errLoop
+	}
+
+	return order, nil
+}
+
+var errLoop = errors.New("combinational loop, run hdl-lint to locate it")
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
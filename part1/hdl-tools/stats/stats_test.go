@@ -0,0 +1,116 @@
+package stats
+
+import (
+	"nand2tetris/hdl-tools/hdl"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func analyze(t *testing.T, l *hdl.Loader, name string) *Stats {
+	chip, err := l.Load(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewAnalyzer(l).Analyze(chip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestAnalyzeRepoChips(t *testing.T) {
+	l := hdl.NewLoader("../../project2", "../../project1")
+
+	tests := []struct {
+		name     string
+		nands    int
+		depth    int
+		from, to string
+	}{
+		{"Nand", 1, 1, "a", "out"},
+		{"Not", 1, 1, "in", "out"},
+		{"And", 2, 2, "a", "out"},
+		{"Mux", 8, 5, "sel", "out"},
+		{"Xor", 9, 5, "a", "out"},
+		{"HalfAdder", 11, 5, "a", "sum"},
+	}
+
+	for _, test := range tests {
+		s := analyze(t, l, test.name)
+		if s.Nands != test.nands || s.Depth != test.depth || s.From != test.from || s.To != test.to {
+			t.Errorf("%s: %d nands, depth %d from %s to %s, want %d nands, depth %d from %s to %s",
+				test.name, s.Nands, s.Depth, s.From, s.To, test.nands, test.depth, test.from, test.to)
+		}
+	}
+
+	// The ALU's Add16(false, false) costs gates, but no delay
+	alu := analyze(t, l, "ALU")
+	add16 := analyze(t, l, "Add16")
+	if alu.Parts[0].Name != "Add16" || alu.Parts[0].Nands != add16.Nands {
+		t.Errorf("ALU's first part = %+v, want an Add16 of %d nands", alu.Parts[0], add16.Nands)
+	}
+	if alu.From == "" || alu.Depth <= add16.Depth {
+		t.Errorf("ALU depth %d from %q, want more than Add16's %d", alu.Depth, alu.From, add16.Depth)
+	}
+}
+
+func TestAnalyzeState(t *testing.T) {
+	dir := t.TempDir()
+	chips := map[string]string{
+		// A 1-bit toggle: state -> Not -> state
+		"Toggle": `CHIP Toggle { IN a; OUT out;
+			PARTS:
+			Not(in=q, out=nq);
+			DFF(in=nq, out=q, out=out);
+			}`,
+		// The input goes through 3 gates into state, and state through 1 gate
+		// out
+		"Paths": `CHIP Paths { IN a; OUT out;
+			PARTS:
+			Not(in=a, out=x);
+			Not(in=x, out=y);
+			Not(in=y, out=z);
+			Register(in[0]=z, load=true, out[0]=r);
+			Not(in=r, out=out);
+			}`,
+	}
+	for name, src := range chips {
+		if err := os.WriteFile(filepath.Join(dir, name+".hdl"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l := hdl.NewLoader(dir, "../../project1")
+
+	s := analyze(t, l, "Toggle")
+	if s.Nands != 1 || s.Builtins["DFF"] != 1 || s.Depth != 1 || s.From != State || s.To != State {
+		t.Errorf("Toggle = %+v", s)
+	}
+
+	s = analyze(t, l, "Paths")
+	if s.Nands != 4 || s.Depth != 3 || s.From != "a" || s.To != State {
+		t.Errorf("Paths = %+v", s)
+	}
+	if s.start["out"] != 1 || s.comb["a"]["out"] != -1 {
+		t.Errorf("Paths: state to out %d, a to out %d, want 1 and -1", s.start["out"], s.comb["a"]["out"])
+	}
+}
+
+func TestAnalyzeLoop(t *testing.T) {
+	dir := t.TempDir()
+	src := `CHIP Loop { IN a; OUT out; PARTS: Nand(a=a, b=x, out=y); Nand(a=y, b=y, out=x, out=out); }`
+	if err := os.WriteFile(filepath.Join(dir, "Loop.hdl"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := hdl.NewLoader(dir)
+	chip, err := l.Load("Loop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewAnalyzer(l).Analyze(chip); err == nil {
+		t.Error("Analyze(Loop) succeeded, want a loop error")
+	}
+}
@@ -0,0 +1,50 @@
+package tst
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An error in a test script, located by file and line
+type ScriptError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+func scriptErrorf(file string, line int, format string, a ...interface{}) *ScriptError {
+	return &ScriptError{File: file, Line: line, Msg: fmt.Sprintf(format, a...)}
+}
+
+// Returned when an output line differs from the comparison file
+type Mismatch struct {
+	File string // The comparison file
+	Line int
+	Want string
+	Got  string
+}
+
+func (e *Mismatch) Error() string {
+	// Mark every differing column under the output line
+	marks := []rune(strings.Repeat(" ", max(len(e.Want), len(e.Got))))
+	for i := range marks {
+		if i >= len(e.Want) || i >= len(e.Got) || (e.Want[i] != e.Got[i] && e.Want[i] != '*') {
+			marks[i] = '^'
+		}
+	}
+
+	return fmt.Sprintf("comparison failure at line %d of %s\n  want: %s\n  got:  %s\n        %s",
+		e.Line, e.File, e.Want, e.Got, strings.TrimRight(string(marks), " "))
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
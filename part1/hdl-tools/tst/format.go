@@ -0,0 +1,100 @@
+package tst
+
+// This file contains the output-list column formats.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A single output-list column, e.g. "out%B1.16.1"
+type column struct {
+	ref    string // Pin, memory cell or "time"
+	format byte   // 'B', 'D', 'X' or 'S'
+	pre    int    // Spaces before the value
+	length int    // Width of the value
+	post   int    // Spaces after the value
+}
+
+// Parses a column written as "ref%FPRE.LEN.POST". Without a format, values are
+// shown in binary with one space on each side.
+func parseColumn(s string, width func(ref string) int) (column, error) {
+	i := strings.Index(s, "%")
+	if i == -1 {
+		return column{ref: s, format: 'B', pre: 1, length: width(s), post: 1}, nil
+	}
+
+	col := column{ref: s[:i]}
+	spec := s[i+1:]
+	if len(spec) < 2 || !strings.ContainsRune("BDXS", rune(spec[0])) {
+		return col, fmt.Errorf("invalid format %q, expected one of %%B, %%D, %%X or %%S", s[i:])
+	}
+	col.format = spec[0]
+
+	parts := strings.Split(spec[1:], ".")
+	if len(parts) != 3 {
+		return col, fmt.Errorf("invalid format %q, expected %%%cPRE.LEN.POST", s[i:], col.format)
+	}
+
+	nums := make([]int, 3)
+	for j, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return col, fmt.Errorf("invalid format %q, expected %%%cPRE.LEN.POST", s[i:], col.format)
+		}
+		nums[j] = n
+	}
+	col.pre, col.length, col.post = nums[0], nums[1], nums[2]
+
+	return col, nil
+}
+
+func (col column) width() int {
+	return col.pre + col.length + col.post
+}
+
+// The column name, centered in the column width
+func (col column) header() string {
+	name := col.ref
+	if len(name) > col.width() {
+		name = name[:col.width()]
+	}
+
+	left := (col.width() - len(name)) / 2
+	right := col.width() - len(name) - left
+
+	return strings.Repeat(" ", left) + name + strings.Repeat(" ", right)
+}
+
+// Formats a value of a pin of the given width. Decimal values of 16-bit pins
+// are shown as signed numbers.
+func (col column) value(v uint16, width int) string {
+	var s string
+
+	switch col.format {
+	case 'B':
+		s = fmt.Sprintf("%0*b", col.length, v)
+		s = s[len(s)-col.length:]
+	case 'X':
+		s = fmt.Sprintf("%0*X", col.length, v)
+		s = s[len(s)-col.length:]
+	default:
+		n := int(v)
+		if width == 16 {
+			n = int(int16(v))
+		}
+		s = fmt.Sprintf("%*d", col.length, n)
+	}
+
+	return col.pad(s)
+}
+
+// Formats a string value, left aligned
+func (col column) text(s string) string {
+	return col.pad(fmt.Sprintf("%-*s", col.length, s))
+}
+
+func (col column) pad(s string) string {
+	return strings.Repeat(" ", col.pre) + s + strings.Repeat(" ", col.post)
+}
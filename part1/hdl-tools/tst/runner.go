@@ -0,0 +1,521 @@
+package tst
+
+// This file contains the test script interpreter.
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"nand2tetris/hdl-tools/hdl"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Options struct {
+	// Directories searched for the parts of the tested chip, after the
+	// directory of the script
+	SearchPath []string
+	// Where the output file is written. Defaults to the directory of the
+	// script.
+	OutDir string
+	// Receives the messages of "echo" commands. Discarded if nil.
+	Echo func(msg string)
+}
+
+// Runs the test script at path. Returns a *Mismatch at the first output line
+// that differs from the comparison file, a *ScriptError if the script is
+// invalid, or the error of the simulator if the chip can't be loaded.
+func Run(path string, opts Options) error {
+	cmds, err := parseFile(path)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	outDir := opts.OutDir
+	if len(outDir) == 0 {
+		outDir = dir
+	}
+
+	r := runner{
+		file:   path,
+		dir:    dir,
+		outDir: outDir,
+		opts:   opts,
+		loader: hdl.NewLoader(append([]string{dir}, opts.SearchPath...)...),
+	}
+
+	err = r.run(cmds)
+	if flushErr := r.flush(); err == nil {
+		err = flushErr
+	}
+
+	return err
+}
+
+type runner struct {
+	file   string
+	dir    string
+	outDir string
+	opts   Options
+	loader *hdl.Loader
+	sim    *hdl.Simulator
+
+	outPath string
+	out     bytes.Buffer
+	cmpPath string
+	cmp     []string
+	outLine int // Number of lines output so far
+	columns []column
+
+	time     int
+	halfTick bool // Between a tick and its tock
+}
+
+func (r *runner) run(cmds []command) error {
+	for _, cmd := range cmds {
+		if err := r.exec(cmd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *runner) exec(cmd command) error {
+	if r.sim == nil && cmd.name != "load" && cmd.name != "output-file" &&
+		cmd.name != "compare-to" && cmd.name != "echo" && cmd.name != "clear-echo" {
+		return r.errorf(cmd, "%s before load", cmd.name)
+	}
+
+	switch cmd.name {
+	case "load":
+		if err := r.args(cmd, 1); err != nil {
+			return err
+		}
+
+		// The chip is looked up in the directory of the script first, then in
+		// the search path
+		sim, err := hdl.Simulate(r.loader, strings.TrimSuffix(cmd.args[0], ".hdl"))
+		if err != nil {
+			return err
+		}
+		r.sim = sim
+
+	case "output-file":
+		if err := r.args(cmd, 1); err != nil {
+			return err
+		}
+		r.outPath = filepath.Join(r.outDir, cmd.args[0])
+
+	case "compare-to":
+		if err := r.args(cmd, 1); err != nil {
+			return err
+		}
+
+		r.cmpPath = filepath.Join(r.dir, cmd.args[0])
+		data, err := os.ReadFile(r.cmpPath)
+		if err != nil {
+			return r.errorf(cmd, "%s", err)
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			r.cmp = append(r.cmp, strings.TrimRight(scanner.Text(), " \t\r"))
+		}
+
+	case "output-list":
+		r.columns = nil
+		for _, arg := range cmd.args {
+			col, err := parseColumn(arg, r.width)
+			if err != nil {
+				return r.errorf(cmd, "%s", err)
+			}
+			r.columns = append(r.columns, col)
+		}
+
+		var header []string
+		for _, col := range r.columns {
+			header = append(header, col.header())
+		}
+		return r.output(cmd, header)
+
+	case "set":
+		if err := r.args(cmd, 2); err != nil {
+			return err
+		}
+
+		v, err := parseValue(cmd.args[1])
+		if err != nil {
+			return r.errorf(cmd, "invalid value %q", cmd.args[1])
+		}
+		if err := r.set(cmd.args[0], uint16(v)); err != nil {
+			return r.errorf(cmd, "%s", err)
+		}
+
+	case "eval":
+		r.sim.Eval()
+
+	case "tick":
+		r.sim.Tick()
+		r.halfTick = true
+
+	case "tock":
+		r.sim.Tock()
+		r.halfTick = false
+		r.time++
+
+	case "output":
+		if r.columns == nil {
+			return r.errorf(cmd, "output before output-list")
+		}
+
+		var line []string
+		for _, col := range r.columns {
+			s, err := r.format(col)
+			if err != nil {
+				return r.errorf(cmd, "%s", err)
+			}
+			line = append(line, s)
+		}
+		return r.output(cmd, line)
+
+	case "repeat":
+		if err := r.args(cmd, 1); err != nil {
+			return err
+		}
+
+		n, err := strconv.Atoi(cmd.args[0])
+		if err != nil || n < 0 {
+			return r.errorf(cmd, "invalid repeat count %q", cmd.args[0])
+		}
+		for i := 0; i < n; i++ {
+			if err := r.run(cmd.body); err != nil {
+				return err
+			}
+		}
+
+	case "while":
+		for {
+			ok, err := r.condition(cmd)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				break
+			}
+
+			if err := r.run(cmd.body); err != nil {
+				return err
+			}
+		}
+
+	case "load-memory":
+		if err := r.args(cmd, 2); err != nil {
+			return err
+		}
+
+		return r.loadMemory(cmd, cmd.args[0], filepath.Join(r.dir, cmd.args[1]))
+
+	case "echo":
+		if r.opts.Echo != nil {
+			r.opts.Echo(strings.Join(cmd.args, " "))
+		}
+
+	case "clear-echo":
+
+	default:
+		return r.errorf(cmd, "unknown command %q", cmd.name)
+	}
+
+	return nil
+}
+
+// Writes an output line and compares it with the comparison file
+func (r *runner) output(cmd command, cols []string) error {
+	line := "|" + strings.Join(cols, "|") + "|"
+	r.out.WriteString(line + "\n")
+	r.outLine++
+
+	if r.cmp == nil {
+		return nil
+	}
+
+	want := ""
+	if r.outLine <= len(r.cmp) {
+		want = r.cmp[r.outLine-1]
+	}
+
+	if !matches(strings.TrimRight(line, " "), want) {
+		return &Mismatch{File: r.cmpPath, Line: r.outLine, Want: want, Got: line}
+	}
+
+	return nil
+}
+
+// Whether an output line matches a comparison line. "*" in the comparison line
+// matches any character.
+func matches(got string, want string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+
+	for i := range got {
+		if got[i] != want[i] && want[i] != '*' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Writes the output file, if the script asked for one
+func (r *runner) flush() error {
+	if len(r.outPath) == 0 {
+		return nil
+	}
+
+	return os.WriteFile(r.outPath, r.out.Bytes(), 0644)
+}
+
+// Returns the value of a column, formatted
+func (r *runner) format(col column) (string, error) {
+	if col.ref == "time" {
+		t := strconv.Itoa(r.time)
+		if r.halfTick {
+			t += "+"
+		}
+		return col.text(t), nil
+	}
+
+	v, width, err := r.get(col.ref)
+	if err != nil {
+		return "", err
+	}
+
+	return col.value(v, width), nil
+}
+
+// Returns the width of a pin or memory cell, for columns without a format
+func (r *runner) width(ref string) int {
+	name, lo, hi, err := splitRef(ref)
+	if err != nil {
+		return 1
+	}
+
+	if w := r.sim.Width(name); w != 0 {
+		if lo != -1 {
+			return hi - lo + 1
+		}
+		return w
+	}
+
+	return 16
+}
+
+// Returns the value of a pin ("a", "a[3]", "a[0..7]") or a cell of a
+// built-in memory ("RAM16K[12]", "ARegister[]"), and its width
+func (r *runner) get(ref string) (uint16, int, error) {
+	name, lo, hi, err := splitRef(ref)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if w := r.sim.Width(name); w != 0 {
+		v, _ := r.sim.Get(name)
+		if lo == -1 {
+			return v, w, nil
+		}
+		if hi >= w {
+			return 0, 0, errors.New(ref + " is out of range")
+		}
+		return (v >> lo) & (1<<(hi-lo+1) - 1), hi - lo + 1, nil
+	}
+
+	cell, err := r.cell(name, lo)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return *cell, 16, nil
+}
+
+// Sets an input pin, part of an input pin, or a cell of a built-in memory
+func (r *runner) set(ref string, v uint16) error {
+	name, lo, hi, err := splitRef(ref)
+	if err != nil {
+		return err
+	}
+
+	if r.sim.Chip.IsInput(name) {
+		if lo == -1 {
+			return r.sim.Set(name, v)
+		}
+
+		w := r.sim.Width(name)
+		if hi >= w {
+			return errors.New(ref + " is out of range")
+		}
+		mask := uint16(1<<(hi-lo+1)-1) << lo
+		old, _ := r.sim.Get(name)
+		return r.sim.Set(name, old&^mask|(v<<lo)&mask)
+	}
+
+	cell, err := r.cell(name, lo)
+	if err != nil {
+		return err
+	}
+	*cell = v
+	r.sim.Eval()
+
+	return nil
+}
+
+// Returns a cell of the built-in memory called name. "X[]" is the single cell
+// of a register.
+func (r *runner) cell(name string, index int) (*uint16, error) {
+	mem := r.sim.Memory(name)
+	if mem == nil {
+		return nil, errors.New("no pin or built-in memory called " + name)
+	}
+
+	if index == -1 {
+		index = 0
+	}
+	if index >= len(mem) {
+		return nil, errors.New(name + "[" + strconv.Itoa(index) + "] is out of range")
+	}
+
+	return &mem[index], nil
+}
+
+// Loads a .hack program, one binary word per line, into a built-in memory
+func (r *runner) loadMemory(cmd command, name string, path string) error {
+	mem := r.sim.Memory(name)
+	if mem == nil {
+		return r.errorf(cmd, "no built-in memory called %s", name)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r.errorf(cmd, "%s", err)
+	}
+
+	for i := range mem {
+		mem[i] = 0
+	}
+
+	i := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if i >= len(mem) {
+			return r.errorf(cmd, "%s doesn't fit in %s", path, name)
+		}
+
+		v, err := strconv.ParseUint(line, 2, 16)
+		if err != nil {
+			return r.errorf(cmd, "%s: invalid word %q", path, line)
+		}
+		mem[i] = uint16(v)
+		i++
+	}
+
+	r.sim.Eval()
+	return nil
+}
+
+// Evaluates the condition of a while loop, e.g. "out <> 5"
+func (r *runner) condition(cmd command) (bool, error) {
+	if len(cmd.args) != 3 {
+		return false, r.errorf(cmd, "expected a condition like \"a <> 5\"")
+	}
+
+	operand := func(s string) (int, error) {
+		if v, err := parseValue(s); err == nil {
+			return v, nil
+		}
+
+		v, width, err := r.get(s)
+		if err != nil {
+			return 0, r.errorf(cmd, "%s", err)
+		}
+		if width == 16 {
+			return int(int16(v)), nil
+		}
+		return int(v), nil
+	}
+
+	x, err := operand(cmd.args[0])
+	if err != nil {
+		return false, err
+	}
+	y, err := operand(cmd.args[2])
+	if err != nil {
+		return false, err
+	}
+
+	switch cmd.args[1] {
+	case "=":
+		return x == y, nil
+	case "<>":
+		return x != y, nil
+	case "<":
+		return x < y, nil
+	case ">":
+		return x > y, nil
+	case "<=":
+		return x <= y, nil
+	case ">=":
+		return x >= y, nil
+	}
+
+	return false, r.errorf(cmd, "unknown comparison %q, expected one of =, <>, <, >, <=, >=", cmd.args[1])
+}
+
+func (r *runner) args(cmd command, n int) error {
+	if len(cmd.args) != n {
+		return r.errorf(cmd, "%s takes %d argument(s), got %d", cmd.name, n, len(cmd.args))
+	}
+
+	return nil
+}
+
+func (r *runner) errorf(cmd command, format string, a ...interface{}) error {
+	return scriptErrorf(r.file, cmd.line, format, a...)
+}
+
+// Splits "name", "name[]", "name[i]" or "name[i..j]" into the name and bit
+// (or cell) range. lo and hi are -1 without an index.
+func splitRef(ref string) (name string, lo int, hi int, err error) {
+	i := strings.Index(ref, "[")
+	if i == -1 {
+		return ref, -1, -1, nil
+	}
+	if !strings.HasSuffix(ref, "]") {
+		return "", 0, 0, errors.New("invalid reference " + ref)
+	}
+
+	name, index := ref[:i], ref[i+1:len(ref)-1]
+	if len(index) == 0 {
+		return name, -1, -1, nil
+	}
+
+	bounds := strings.SplitN(index, "..", 2)
+	if lo, err = strconv.Atoi(bounds[0]); err != nil || lo < 0 {
+		return "", 0, 0, errors.New("invalid reference " + ref)
+	}
+	hi = lo
+	if len(bounds) == 2 {
+		if hi, err = strconv.Atoi(bounds[1]); err != nil || hi < lo {
+			return "", 0, 0, errors.New("invalid reference " + ref)
+		}
+	}
+
+	return name, lo, hi, nil
+}
@@ -0,0 +1,173 @@
+package tst
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testOptions(t *testing.T) Options {
+	return Options{
+		SearchPath: []string{"../../project2", "../../project1"},
+		OutDir:     t.TempDir(),
+	}
+}
+
+func TestRun(t *testing.T) {
+	for _, script := range []string{"Xor.tst", "Bit.tst", "Add16.tst", "RAM8.tst"} {
+		t.Run(script, func(t *testing.T) {
+			if err := Run(filepath.Join("testdata", script), testOptions(t)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRunWritesOutput(t *testing.T) {
+	opts := testOptions(t)
+	if err := Run("testdata/Xor.tst", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(opts.OutDir, "Xor.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("testdata/Xor.cmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != string(want) {
+		t.Errorf("Xor.out = \n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRunMismatch(t *testing.T) {
+	err := Run("testdata/XorWrong.tst", testOptions(t))
+
+	var mismatch *Mismatch
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Run() = %v, want a *Mismatch", err)
+	}
+	if mismatch.Line != 3 {
+		t.Errorf("mismatch at line %d, want 3", mismatch.Line)
+	}
+	if mismatch.Want != "|   0   |   1   |   0   |" || mismatch.Got != "|   0   |   1   |   1   |" {
+		t.Errorf("mismatch want %q got %q", mismatch.Want, mismatch.Got)
+	}
+	if !strings.HasSuffix(err.Error(), "\n"+strings.Repeat(" ", 8+20)+"^") {
+		t.Errorf("differing column not marked:\n%s", err)
+	}
+}
+
+func TestRunScriptError(t *testing.T) {
+	err := Run("testdata/Bad.tst", testOptions(t))
+
+	var scriptErr *ScriptError
+	if !errors.As(err, &scriptErr) {
+		t.Fatalf("Run() = %v, want a *ScriptError", err)
+	}
+	if scriptErr.Line != 3 || !strings.Contains(scriptErr.Msg, "c") {
+		t.Errorf("Run() = %v, want an error about pin c at line 3", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	src := `/* header */ load Foo.hdl, // comment
+ROM32K load Prog.hack,
+echo "two words";
+repeat 2 { tick, tock; while out <> 3 { eval; } }
+`
+	cmds, err := parse("test.tst", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, cmd := range cmds {
+		names = append(names, cmd.name)
+	}
+	if got := strings.Join(names, " "); got != "load load-memory echo repeat" {
+		t.Fatalf("commands = %q", got)
+	}
+
+	if cmds[1].args[0] != "ROM32K" || cmds[1].args[1] != "Prog.hack" || cmds[1].line != 2 {
+		t.Errorf("load-memory = %+v", cmds[1])
+	}
+	if len(cmds[2].args) != 1 || cmds[2].args[0] != "two words" {
+		t.Errorf("echo args = %q", cmds[2].args)
+	}
+
+	repeat := cmds[3]
+	if len(repeat.body) != 3 || repeat.body[2].name != "while" || len(repeat.body[2].body) != 1 {
+		t.Errorf("repeat body = %+v", repeat.body)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		line int
+	}{
+		{"load A.hdl;\nrepeat 2 { tick;", 2},
+		{"load A.hdl;\n}", 2},
+		{"set a 1 { eval; }", 1},
+		{"repeat 3;", 1},
+		{"echo \"unterminated;", 1},
+		{"\n/* unterminated", 2},
+	}
+
+	for _, test := range tests {
+		_, err := parse("test.tst", test.src)
+
+		var scriptErr *ScriptError
+		if !errors.As(err, &scriptErr) {
+			t.Errorf("parse(%q) = %v, want a *ScriptError", test.src, err)
+			continue
+		}
+		if scriptErr.Line != test.line {
+			t.Errorf("parse(%q) error at line %d, want %d", test.src, scriptErr.Line, test.line)
+		}
+	}
+}
+
+func TestColumn(t *testing.T) {
+	tests := []struct {
+		spec   string
+		value  uint16
+		width  int
+		header string
+		out    string
+	}{
+		{"a%B3.1.3", 1, 1, "   a   ", "   1   "},
+		{"out%B1.16.1", 5, 16, "       out        ", " 0000000000000101 "},
+		{"out%X1.4.1", 0xBEEF, 16, " out  ", " BEEF "},
+		{"out%D1.6.1", 0xFFFF, 16, "  out   ", "     -1 "},
+		{"sel%D1.3.1", 7, 3, " sel ", "   7 "},
+		{"instruction%B0.4.0", 0xF, 16, "inst", "1111"},
+	}
+
+	for _, test := range tests {
+		col, err := parseColumn(test.spec, func(string) int { return 16 })
+		if err != nil {
+			t.Errorf("parseColumn(%q) = %v", test.spec, err)
+			continue
+		}
+
+		if got := col.header(); got != test.header {
+			t.Errorf("%q header = %q, want %q", test.spec, got, test.header)
+		}
+		if got := col.value(test.value, test.width); got != test.out {
+			t.Errorf("%q value = %q, want %q", test.spec, got, test.out)
+		}
+	}
+
+	for _, spec := range []string{"a%Q1.1.1", "a%B1.1", "a%B1.x.1"} {
+		if _, err := parseColumn(spec, nil); err == nil {
+			t.Errorf("parseColumn(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package tst
+
+// This file contains the test script parser.
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A single script command, e.g. "set a 1" or "repeat 3 { ... }"
+type command struct {
+	name string   // e.g. "set", "output-list", or "load" for "ROM32K load"
+	args []string // Arguments as written
+	body []command
+	line int
+}
+
+type token struct {
+	text   string
+	line   int
+	quoted bool // Text of an echo string, without the quotes
+}
+
+// Splits a script into tokens. ",", ";", "!", "{" and "}" are tokens on their
+// own, comments are dropped, and quoted strings are kept whole.
+func tokenize(file string, src string) ([]token, error) {
+	var out []token
+	line := 1
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+
+		switch {
+		case c == '\n':
+			line++
+			i++
+
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start := line
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i+1 >= len(runes) {
+				return nil, scriptErrorf(file, start, "unterminated comment")
+			}
+			i += 2
+
+		case c == '"':
+			j := i + 1
+			for j < len(runes) && runes[j] != '"' && runes[j] != '\n' {
+				j++
+			}
+			if j >= len(runes) || runes[j] != '"' {
+				return nil, scriptErrorf(file, line, "unterminated string")
+			}
+			out = append(out, token{text: string(runes[i+1 : j]), line: line, quoted: true})
+			i = j + 1
+
+		case strings.ContainsRune(",;!{}", c):
+			out = append(out, token{text: string(c), line: line})
+			i++
+
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune(",;!{}\"", runes[j]) {
+				j++
+			}
+			out = append(out, token{text: string(runes[i:j]), line: line})
+			i = j
+		}
+	}
+
+	return out, nil
+}
+
+// Reads and parses the test script at path
+func parseFile(path string) ([]command, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(path, string(src))
+}
+
+func parse(file string, src string) ([]command, error) {
+	tokens, err := tokenize(file, src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{file: file, tokens: tokens}
+	cmds, err := p.commands(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return cmds, nil
+}
+
+type parser struct {
+	file   string
+	tokens []token
+	pos    int
+}
+
+// Parses commands up to the end of the script, or up to the closing "}" of a
+// block
+func (p *parser) commands(inBlock bool) ([]command, error) {
+	var cmds []command
+
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+
+		switch t.text {
+		case ",", ";", "!":
+			// Separators carry no meaning for the runner
+			p.pos++
+			continue
+
+		case "}":
+			if !inBlock {
+				return nil, scriptErrorf(p.file, t.line, "unexpected \"}\"")
+			}
+			p.pos++
+			return cmds, nil
+		}
+
+		cmd, err := p.command()
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	if inBlock {
+		return nil, scriptErrorf(p.file, p.tokens[len(p.tokens)-1].line, "missing \"}\"")
+	}
+
+	return cmds, nil
+}
+
+// Parses one command and its arguments, up to the next separator
+func (p *parser) command() (command, error) {
+	first := p.tokens[p.pos]
+	p.pos++
+
+	cmd := command{name: first.text, line: first.line}
+
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		if !t.quoted && strings.Contains(",;!}", t.text) {
+			break
+		}
+		p.pos++
+
+		if !t.quoted && t.text == "{" {
+			if cmd.name != "repeat" && cmd.name != "while" {
+				return cmd, scriptErrorf(p.file, t.line, "unexpected \"{\" after %s", cmd.name)
+			}
+
+			body, err := p.commands(true)
+			if err != nil {
+				return cmd, err
+			}
+			cmd.body = body
+			return cmd, nil
+		}
+
+		cmd.args = append(cmd.args, t.text)
+	}
+
+	// "ROM32K load Program.hack" loads a program into a built-in memory
+	if len(cmd.args) == 2 && cmd.args[0] == "load" && cmd.name != "set" {
+		cmd.args[0] = cmd.name
+		cmd.name = "load-memory"
+	}
+
+	if cmd.name == "repeat" || cmd.name == "while" {
+		return cmd, scriptErrorf(p.file, cmd.line, "%s without a block", cmd.name)
+	}
+
+	return cmd, nil
+}
+
+// Parses a value written in a script: "%B0101", "%XFF", "%D-3" or "-3"
+func parseValue(s string) (int, error) {
+	base := 10
+	if len(s) > 2 && s[0] == '%' {
+		switch s[1] {
+		case 'B':
+			base = 2
+		case 'X':
+			base = 16
+		case 'D':
+			base = 10
+		default:
+			return 0, strconv.ErrSyntax
+		}
+		s = s[2:]
+	}
+
+	v, err := strconv.ParseInt(s, base, 32)
+	return int(v), err
+}
@@ -0,0 +1,7 @@
+load Add16.hdl,
+compare-to Add16.cmp,
+output-list a%B1.16.1 b%X1.4.1 out%D1.6.1 out[15]%B1.1.1;
+
+set a %B0000000000000001, set b %XFFFE, eval, output;
+set a -1, set b %X0001, eval, output;
+set a[0..7] %XFF, set b 0, eval, output;
@@ -0,0 +1,3 @@
+load Xor.hdl,
+output-list a b out;
+set c 1;
@@ -0,0 +1,18 @@
+// Clocked chips: tick/tock, time column, repeat and while loops.
+
+load Bit.hdl,
+output-file Bit.out,
+compare-to Bit.cmp,
+output-list time%S1.4.1 in%B2.1.3 load%B2.1.3 out%B2.1.3;
+
+set in 1, set load 1,
+repeat 2 {
+    tick, output;
+    tock, output;
+}
+
+set load 0, set in 0,
+while out <> 1 {
+    tick, tock;
+}
+output;
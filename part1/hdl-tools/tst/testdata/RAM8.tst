@@ -0,0 +1,13 @@
+// Built-in memories can be read and written by name.
+
+load RAM8,
+compare-to RAM8.cmp,
+output-list address%D1.1.1 out%D1.3.1 RAM8[3]%D1.3.1;
+
+set RAM8[3] 42,
+set address 3,
+eval,
+output;
+
+set in 7, set load 1, tick, tock,
+output;
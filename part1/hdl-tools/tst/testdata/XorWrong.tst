@@ -0,0 +1,26 @@
+// Tests Xor.hdl from project 1, in the course's format.
+
+load Xor.hdl,
+output-file XorWrong.out,
+compare-to XorWrong.cmp,
+output-list a%B3.1.3 b%B3.1.3 out%B3.1.3;
+
+set a 0,
+set b 0,
+eval,
+output;
+
+set a 0,
+set b 1,
+eval,
+output;
+
+set a 1,
+set b 0,
+eval,
+output;
+
+set a 1,
+set b 1,
+eval,
+output;
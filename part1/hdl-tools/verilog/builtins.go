@@ -0,0 +1,147 @@
+package verilog
+
+// This file contains the behavioural modules of the built-in chips. They
+// follow the simulator: reads are immediate, writes happen on the rising edge
+// of the clock, and all state starts at zero.
+
+import (
+	"bufio"
+	"fmt"
+	"nand2tetris/hdl-tools/hdl"
+)
+
+func (e *exporter) writeBuiltin(w *bufio.Writer, chip *hdl.Chip) error {
+	fmt.Fprintf(w, "// Built-in %s\n", chip.Name)
+
+	switch chip.Name {
+	case "Nand":
+		w.WriteString(`module Nand (
+    input a,
+    input b,
+    output out
+);
+    assign out = ~(a & b);
+endmodule
+`)
+
+	case "DFF":
+		w.WriteString(`module DFF (
+    input clk,
+    input in,
+    output reg out
+);
+    initial out = 1'b0;
+
+    always @(posedge clk)
+        out <= in;
+endmodule
+`)
+
+	case "Bit", "Register", "ARegister", "DRegister":
+		p, _ := chip.Pin("in")
+		fmt.Fprintf(w, `module %s (
+    input clk,
+    input %sin,
+    input load,
+    output reg %sout
+);
+    initial out = %s;
+
+    always @(posedge clk)
+        if (load)
+            out <= in;
+endmodule
+`, chip.Name, vector(p.Width), vector(p.Width), constant(false, p.Width))
+
+	case "PC":
+		w.WriteString(`module PC (
+    input clk,
+    input [15:0] in,
+    input load,
+    input inc,
+    input reset,
+    output reg [15:0] out
+);
+    initial out = 16'd0;
+
+    always @(posedge clk)
+        if (reset)
+            out <= 16'd0;
+        else if (load)
+            out <= in;
+        else if (inc)
+            out <= out + 16'd1;
+endmodule
+`)
+
+	case "RAM8", "RAM64", "RAM512", "RAM4K", "RAM16K", "Screen":
+		p, _ := chip.Pin("address")
+		if chip.Name == "Screen" {
+			w.WriteString("// Connect a display controller to mem to show the screen\n")
+		}
+		fmt.Fprintf(w, `module %s (
+    input clk,
+    input [15:0] in,
+    input load,
+    input %saddress,
+    output [15:0] out
+);
+    reg [15:0] mem [0:%d];
+
+    integer i;
+    initial
+        for (i = 0; i < %d; i = i + 1)
+            mem[i] = 16'd0;
+
+    assign out = mem[address];
+
+    always @(posedge clk)
+        if (load)
+            mem[address] <= in;
+endmodule
+`, chip.Name, vector(p.Width), 1<<p.Width-1, 1<<p.Width)
+
+	case "ROM32K":
+		w.WriteString(`module ROM32K (
+    input [14:0] address,
+    output [15:0] out
+);
+    reg [15:0] mem [0:32767];
+`)
+		if e.opts.RomFile != "" {
+			fmt.Fprintf(w, `
+    parameter ROM_FILE = %q;
+    initial
+        $readmemb(ROM_FILE, mem);
+`, e.opts.RomFile)
+		} else {
+			w.WriteString(`
+    // Empty: export with a ROM file, e.g. written by "hack-assembler --mem",
+    // to preload a program
+    integer i;
+    initial
+        for (i = 0; i < 32768; i = i + 1)
+            mem[i] = 16'd0;
+`)
+		}
+		w.WriteString(`
+    assign out = mem[address];
+endmodule
+`)
+
+	case "Keyboard":
+		w.WriteString(`// Stub: replace key with the scan code of a keyboard controller
+module Keyboard (
+    output [15:0] out
+);
+    reg [15:0] key = 16'd0;
+    assign out = key;
+endmodule
+`)
+
+	default:
+		return &hdl.Error{File: chip.Name, Msg: "no Verilog module for built-in chip " + chip.Name}
+	}
+
+	return nil
+}
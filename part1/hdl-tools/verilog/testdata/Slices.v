@@ -0,0 +1,149 @@
+// Slices, exported from the Nand2Tetris HDL
+
+// Built-in Nand
+module Nand (
+    input a,
+    input b,
+    output out
+);
+    assign out = ~(a & b);
+endmodule
+
+// ../../project1/Not.hdl
+module Not (
+    input in,
+    output out
+);
+
+    // Nand, line 16
+    wire part0_out;
+    Nand part0 (.a(in), .b(in), .out(part0_out));
+    assign out = part0_out;
+endmodule
+
+// ../../project1/Not16.hdl
+module Not16 (
+    input [15:0] in,
+    output [15:0] out
+);
+
+    // Not, line 16
+    wire part0_out;
+    Not part0 (.in(in[0]), .out(part0_out));
+    assign out[0] = part0_out;
+
+    // Not, line 17
+    wire part1_out;
+    Not part1 (.in(in[1]), .out(part1_out));
+    assign out[1] = part1_out;
+
+    // Not, line 18
+    wire part2_out;
+    Not part2 (.in(in[2]), .out(part2_out));
+    assign out[2] = part2_out;
+
+    // Not, line 19
+    wire part3_out;
+    Not part3 (.in(in[3]), .out(part3_out));
+    assign out[3] = part3_out;
+
+    // Not, line 20
+    wire part4_out;
+    Not part4 (.in(in[4]), .out(part4_out));
+    assign out[4] = part4_out;
+
+    // Not, line 21
+    wire part5_out;
+    Not part5 (.in(in[5]), .out(part5_out));
+    assign out[5] = part5_out;
+
+    // Not, line 22
+    wire part6_out;
+    Not part6 (.in(in[6]), .out(part6_out));
+    assign out[6] = part6_out;
+
+    // Not, line 23
+    wire part7_out;
+    Not part7 (.in(in[7]), .out(part7_out));
+    assign out[7] = part7_out;
+
+    // Not, line 24
+    wire part8_out;
+    Not part8 (.in(in[8]), .out(part8_out));
+    assign out[8] = part8_out;
+
+    // Not, line 25
+    wire part9_out;
+    Not part9 (.in(in[9]), .out(part9_out));
+    assign out[9] = part9_out;
+
+    // Not, line 26
+    wire part10_out;
+    Not part10 (.in(in[10]), .out(part10_out));
+    assign out[10] = part10_out;
+
+    // Not, line 27
+    wire part11_out;
+    Not part11 (.in(in[11]), .out(part11_out));
+    assign out[11] = part11_out;
+
+    // Not, line 28
+    wire part12_out;
+    Not part12 (.in(in[12]), .out(part12_out));
+    assign out[12] = part12_out;
+
+    // Not, line 29
+    wire part13_out;
+    Not part13 (.in(in[13]), .out(part13_out));
+    assign out[13] = part13_out;
+
+    // Not, line 30
+    wire part14_out;
+    Not part14 (.in(in[14]), .out(part14_out));
+    assign out[14] = part14_out;
+
+    // Not, line 31
+    wire part15_out;
+    Not part15 (.in(in[15]), .out(part15_out));
+    assign out[15] = part15_out;
+endmodule
+
+// Built-in Register
+module Register (
+    input clk,
+    input [15:0] in,
+    input load,
+    output reg [15:0] out
+);
+    initial out = {16{1'b0}};
+
+    always @(posedge clk)
+        if (load)
+            out <= in;
+endmodule
+
+// testdata/Slices.hdl
+module Slices (
+    input clk,
+    input [3:0] a,
+    input wire_,
+    input load,
+    output [15:0] out,
+    output msb,
+    output reg_
+);
+    wire [15:0] all;
+
+    // Not16, line 9
+    wire [15:0] part0_out;
+    Not16 part0 (.in({wire_, {6{1'b0}}, 1'b1, {4{1'b0}}, a}), .out(part0_out));
+    assign out[7:0] = part0_out[7:0];
+    assign msb = part0_out[15];
+    assign all = part0_out;
+
+    // Register, line 10
+    wire [15:0] part1_out;
+    Register part1 (.clk(clk), .in(all), .load(load), .out(part1_out));
+    assign out[15:8] = part1_out[15:8];
+    assign reg_ = part1_out[0];
+endmodule
@@ -0,0 +1,103 @@
+// Xor, exported from the Nand2Tetris HDL
+
+// Built-in Nand
+module Nand (
+    input a,
+    input b,
+    output out
+);
+    assign out = ~(a & b);
+endmodule
+
+// ../../project1/Not.hdl
+module Not (
+    input in,
+    output out
+);
+
+    // Nand, line 16
+    wire part0_out;
+    Nand part0 (.a(in), .b(in), .out(part0_out));
+    assign out = part0_out;
+endmodule
+
+// ../../project1/And.hdl
+module And (
+    input a,
+    input b,
+    output out
+);
+    wire nand_;
+
+    // Nand, line 17
+    wire part0_out;
+    Nand part0 (.a(a), .b(b), .out(part0_out));
+    assign nand_ = part0_out;
+
+    // Not, line 18
+    wire part1_out;
+    Not part1 (.in(nand_), .out(part1_out));
+    assign out = part1_out;
+endmodule
+
+// ../../project1/Or.hdl
+module Or (
+    input a,
+    input b,
+    output out
+);
+    wire na;
+    wire nb;
+
+    // Not, line 17
+    wire part0_out;
+    Not part0 (.in(a), .out(part0_out));
+    assign na = part0_out;
+
+    // Not, line 18
+    wire part1_out;
+    Not part1 (.in(b), .out(part1_out));
+    assign nb = part1_out;
+
+    // Nand, line 19
+    wire part2_out;
+    Nand part2 (.a(na), .b(nb), .out(part2_out));
+    assign out = part2_out;
+endmodule
+
+// ../../project1/Xor.hdl
+module Xor (
+    input a,
+    input b,
+    output out
+);
+    wire apnb;
+    wire na;
+    wire napb;
+    wire nb;
+
+    // Not, line 16
+    wire part0_out;
+    Not part0 (.in(a), .out(part0_out));
+    assign na = part0_out;
+
+    // Not, line 17
+    wire part1_out;
+    Not part1 (.in(b), .out(part1_out));
+    assign nb = part1_out;
+
+    // And, line 19
+    wire part2_out;
+    And part2 (.a(a), .b(nb), .out(part2_out));
+    assign apnb = part2_out;
+
+    // And, line 20
+    wire part3_out;
+    And part3 (.a(na), .b(b), .out(part3_out));
+    assign napb = part3_out;
+
+    // Or, line 22
+    wire part4_out;
+    Or part4 (.a(apnb), .b(napb), .out(part4_out));
+    assign out = part4_out;
+endmodule
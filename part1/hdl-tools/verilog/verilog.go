@@ -0,0 +1,369 @@
+// Package verilog translates HDL chips into synthesisable Verilog, one module
+// per chip. Built-in chips become behavioural modules.
+package verilog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"nand2tetris/hdl-tools/hdl"
+	"sort"
+	"strings"
+)
+
+type Options struct {
+	// Memory file loaded into ROM32K with $readmemb, e.g. the .mem file
+	// written by "hack-assembler --mem". ROM32K starts empty if not set.
+	RomFile string
+}
+
+// Writes chip, and every chip it uses, as Verilog modules to w. The modules
+// of parts come before the modules using them. Modules of chips holding
+// state have a "clk" input, the clock the HDL leaves implicit.
+func Export(w io.Writer, l *hdl.Loader, chip *hdl.Chip, opts Options) error {
+	e := exporter{loader: l, opts: opts, clocked: map[string]bool{}, done: map[string]bool{}, loading: map[string]bool{}}
+	if err := e.collect(chip); err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "// %s, exported from the Nand2Tetris HDL\n", chip.Name)
+
+	for _, c := range e.order {
+		out.WriteString("\n")
+
+		var err error
+		if c.Builtin != nil {
+			err = e.writeBuiltin(out, c)
+		} else {
+			err = e.writeModule(out, c)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+type exporter struct {
+	loader  *hdl.Loader
+	opts    Options
+	order   []*hdl.Chip     // Chips to write, parts first
+	clocked map[string]bool // Chips holding state, which need the clock
+	done    map[string]bool
+	loading map[string]bool // Chips being collected, to catch recursion
+}
+
+// Adds chip and its parts to the chips to write
+func (e *exporter) collect(chip *hdl.Chip) error {
+	if e.done[chip.Name] {
+		return nil
+	}
+	if e.loading[chip.Name] {
+		return &hdl.Error{File: chip.File, Line: chip.Line, Msg: "chip " + chip.Name + " contains itself"}
+	}
+	e.loading[chip.Name] = true
+	defer delete(e.loading, chip.Name)
+
+	if chip.Builtin != nil {
+		e.clocked[chip.Name] = len(chip.Builtin.Clocked) != 0
+	}
+
+	for _, part := range chip.Parts {
+		def, err := e.loader.Load(part.Name)
+		if err != nil {
+			return &hdl.Error{File: chip.File, Line: part.Line, Msg: err.Error()}
+		}
+		if err := e.collect(def); err != nil {
+			return err
+		}
+
+		if e.clocked[def.Name] {
+			e.clocked[chip.Name] = true
+		}
+	}
+
+	e.done[chip.Name] = true
+	e.order = append(e.order, chip)
+	return nil
+}
+
+// Writes the module of a chip made of parts
+func (e *exporter) writeModule(w *bufio.Writer, chip *hdl.Chip) error {
+	m := module{chip: chip, names: map[string]bool{}, widths: map[string]int{}}
+	for _, ps := range [][]hdl.Pin{chip.In, chip.Out} {
+		for _, p := range ps {
+			m.names[p.Name] = true
+		}
+	}
+
+	// Parts, with their definitions
+	defs := make([]*hdl.Chip, len(chip.Parts))
+	for i, part := range chip.Parts {
+		def, err := e.loader.Load(part.Name)
+		if err != nil {
+			return &hdl.Error{File: chip.File, Line: part.Line, Msg: err.Error()}
+		}
+		defs[i] = def
+
+		if err := m.internalPins(part, def); err != nil {
+			return err
+		}
+	}
+
+	var ports []string
+	if e.clocked[chip.Name] {
+		ports = append(ports, "input clk")
+	}
+	for _, p := range chip.In {
+		ports = append(ports, "input "+vector(p.Width)+ident(p.Name))
+	}
+	for _, p := range chip.Out {
+		ports = append(ports, "output "+vector(p.Width)+ident(p.Name))
+	}
+
+	fmt.Fprintf(w, "// %s\n", chip.File)
+	fmt.Fprintf(w, "module %s (\n    %s\n);\n", ident(chip.Name), strings.Join(ports, ",\n    "))
+
+	// Internal pins
+	var internal []string
+	for name := range m.widths {
+		internal = append(internal, name)
+	}
+	sort.Strings(internal)
+	for _, name := range internal {
+		fmt.Fprintf(w, "    wire %s%s;\n", vector(m.widths[name]), ident(name))
+	}
+
+	for i, part := range chip.Parts {
+		w.WriteString("\n")
+		if err := m.writePart(w, part, defs[i], i, e.clocked[defs[i].Name]); err != nil {
+			return err
+		}
+	}
+
+	w.WriteString("endmodule\n")
+	return nil
+}
+
+type module struct {
+	chip   *hdl.Chip
+	names  map[string]bool // Names taken in the module
+	widths map[string]int  // Internal pin -> width
+}
+
+// Records the internal pins connected to a part. Their width is the width
+// of the part pin they're connected to.
+func (m *module) internalPins(part hdl.Part, def *hdl.Chip) error {
+	for _, conn := range part.Conns {
+		sig := conn.Signal
+		if sig.IsConst() || m.chip.IsInput(sig.Name) || m.chip.IsOutput(sig.Name) {
+			continue
+		}
+
+		pin, found := def.Pin(conn.Part.Name)
+		if !found {
+			return &hdl.Error{File: m.chip.File, Line: conn.Line, Msg: "chip " + def.Name + " has no pin called " + conn.Part.Name}
+		}
+
+		if _, found := m.widths[sig.Name]; !found || def.IsOutput(pin.Name) {
+			m.widths[sig.Name] = conn.Part.Width(pin.Width)
+		}
+		m.names[sig.Name] = true
+	}
+
+	return nil
+}
+
+// Returns a name not taken in the module, based on name
+func (m *module) unique(name string) string {
+	for m.names[name] {
+		name += "_"
+	}
+	m.names[name] = true
+
+	return name
+}
+
+// Writes the instance of the i-th part. Every output of the part drives a
+// wire of its own, assigned to the signals connected to it.
+func (m *module) writePart(w *bufio.Writer, part hdl.Part, def *hdl.Chip, i int, clocked bool) error {
+	inst := m.unique(fmt.Sprintf("part%d", i))
+
+	var conns []string
+	var wires []string
+	var assigns []string
+	if clocked {
+		conns = append(conns, ".clk(clk)")
+	}
+
+	for _, pin := range def.In {
+		expr, err := m.inputExpr(part, pin)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, fmt.Sprintf(".%s(%s)", ident(pin.Name), expr))
+	}
+
+	for _, pin := range def.Out {
+		var wire string
+		for _, conn := range part.Conns {
+			if conn.Part.Name != pin.Name {
+				continue
+			}
+			if conn.Signal.IsConst() || m.chip.IsInput(conn.Signal.Name) {
+				return &hdl.Error{File: m.chip.File, Line: conn.Line, Msg: "output " + conn.Part.String() + " can't drive " + conn.Signal.Name}
+			}
+
+			if wire == "" {
+				wire = m.unique(inst + "_" + pin.Name)
+				wires = append(wires, fmt.Sprintf("    wire %s%s;\n", vector(pin.Width), ident(wire)))
+			}
+			assigns = append(assigns, fmt.Sprintf("    assign %s = %s;\n",
+				m.signalExpr(conn.Signal, conn.Part.Width(pin.Width)), sliceExpr(wire, conn.Part, pin.Width)))
+		}
+		conns = append(conns, fmt.Sprintf(".%s(%s)", ident(pin.Name), ident(wire)))
+	}
+
+	fmt.Fprintf(w, "    // %s, line %d\n", part.Name, part.Line)
+	for _, wire := range wires {
+		w.WriteString(wire)
+	}
+	fmt.Fprintf(w, "    %s %s (%s);\n", ident(def.Name), ident(inst), strings.Join(conns, ", "))
+	for _, a := range assigns {
+		w.WriteString(a)
+	}
+
+	return nil
+}
+
+// A run of bits of a part input, connected to a signal
+type segment struct {
+	lo, hi int
+	expr   string
+}
+
+// Returns the expression feeding the input pin of a part: the signal
+// connected to it, or the concatenation of the signals connected to its
+// bits. Unconnected bits are false.
+func (m *module) inputExpr(part hdl.Part, pin hdl.Pin) (string, error) {
+	var segs []segment
+	for _, conn := range part.Conns {
+		if conn.Part.Name != pin.Name {
+			continue
+		}
+
+		lo, hi := 0, pin.Width-1
+		if conn.Part.IsSliced() {
+			lo, hi = conn.Part.Lo, conn.Part.Hi
+		}
+		if hi >= pin.Width {
+			return "", &hdl.Error{File: m.chip.File, Line: conn.Line, Msg: conn.Part.String() + " is out of range"}
+		}
+		segs = append(segs, segment{lo: lo, hi: hi, expr: m.signalExpr(conn.Signal, hi-lo+1)})
+	}
+
+	// Most significant bits first, as in a Verilog concatenation
+	sort.Slice(segs, func(i, j int) bool { return segs[i].lo > segs[j].lo })
+
+	var parts []string
+	next := pin.Width - 1 // Highest bit not covered yet
+	for _, seg := range segs {
+		if seg.hi < next {
+			parts = append(parts, constant(false, next-seg.hi))
+		}
+		parts = append(parts, seg.expr)
+		next = seg.lo - 1
+	}
+	if next >= 0 {
+		parts = append(parts, constant(false, next+1))
+	}
+
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+
+	return "{" + strings.Join(parts, ", ") + "}", nil
+}
+
+// Returns the Verilog expression of a signal of the chip, width bits wide
+func (m *module) signalExpr(sig hdl.PinRef, width int) string {
+	if sig.IsConst() {
+		return constant(sig.Name == hdl.True, width)
+	}
+
+	pinWidth := m.widths[sig.Name]
+	if p, found := m.chip.Pin(sig.Name); found {
+		pinWidth = p.Width
+	}
+
+	return sliceExpr(sig.Name, sig, pinWidth)
+}
+
+// Returns "name", "name[i]" or "name[hi:lo]". Whole pins, and single-bit
+// pins which can't be indexed, are left unsliced.
+func sliceExpr(name string, ref hdl.PinRef, width int) string {
+	switch {
+	case !ref.IsSliced() || width == 1:
+		return ident(name)
+	case ref.Lo == ref.Hi:
+		return fmt.Sprintf("%s[%d]", ident(name), ref.Lo)
+	default:
+		return fmt.Sprintf("%s[%d:%d]", ident(name), ref.Hi, ref.Lo)
+	}
+}
+
+// e.g. "1'b0" or "{16{1'b1}}"
+func constant(value bool, width int) string {
+	bit := "1'b0"
+	if value {
+		bit = "1'b1"
+	}
+	if width == 1 {
+		return bit
+	}
+
+	return fmt.Sprintf("{%d{%s}}", width, bit)
+}
+
+// Returns the range of a bus declaration, e.g. "[15:0] ", or "" for a
+// single bit
+func vector(width int) string {
+	if width == 1 {
+		return ""
+	}
+
+	return fmt.Sprintf("[%d:0] ", width-1)
+}
+
+// Names of the HDL that are reserved in Verilog, or clash with the clock, get
+// a trailing "_"
+func ident(name string) string {
+	if keywords[name] {
+		return name + "_"
+	}
+
+	return name
+}
+
+var keywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`always and assign automatic begin buf bufif0
+		bufif1 case casex casez cell cmos config deassign default defparam
+		design disable edge else end endcase endconfig endfunction endgenerate
+		endmodule endprimitive endspecify endtable endtask event for force
+		forever fork function generate genvar highz0 highz1 if ifnone incdir
+		include initial inout input instance integer join large liblist library
+		localparam macromodule medium module nand negedge nmos nor
+		noshowcancelled not notif0 notif1 or output parameter pmos posedge
+		primitive pull0 pull1 pulldown pullup pulsestyle_ondetect
+		pulsestyle_onevent rcmos real realtime reg release repeat rnmos rpmos
+		rtran rtranif0 rtranif1 scalared showcancelled signed small specify
+		specparam strong0 strong1 supply0 supply1 table task time tran tranif0
+		tranif1 tri tri0 tri1 triand trior trireg unsigned use uwire vectored
+		wait wand weak0 weak1 while wire wor xnor xor clk`) {
+		keywords[k] = true
+	}
+}
@@ -0,0 +1,94 @@
+package verilog
+
+import (
+	"bytes"
+	"flag"
+	"nand2tetris/hdl-tools/hdl"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata")
+
+func export(t *testing.T, l *hdl.Loader, name string, opts Options) string {
+	chip, err := l.Load(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := Export(&out, l, chip, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	return out.String()
+}
+
+func TestGolden(t *testing.T) {
+	l := hdl.NewLoader("testdata", "../../project1")
+
+	for _, name := range []string{"Slices", "Xor"} {
+		got := export(t, l, name, Options{})
+		path := filepath.Join("testdata", name+".v")
+
+		if *update {
+			if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			t.Errorf("%s: output differs from %s, run with -update to inspect", name, path)
+		}
+	}
+}
+
+func TestExportComputer(t *testing.T) {
+	l := hdl.NewLoader("../../project5", "../../project2", "../../project1")
+	got := export(t, l, "Computer", Options{RomFile: "Pong.mem"})
+
+	// Every chip once, each part before its users, the top chip last
+	var modules []string
+	for _, line := range strings.Split(got, "\n") {
+		if strings.HasPrefix(line, "module ") {
+			modules = append(modules, strings.Fields(line)[1])
+		}
+	}
+	seen := map[string]bool{}
+	for _, m := range modules {
+		if seen[m] {
+			t.Errorf("module %s written twice", m)
+		}
+		seen[m] = true
+	}
+	for _, m := range []string{"Nand", "ROM32K", "RAM16K", "Screen", "Keyboard", "PC", "ARegister", "DRegister", "ALU", "CPU", "Memory"} {
+		if !seen[m] {
+			t.Errorf("module %s missing", m)
+		}
+	}
+	if modules[len(modules)-1] != "Computer" {
+		t.Errorf("last module = %s, want Computer", modules[len(modules)-1])
+	}
+	if strings.Index(got, "module ALU") > strings.Index(got, "module CPU") {
+		t.Error("ALU written after CPU")
+	}
+
+	if strings.Count(got, "module ") != strings.Count(got, "endmodule") {
+		t.Error("unbalanced module/endmodule")
+	}
+	if !strings.Contains(got, `parameter ROM_FILE = "Pong.mem";`) {
+		t.Error("ROM32K doesn't load the ROM file")
+	}
+
+	// Only chips holding state take the clock
+	if !strings.Contains(got, "module Computer (\n    input clk,") || strings.Contains(got, "module ALU (\n    input clk,") {
+		t.Error("clock ports misplaced")
+	}
+}
@@ -0,0 +1,211 @@
+// Package cpu emulates the Hack computer of project 5: the CPU running a
+// program from ROM, with the RAM and the memory maps of the screen and the
+// keyboard.
+package cpu
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	ROMSize   = 32768
+	RAMSize   = 24577 // 16K of RAM, the 8K screen, and the keyboard
+	ScreenAdr = 16384
+	KbdAdr    = 24576
+)
+
+// The Hack computer. Words are kept unsigned: arithmetic wraps around as in
+// the 16-bit hardware.
+type Computer struct {
+	ROM    []uint16 // The program. The rest of ROM32K reads as zeros.
+	RAM    [RAMSize]uint16
+	A, D   uint16
+	PC     uint16
+	Cycles int // Instructions executed
+
+	ops []op // ROM decoded by the first Run
+}
+
+// Returns a computer running program from address 0
+func New(program []uint16) (*Computer, error) {
+	if len(program) > ROMSize {
+		return nil, fmt.Errorf("%d instructions don't fit in the %d words of ROM32K", len(program), ROMSize)
+	}
+
+	return &Computer{ROM: program}, nil
+}
+
+// Parses machine language instructions, strings of 16 "0" and "1" as written
+// by the assembler
+func Parse(lines []string) ([]uint16, error) {
+	program := make([]uint16, len(lines))
+
+	for i, line := range lines {
+		word, err := strconv.ParseUint(line, 2, 16)
+		if err != nil || len(line) != 16 {
+			return nil, fmt.Errorf("line %d: invalid instruction %q", i+1, line)
+		}
+		program[i] = uint16(word)
+	}
+
+	return program, nil
+}
+
+// Reads a program from a .hack file. Blank lines are skipped.
+func Load(r io.Reader) ([]uint16, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return Parse(lines)
+}
+
+// Returns the instruction at address adr of ROM32K
+func (c *Computer) fetch(adr uint16) uint16 {
+	if int(adr) < len(c.ROM) {
+		return c.ROM[adr]
+	}
+
+	return 0
+}
+
+// Returns the word at address adr. Addresses past the keyboard read as 0.
+func (c *Computer) Read(adr uint16) uint16 {
+	if int(adr) < RAMSize {
+		return c.RAM[adr]
+	}
+
+	return 0
+}
+
+// Writes the word at address adr. The keyboard, and addresses past it, can't
+// be written.
+func (c *Computer) Write(adr uint16, v uint16) {
+	if adr < KbdAdr {
+		c.RAM[adr] = v
+	}
+}
+
+// Sets the key held down, as read at address KbdAdr. 0 means no key.
+func (c *Computer) SetKey(key uint16) {
+	c.RAM[KbdAdr] = key
+}
+
+// Executes one instruction. Step is the reference interpreter, decoding every
+// instruction as it goes; Run executes the same program from decoded ROM.
+func (c *Computer) Step() {
+	in := c.fetch(c.PC)
+	c.Cycles++
+
+	// A instruction
+	if in&0x8000 == 0 {
+		c.A = in
+		c.PC++
+		return
+	}
+
+	c.execute(in)
+}
+
+// Executes C instruction in at PC
+func (c *Computer) execute(in uint16) {
+	// The memory is read and written, and the jump taken, at the address
+	// held in A before the instruction
+	adr := c.A
+	y := c.A
+	if in&0x1000 != 0 {
+		y = c.Read(adr)
+	}
+	out := ALU(c.D, y, in>>6)
+
+	if in&0x08 != 0 {
+		c.Write(adr, out)
+	}
+	if in&0x10 != 0 {
+		c.D = out
+	}
+	if in&0x20 != 0 {
+		c.A = out
+	}
+
+	if jumps(out, in) {
+		c.PC = adr
+	} else {
+		c.PC++
+	}
+}
+
+// Computes the ALU output for inputs x and y, the control bits being the 6
+// lowest bits of c: zx, nx, zy, ny, f, no
+func ALU(x, y uint16, c uint16) uint16 {
+	if c&0x20 != 0 {
+		x = 0
+	}
+	if c&0x10 != 0 {
+		x = ^x
+	}
+	if c&0x08 != 0 {
+		y = 0
+	}
+	if c&0x04 != 0 {
+		y = ^y
+	}
+
+	var out uint16
+	if c&0x02 != 0 {
+		out = x + y
+	} else {
+		out = x & y
+	}
+	if c&0x01 != 0 {
+		out = ^out
+	}
+
+	return out
+}
+
+// Returns whether C instruction in jumps, given its ALU output
+func jumps(out uint16, in uint16) bool {
+	neg, zero := int16(out) < 0, out == 0
+
+	return (in&0x04 != 0 && neg) || (in&0x02 != 0 && zero) || (in&0x01 != 0 && !neg && !zero)
+}
+
+// Returns whether C instruction in always jumps
+func isUnconditionalJump(in uint16) bool {
+	return in&0x8000 != 0 && in&0x07 == 0x07
+}
+
+// Returns whether the computer is stuck in the loop ending Hack programs,
+// "(END) @END 0;JMP", or a jump to itself, or has run past the end of the
+// program, where the empty ROM does nothing
+func (c *Computer) Halted() bool {
+	in := c.fetch(c.PC)
+
+	switch {
+	case int(c.PC) >= len(c.ROM):
+		return true
+	case in == c.PC && in&0x8000 == 0:
+		// At "@END": halted if the next instruction jumps back without
+		// changing A
+		next := c.fetch(c.PC + 1)
+		return isUnconditionalJump(next) && next&0x20 == 0
+	case isUnconditionalJump(in) && in&0x20 == 0:
+		// At the jump: back to itself, or to the "@END" before it
+		return c.A == c.PC || (c.PC > 0 && c.A == c.PC-1 && c.fetch(c.PC-1) == c.PC-1)
+	}
+
+	return false
+}
@@ -0,0 +1,148 @@
+package cpu
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestALU(t *testing.T) {
+	const x, y = 17, 3
+
+	// Control bits of the computations of the Hack C instructions
+	tests := []struct {
+		comp string
+		c    uint16
+		want int16
+	}{
+		{"0", 0b101010, 0},
+		{"1", 0b111111, 1},
+		{"-1", 0b111010, -1},
+		{"D", 0b001100, x},
+		{"A", 0b110000, y},
+		{"!D", 0b001101, ^x},
+		{"!A", 0b110001, ^y},
+		{"-D", 0b001111, -x},
+		{"-A", 0b110011, -y},
+		{"D+1", 0b011111, x + 1},
+		{"A+1", 0b110111, y + 1},
+		{"D-1", 0b001110, x - 1},
+		{"A-1", 0b110010, y - 1},
+		{"D+A", 0b000010, x + y},
+		{"D-A", 0b010011, x - y},
+		{"A-D", 0b000111, y - x},
+		{"D&A", 0b000000, x & y},
+		{"D|A", 0b010101, x | y},
+	}
+
+	for _, tt := range tests {
+		if got := int16(ALU(x, y, tt.c)); got != tt.want {
+			t.Errorf("ALU(%s) = %d, want %d", tt.comp, got, tt.want)
+		}
+	}
+}
+
+func load(t testing.TB, name string) *Computer {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name+".hack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	program, err := Load(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestPrograms(t *testing.T) {
+	tests := []struct {
+		name   string
+		r0, r1 int16
+		want   int16 // R2 (R0 for Add)
+	}{
+		{"Add", 0, 0, 5},
+		{"Max", 3, 10, 10},
+		{"Max", -4, -9, -4},
+		{"Mult", 6, 7, 42},
+		{"Mult", -3, 5, -15},
+		{"Mult", 9, 0, 0},
+	}
+
+	for _, tt := range tests {
+		c := load(t, tt.name)
+		c.RAM[0], c.RAM[1] = uint16(tt.r0), uint16(tt.r1)
+
+		if !c.Run(10000) {
+			t.Errorf("%s(%d, %d) didn't halt", tt.name, tt.r0, tt.r1)
+			continue
+		}
+
+		got := int16(c.RAM[2])
+		if tt.name == "Add" {
+			got = int16(c.RAM[0])
+		}
+		if got != tt.want {
+			t.Errorf("%s(%d, %d) = %d, want %d", tt.name, tt.r0, tt.r1, got, tt.want)
+		}
+	}
+}
+
+func TestFill(t *testing.T) {
+	c := load(t, "Fill")
+
+	c.SetKey(65)
+	if c.Run(200000) {
+		t.Fatal("Fill halted")
+	}
+	if c.RAM[ScreenAdr] != 0xFFFF || c.RAM[KbdAdr-1] != 0xFFFF {
+		t.Errorf("screen not filled: %04x...%04x", c.RAM[ScreenAdr], c.RAM[KbdAdr-1])
+	}
+
+	c.SetKey(0)
+	c.Run(200000)
+	if c.RAM[ScreenAdr] != 0 || c.RAM[KbdAdr-1] != 0 {
+		t.Errorf("screen not cleared: %04x...%04x", c.RAM[ScreenAdr], c.RAM[KbdAdr-1])
+	}
+}
+
+func TestMemoryMap(t *testing.T) {
+	// @24576 M=1 D=M @100 M=D, then "(END) @5 0;JMP"
+	program, err := Parse([]string{
+		"0110000000000000",
+		"1110111111001000",
+		"1111110000010000",
+		"0000000001100100",
+		"1110001100001000",
+		"0000000000000101",
+		"1110101010000111",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(program)
+	c.SetKey(32)
+
+	if !c.Run(100) {
+		t.Fatal("didn't halt")
+	}
+	if c.RAM[100] != 32 || c.Cycles != 5 {
+		t.Errorf("RAM[100] = %d after %d cycles, want the key, 32, after 5", c.RAM[100], c.Cycles)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, line := range []string{"0101", "01010101010101012", "0000000000000000 "} {
+		if _, err := Parse([]string{line}); err == nil {
+			t.Errorf("Parse(%q) succeeded", line)
+		}
+	}
+}
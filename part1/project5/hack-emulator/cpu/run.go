@@ -0,0 +1,201 @@
+package cpu
+
+// This file contains the fast interpreter: ROM is decoded once into a table
+// of operations, common instructions get their own operation, and every A
+// instruction is run along with the C instruction following it.
+
+// Operations of the decoded ROM
+const (
+	opA     = iota // An A instruction on its own
+	opC            // Any C instruction, through the ALU
+	opDM           // D=M
+	opMD           // M=D
+	opDA           // D=A
+	opAM           // A=M
+	opMInc         // M=M+1
+	opAMDec        // AM=M-1, popping the stack
+	opJump         // 0;JMP
+	opDJump        // D;JGT... jumping on D
+)
+
+// Instructions of the operations, with the 2 unused bits of C instructions set
+const (
+	inDM     = 0b1111110000010000
+	inMD     = 0b1110001100001000
+	inDA     = 0b1110110000010000
+	inAM     = 0b1111110000100000
+	inMInc   = 0b1111110111001000
+	inAMDec  = 0b1111110010101000
+	inJump   = 0b1110101010000111
+	inDJump  = 0b1110001100000000 // Plus the jump bits
+	unusedIn = 0x6000
+)
+
+// How the computer can be halted at an address, see Halted
+const (
+	haltNever  = iota
+	haltAlways // At the "@END" of "(END) @END 0;JMP"
+	haltIfA    // At an unconditional jump keeping A, if it jumps back
+)
+
+// A decoded instruction. An A instruction followed by a C instruction is
+// fused with it: both run in one operation, of the C instruction's kind.
+type op struct {
+	kind  uint8
+	fused bool
+	halt  uint8
+	value uint16 // The address loaded by the A instruction
+	in    uint16 // The C instruction
+}
+
+// Decodes the program, once
+func (c *Computer) decode() []op {
+	if c.ops != nil {
+		return c.ops
+	}
+
+	ops := make([]op, len(c.ROM))
+	for i, in := range c.ROM {
+		ops[i] = op{kind: opC, in: in}
+		if in&0x8000 == 0 {
+			ops[i] = op{kind: opA, value: in}
+		} else {
+			ops[i].kind = kindOf(in)
+		}
+
+		pc := uint16(i)
+		switch {
+		case in == pc && in&0x8000 == 0:
+			next := c.fetch(pc + 1)
+			if isUnconditionalJump(next) && next&0x20 == 0 {
+				ops[i].halt = haltAlways
+			}
+		case isUnconditionalJump(in) && in&0x20 == 0:
+			ops[i].halt = haltIfA
+		}
+	}
+
+	// Fusing skips the halt check of the C instruction, so it's never done
+	// when the C instruction can halt
+	for i := 0; i+1 < len(ops); i++ {
+		if ops[i].kind == opA && ops[i+1].kind != opA && ops[i+1].halt == haltNever {
+			ops[i].fused = true
+			ops[i].kind = ops[i+1].kind
+			ops[i].in = ops[i+1].in
+		}
+	}
+
+	c.ops = ops
+
+	return ops
+}
+
+// Returns the operation of C instruction in
+func kindOf(in uint16) uint8 {
+	switch in | unusedIn {
+	case inDM:
+		return opDM
+	case inMD:
+		return opMD
+	case inDA:
+		return opDA
+	case inAM:
+		return opAM
+	case inMInc:
+		return opMInc
+	case inAMDec:
+		return opAMDec
+	case inJump:
+		return opJump
+	}
+	if (in|unusedIn)&^0x07 == inDJump && in&0x07 != 0 {
+		return opDJump
+	}
+
+	return opC
+}
+
+// Runs the program until it halts, or for at most maxCycles instructions if
+// maxCycles > 0. Returns whether it halted.
+//
+// ROM is decoded by the first Run: changes to ROM after it aren't seen. Run
+// gives the same results as running Step until Halted.
+func (c *Computer) Run(maxCycles int) bool {
+	ops := c.decode()
+
+	for n := 0; maxCycles <= 0 || n < maxCycles; {
+		pc := c.PC
+		if int(pc) >= len(ops) {
+			return true
+		}
+		o := &ops[pc]
+
+		switch o.halt {
+		case haltAlways:
+			return true
+		case haltIfA:
+			if c.A == pc || (pc > 0 && c.A == pc-1 && ops[pc-1].halt == haltAlways) {
+				return true
+			}
+		}
+
+		if o.kind == opA {
+			c.A = o.value
+			c.PC++
+			c.Cycles++
+			n++
+			continue
+		}
+		if o.fused {
+			if maxCycles > 0 && n+1 == maxCycles {
+				// No cycle left for the C instruction
+				c.A = o.value
+				c.PC++
+				c.Cycles++
+				return c.Halted()
+			}
+			c.A = o.value
+			pc++
+			c.Cycles++
+			n++
+		}
+		c.Cycles++
+		n++
+
+		switch o.kind {
+		case opDM:
+			c.D = c.Read(c.A)
+			c.PC = pc + 1
+		case opMD:
+			c.Write(c.A, c.D)
+			c.PC = pc + 1
+		case opDA:
+			c.D = c.A
+			c.PC = pc + 1
+		case opAM:
+			c.A = c.Read(c.A)
+			c.PC = pc + 1
+		case opMInc:
+			c.Write(c.A, c.Read(c.A)+1)
+			c.PC = pc + 1
+		case opAMDec:
+			v := c.Read(c.A) - 1
+			c.Write(c.A, v)
+			c.A = v
+			c.PC = pc + 1
+		case opJump:
+			c.PC = c.A
+		case opDJump:
+			if jumps(c.D, o.in) {
+				c.PC = c.A
+			} else {
+				c.PC = pc + 1
+			}
+		default:
+			c.PC = pc
+			c.execute(o.in)
+		}
+	}
+
+	return c.Halted()
+}
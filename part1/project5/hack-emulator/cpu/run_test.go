@@ -0,0 +1,137 @@
+package cpu
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// Runs the program with the reference interpreter, as Run does
+func runReference(c *Computer, maxCycles int) bool {
+	for n := 0; maxCycles <= 0 || n < maxCycles; n++ {
+		if c.Halted() {
+			return true
+		}
+		c.Step()
+	}
+
+	return c.Halted()
+}
+
+// Returns whether the two computers are in the same state
+func sameState(t *testing.T, fast, ref *Computer) bool {
+	t.Helper()
+
+	if fast.A != ref.A || fast.D != ref.D || fast.PC != ref.PC || fast.Cycles != ref.Cycles {
+		t.Errorf("A, D, PC, cycles = %d %d %d %d, want %d %d %d %d",
+			fast.A, fast.D, fast.PC, fast.Cycles, ref.A, ref.D, ref.PC, ref.Cycles)
+		return false
+	}
+	if fast.RAM != ref.RAM {
+		for adr := range fast.RAM {
+			if fast.RAM[adr] != ref.RAM[adr] {
+				t.Errorf("RAM[%d] = %d, want %d", adr, fast.RAM[adr], ref.RAM[adr])
+				break
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// Returns a random instruction, mostly the common ones, jumping within
+// the first size addresses
+func randomInstruction(r *rand.Rand, size int) uint16 {
+	switch r.Intn(12) {
+	case 0, 1, 2:
+		return uint16(r.Intn(size))
+	case 3:
+		adrs := []uint16{ScreenAdr, KbdAdr - 1, KbdAdr, KbdAdr + 1, 0x7FFF}
+		return adrs[r.Intn(len(adrs))]
+	case 4:
+		return inDJump | uint16(r.Intn(8))
+	case 5:
+		// Any C instruction, the unused bits too
+		return 0x8000 | uint16(r.Intn(0x8000))
+	}
+
+	common := []uint16{inDM, inMD, inDA, inAM, inMInc, inAMDec, inJump}
+	in := common[r.Intn(len(common))]
+	if r.Intn(4) == 0 {
+		in &^= uint16(r.Intn(4)) << 13
+	}
+
+	return in
+}
+
+// Runs random programs, and the test programs, on both interpreters in
+// random slices of cycles, comparing their states after each
+func TestRunDifferential(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	var programs [][]uint16
+	for _, name := range []string{"Add", "Max", "Mult", "Fill"} {
+		programs = append(programs, load(t, name).ROM)
+	}
+	for i := 0; i < 500; i++ {
+		size := 1 + r.Intn(64)
+		program := make([]uint16, size)
+		for j := range program {
+			program[j] = randomInstruction(r, size+2)
+		}
+		// Some end with the loop ending Hack programs
+		if r.Intn(2) == 0 {
+			n := uint16(len(program))
+			program = append(program, n, inJump&^uint16(r.Intn(2))<<13)
+		}
+		programs = append(programs, program)
+	}
+
+	for i, program := range programs {
+		fast, _ := New(program)
+		ref, _ := New(program)
+		for adr := 0; adr < 64; adr++ {
+			v := uint16(r.Intn(0x10000))
+			fast.RAM[adr], ref.RAM[adr] = v, v
+		}
+		key := uint16(r.Intn(2) * 65)
+		fast.SetKey(key)
+		ref.SetKey(key)
+
+		for fast.Cycles < 5000 {
+			n := 1 + r.Intn(50)
+			halted, want := fast.Run(n), runReference(ref, n)
+			if halted != want {
+				t.Errorf("program %d: Run(%d) = %v after %d cycles, want %v", i, n, halted, ref.Cycles, want)
+			}
+			if !sameState(t, fast, ref) {
+				t.Fatalf("program %d: %016b", i, program)
+			}
+			if halted {
+				break
+			}
+		}
+	}
+}
+
+// Reports the instructions per second of run on Fill, with a key held down
+func benchmarkFill(b *testing.B, run func(c *Computer, n int) bool) {
+	c := load(b, "Fill")
+	c.SetKey(65)
+
+	const slice = 100000
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		run(c, slice)
+	}
+	b.ReportMetric(float64(b.N)*slice/time.Since(start).Seconds(), "instr/s")
+}
+
+func BenchmarkRun(b *testing.B) {
+	benchmarkFill(b, (*Computer).Run)
+}
+
+func BenchmarkStep(b *testing.B) {
+	benchmarkFill(b, runReference)
+}
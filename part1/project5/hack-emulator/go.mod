@@ -0,0 +1,3 @@
+module nand2tetris/hack-emulator
+
+go 1.18
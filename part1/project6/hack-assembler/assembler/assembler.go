@@ -0,0 +1,200 @@
+// Package assembler translates Hack assembly to Hack machine language.
+package assembler
+
+// This file contains the assembler/translator logic.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	varStore  symbolStore
+	jmpStore  = dataStore{}
+	compStore = dataStore{}
+
+	// The builtin symbols alone, for objects
+	builtins = symbolStore{store: dataStore{}}
+
+	// The variable store is shared: one program is assembled at a time
+	storeLock sync.Mutex
+)
+
+func init() {
+	// initialises data stores
+	jmpStore.populateJMP()
+	compStore.populateCompPatterns()
+	builtins.populateBuiltinVars()
+}
+
+// Start every assembly with only the builtin symbols
+func resetSymbols() {
+	varStore = symbolStore{store: dataStore{}, used: 15, vars: map[string]bool{}} // variable memory starts at 16
+	varStore.populateBuiltinVars()
+}
+
+// Assembles the Hack assembly read from r, and returns its machine language
+// instructions, as strings of 16 "0" and "1"
+func Assemble(r io.Reader) ([]string, error) {
+	outLines, _, err := AssembleWithLabels(r)
+
+	return outLines, err
+}
+
+// Assembles as Assemble does, and also returns the address of every label
+// declared by the program
+func AssembleWithLabels(r io.Reader) ([]string, map[string]int, error) {
+	storeLock.Lock()
+	defer storeLock.Unlock()
+	resetSymbols()
+
+	// Using array allows convenient access to the "instruction - instruction
+	// number" mapping, and the "total instructions" count. Once comments are
+	// removed, the array element index == instruction number, and array
+	// element value == assembly instruction.
+	var inLines []string
+	if err := readInput(&inLines, r); err != nil {
+		return nil, nil, err
+	}
+
+	// Only the builtin symbols and the labels are known before assembling
+	labels := map[string]int{}
+	for symbol, v := range varStore.store {
+		if _, found := builtins.store[symbol]; !found {
+			labels[symbol], _ = strconv.Atoi(v)
+		}
+	}
+
+	var outLines []string
+	if err := assemble(&inLines, &outLines); err != nil {
+		return nil, nil, err
+	}
+
+	return outLines, labels, nil
+}
+
+// Read the contents of r line-by-line and store each instruction as an array
+// element of buffer
+func readInput(buf *[]string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		line = removeInlineComment(line)
+		line = strings.TrimSpace(line)
+
+		// Entire line is comment or white space, don't add to buffer
+		if len(line) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(line, "(") && strings.HasSuffix(line, ")") {
+			// Line is a jump label. Process it and don't add to instruction
+			// list
+			processLabel(buf, line)
+			continue
+		}
+
+		*buf = append(*buf, line)
+	}
+
+	return scanner.Err()
+}
+
+// Takes the assembly instructions in bufIn, translates to their machine language
+// equivalent and stores them in bufOut, line-by-line
+func assemble(bufIn *[]string, bufOut *[]string) error {
+	for _, s := range *bufIn {
+		var translated string
+		var err error
+
+		if strings.HasPrefix(s, markAInstruction) {
+			s, err = resolveSymbol(s)
+			if err == nil {
+				translated, err = translateA(s)
+			}
+		} else {
+			translated, err = translateC(s)
+		}
+		if err != nil {
+			return err
+		}
+
+		*bufOut = append(*bufOut, translated)
+	}
+
+	return nil
+}
+
+// Takes a single A instruction and returns its machine language equivalent
+func translateA(in string) (string, error) {
+	address, err := strconv.Atoi(in[1:])
+	if err != nil {
+		return "", fmt.Errorf("unable to parse A instruction %q: %s", in, err)
+	}
+
+	return encodeA(in, address)
+}
+
+// Returns the machine language A instruction loading address, checked against
+// the 15 bits of the instruction. in is the assembly instruction, for errors.
+func encodeA(in string, address int) (string, error) {
+	if address < 0 || address > maxAddress {
+		return "", fmt.Errorf("A instruction %q out of range 0-%d", in, maxAddress)
+	}
+
+	return fmt.Sprintf("%s%015s", opcodeA, getBinaryString(address)), nil
+}
+
+// Takes a single C instruction and returns its machine language equivalent
+func translateC(in string) (string, error) {
+	asmC := parseC(in)
+	binC := binaryInC{opcode: opcodeC, jump: "000"}
+
+	if strings.Trim(asmC.dest, "AMD") != "" {
+		return "", fmt.Errorf("invalid destination in C instruction %q", in)
+	}
+
+	// Handles destination bits: switch on the corresponding destination bit
+	if strings.ContainsAny(asmC.dest, "M") {
+		binC.dest[iM] = 1
+	}
+	if strings.ContainsAny(asmC.dest, "D") {
+		binC.dest[iD] = 1
+	}
+	if strings.ContainsAny(asmC.dest, "A") {
+		binC.dest[iA] = 1
+	}
+
+	// Handles jump bits
+	if len(asmC.jump) != 0 {
+		jump, found := jmpStore[asmC.jump]
+		if !found {
+			return "", fmt.Errorf("invalid jump in C instruction %q", in)
+		}
+		binC.jump = jump
+	}
+
+	// Handles a bit
+	if strings.ContainsAny(asmC.comp, "M") && !strings.ContainsAny(asmC.comp, "A") {
+		binC.aOrM = 1
+	}
+
+	// Handles computation bits
+	for pattern, binary := range compStore {
+		if regexp.MustCompile(pattern).MatchString(asmC.comp) {
+			binC.comp = binary
+			break
+		}
+	}
+	if binC.comp == "" {
+		return "", fmt.Errorf("invalid computation in C instruction %q", in)
+	}
+
+	return binC.String(), nil
+}
@@ -0,0 +1,248 @@
+package assembler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTranslateC(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		// Every computation
+		{"0", "1110101010000000"},
+		{"1", "1110111111000000"},
+		{"-1", "1110111010000000"},
+		{"D", "1110001100000000"},
+		{"A", "1110110000000000"},
+		{"M", "1111110000000000"},
+		{"!D", "1110001101000000"},
+		{"!A", "1110110001000000"},
+		{"!M", "1111110001000000"},
+		{"-D", "1110001111000000"},
+		{"-A", "1110110011000000"},
+		{"-M", "1111110011000000"},
+		{"D+1", "1110011111000000"},
+		{"A+1", "1110110111000000"},
+		{"M+1", "1111110111000000"},
+		{"D-1", "1110001110000000"},
+		{"A-1", "1110110010000000"},
+		{"M-1", "1111110010000000"},
+		{"D+A", "1110000010000000"},
+		{"D+M", "1111000010000000"},
+		{"D-A", "1110010011000000"},
+		{"D-M", "1111010011000000"},
+		{"A-D", "1110000111000000"},
+		{"M-D", "1111000111000000"},
+		{"D&A", "1110000000000000"},
+		{"D&M", "1111000000000000"},
+		{"D|A", "1110010101000000"},
+		{"D|M", "1111010101000000"},
+
+		// Commuted forms
+		{"A+D", "1110000010000000"},
+		{"M+D", "1111000010000000"},
+		{"M&D", "1111000000000000"},
+		{"A|D", "1110010101000000"},
+
+		// Every destination
+		{"M=0", "1110101010001000"},
+		{"D=0", "1110101010010000"},
+		{"MD=0", "1110101010011000"},
+		{"A=0", "1110101010100000"},
+		{"AM=0", "1110101010101000"},
+		{"AD=0", "1110101010110000"},
+		{"AMD=0", "1110101010111000"},
+
+		// Every jump
+		{"D;JGT", "1110001100000001"},
+		{"D;JEQ", "1110001100000010"},
+		{"D;JGE", "1110001100000011"},
+		{"D;JLT", "1110001100000100"},
+		{"D;JNE", "1110001100000101"},
+		{"D;JLE", "1110001100000110"},
+		{"0;JMP", "1110101010000111"},
+
+		{"MD=M-1", "1111110010011000"},
+	}
+
+	for _, tt := range tests {
+		if got, err := translateC(tt.in); err != nil || got != tt.want {
+			t.Errorf("translateC(%q) = %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestTranslateA(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"@0", "0000000000000000"},
+		{"@1", "0000000000000001"},
+		{"@16384", "0100000000000000"},
+		{"@32767", "0111111111111111"},
+	}
+
+	for _, tt := range tests {
+		if got, err := translateA(tt.in); err != nil || got != tt.want {
+			t.Errorf("translateA(%q) = %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// Assembles the course's test programs and the project 4 programs, and compares
+// the output against the checked-in .hack files. Add, Max and Rect are the
+// binaries published with the course.
+// The .hack files are checked against specAssemble, encoding with the tables
+// of the book alone, so they don't merely record the output of Assemble
+func TestGolden(t *testing.T) {
+	tests := []string{
+		"testdata/Add.asm",
+		"testdata/Max.asm",
+		"testdata/Rect.asm",
+		"../../../project4/Mult.asm",
+		"../../../project4/Fill.asm",
+	}
+
+	for _, path := range tests {
+		name := strings.TrimSuffix(filepath.Base(path), ".asm")
+
+		t.Run(name, func(t *testing.T) {
+			asm, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", name+".hack"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if spec := strings.Join(specAssemble(t, string(asm)), "\n") + "\n"; spec != string(want) {
+				t.Fatalf("testdata/%s.hack differs from the book's encoding:\n%s", name, spec)
+			}
+
+			outLines, err := Assemble(strings.NewReader(string(asm)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := strings.Join(outLines, "\n") + "\n"
+			if got != string(want) {
+				t.Errorf("output differs from testdata/%s.hack:\n%s", name, got)
+			}
+		})
+	}
+}
+
+// Assembles a program with the tables of the book, sharing no code with the
+// assembler. Only the syntax of the course's programs is supported.
+func specAssemble(t *testing.T, asm string) []string {
+	t.Helper()
+
+	comps := map[string]string{
+		"0": "101010", "1": "111111", "-1": "111010", "D": "001100",
+		"A": "110000", "!D": "001101", "!A": "110001", "-D": "001111",
+		"-A": "110011", "D+1": "011111", "A+1": "110111", "D-1": "001110",
+		"A-1": "110010", "D+A": "000010", "D-A": "010011", "A-D": "000111",
+		"D&A": "000000", "D|A": "010101",
+	}
+	jumps := map[string]string{
+		"": "000", "JGT": "001", "JEQ": "010", "JGE": "011",
+		"JLT": "100", "JNE": "101", "JLE": "110", "JMP": "111",
+	}
+	symbols := map[string]int{"SP": 0, "LCL": 1, "ARG": 2, "THIS": 3, "THAT": 4, "SCREEN": 16384, "KBD": 24576}
+	for i := 0; i < 16; i++ {
+		symbols[fmt.Sprintf("R%d", i)] = i
+	}
+
+	// First pass: the labels
+	var lines []string
+	for _, line := range strings.Split(asm, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+		switch {
+		case line == "":
+		case line[0] == '(':
+			symbols[line[1:len(line)-1]] = len(lines)
+		default:
+			lines = append(lines, line)
+		}
+	}
+
+	var code []string
+	variable := 16
+	for _, line := range lines {
+		if line[0] == '@' {
+			v, err := strconv.Atoi(line[1:])
+			if err != nil {
+				adr, found := symbols[line[1:]]
+				if !found {
+					adr = variable
+					symbols[line[1:]] = adr
+					variable++
+				}
+				v = adr
+			}
+			code = append(code, fmt.Sprintf("%016b", v))
+			continue
+		}
+
+		dest, comp, jump := "", line, ""
+		if i := strings.Index(comp, "="); i >= 0 {
+			dest, comp = comp[:i], comp[i+1:]
+		}
+		if i := strings.Index(comp, ";"); i >= 0 {
+			comp, jump = comp[:i], comp[i+1:]
+		}
+		a := "0"
+		if strings.Contains(comp, "M") {
+			a, comp = "1", strings.ReplaceAll(comp, "M", "A")
+		}
+		// Commuted operands, e.g. "M+D" in Fill.asm, as written in the book
+		if len(comp) == 3 && comp[2] == 'D' && strings.ContainsRune("+&|", rune(comp[1])) {
+			comp = "D" + comp[1:2] + comp[:1]
+		}
+		c, found := comps[comp]
+		if !found {
+			t.Fatalf("computation %q not in the book", comp)
+		}
+		d := ""
+		for _, r := range "ADM" {
+			if strings.ContainsRune(dest, r) {
+				d += "1"
+			} else {
+				d += "0"
+			}
+		}
+		code = append(code, "111"+a+c+d+jumps[jump])
+	}
+
+	return code
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"@32768", `A instruction "@32768" out of range 0-32767`},
+		{"D=D*A", `invalid computation in C instruction "D=D*A"`},
+		{"X=D", `invalid destination in C instruction "X=D"`},
+		{"D;JMPS", `invalid jump in C instruction "D;JMPS"`},
+	}
+
+	for _, tt := range tests {
+		_, err := Assemble(strings.NewReader("@0\n" + tt.in + "\n"))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Assemble(%q) error = %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
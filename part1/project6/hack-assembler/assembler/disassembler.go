@@ -0,0 +1,57 @@
+package assembler
+
+// This file contains the disassembler, translating machine language back to
+// assembly.
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Computation mnemonics by their a bit and 6 computation bits
+var compMnemonics = map[string]string{
+	"0" + comp0: "0", "0" + comp1: "1", "0" + comp2: "-1",
+	"0" + comp3: "D", "0" + comp4: "A", "1" + comp4: "M",
+	"0" + comp5: "!D", "0" + comp6: "!A", "1" + comp6: "!M",
+	"0" + comp7: "-D", "0" + comp8: "-A", "1" + comp8: "-M",
+	"0" + comp9: "D+1", "0" + comp10: "A+1", "1" + comp10: "M+1",
+	"0" + comp11: "D-1", "0" + comp12: "A-1", "1" + comp12: "M-1",
+	"0" + comp13: "D+A", "1" + comp13: "D+M",
+	"0" + comp14: "D-A", "1" + comp14: "D-M",
+	"0" + comp15: "A-D", "1" + comp15: "M-D",
+	"0" + comp16: "D&A", "1" + comp16: "D&M",
+	"0" + comp17: "D|A", "1" + comp17: "D|M",
+}
+
+// Destination mnemonics by their 3 bits
+var destMnemonics = []string{"", "M", "D", "MD", "A", "AM", "AD", "AMD"}
+
+// Translates a machine language instruction, a string of 16 "0" and "1", to
+// assembly. A instructions are given their address, e.g. "@16".
+func Disassemble(code string) (string, error) {
+	word, err := strconv.ParseUint(code, 2, 16)
+	if err != nil || len(code) != 16 {
+		return "", fmt.Errorf("invalid instruction %q", code)
+	}
+
+	if code[:1] == opcodeA {
+		return fmt.Sprintf("%s%d", markAInstruction, word), nil
+	}
+
+	comp, found := compMnemonics[code[3:10]]
+	if !found {
+		return "", fmt.Errorf("instruction %q has no computation mnemonic", code)
+	}
+
+	out := comp
+	if dest := destMnemonics[word>>3&7]; dest != "" {
+		out = dest + markDestComp + out
+	}
+	for jump, bits := range jmpStore {
+		if bits == code[13:] {
+			out += markCompJmp + jump
+		}
+	}
+
+	return out, nil
+}
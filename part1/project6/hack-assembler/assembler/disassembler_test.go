@@ -0,0 +1,86 @@
+package assembler
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDisassemble(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"0000000000000000", "@0"},
+		{"0111111111111111", "@32767"},
+		{"1110101010000111", "0;JMP"},
+		{"1111110010011000", "MD=M-1"},
+		{"1110000010100000", "A=D+A"},
+		{"1111000010111101", "AMD=D+M;JNE"},
+		{"1110001100000001", "D;JGT"},
+	}
+
+	for _, tt := range tests {
+		if got, err := Disassemble(tt.in); err != nil || got != tt.want {
+			t.Errorf("Disassemble(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"1110111110000000", "111", "0000000000000002"} {
+		if _, err := Disassemble(in); err == nil {
+			t.Errorf("Disassemble(%q) returned no error", in)
+		}
+	}
+}
+
+// Every computation, and the golden programs, assemble back to themselves
+func TestDisassembleRoundTrip(t *testing.T) {
+	var programs []string
+	for _, name := range []string{"Add", "Max", "Rect", "Mult", "Fill"} {
+		src, err := os.ReadFile("testdata/" + name + ".hack")
+		if err != nil {
+			t.Fatal(err)
+		}
+		programs = append(programs, strings.Fields(string(src))...)
+	}
+	for bits := range compMnemonics {
+		programs = append(programs, "111"+bits+"010000")
+	}
+
+	for _, code := range programs {
+		in, err := Disassemble(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := Assemble(strings.NewReader(in))
+		if err != nil || out[0] != code {
+			t.Errorf("%s disassembled to %q, assembled to %v, %v", code, in, out, err)
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	_, labels, err := AssembleWithLabels(strings.NewReader("@i\n(LOOP)\n@LOOP\n0;JMP\n(END)\n(A.b$c)\n@R0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	if err := WriteSymbols(&buf, labels); err != nil {
+		t.Fatal(err)
+	}
+	want := "LOOP 1\nA.b$c 3\nEND 3\n"
+	if buf.String() != want {
+		t.Errorf("WriteSymbols() = %q, want %q", buf.String(), want)
+	}
+
+	read, err := ReadSymbols(strings.NewReader("// Labels\n" + buf.String() + "\n"))
+	if err != nil || len(read) != 3 || read["END"] != 3 {
+		t.Errorf("ReadSymbols() = %v, %v, want %v", read, err, labels)
+	}
+	for _, in := range []string{"LOOP\n", "LOOP x\n", "1A 2\n", "L -1\n"} {
+		if _, err := ReadSymbols(strings.NewReader(in)); err == nil {
+			t.Errorf("ReadSymbols(%q) returned no error", in)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package assembler
+
+// This file contains the parser and evaluator of A instruction operands:
+// numeric literals, symbols, and constant expressions over them.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Binary operators by precedence, lowest first, as in C
+var precedence = [][]string{
+	{"|"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+// A parsed operand: a number, a symbol, or an operator applied to two operands
+type expr struct {
+	op          string // "" for a number or a symbol
+	symbol      string
+	value       int
+	left, right *expr
+}
+
+// Returns whether s is a symbol: letters, digits, "_", ".", "$" and ":", not
+// starting with a digit
+func isSymbol(s string) bool {
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		return false
+	}
+
+	for _, c := range s {
+		if !isSymbolChar(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isSymbolChar(c rune) bool {
+	return c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("_.$:", c))
+}
+
+// Parses the operand of an A instruction, without the "@". Numbers are
+// decimal, hexadecimal ("0x4000"), binary ("0b1010") or characters ("'A'").
+// Operators are "+ - * / & | << >>", with parentheses. Errors don't quote the
+// instruction, for the caller to do so.
+func parseOperand(in string) (*expr, error) {
+	tokens, err := tokenize(in)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("missing operand")
+	}
+
+	p := &exprParser{tokens: tokens}
+	e, err := p.parse(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(tokens) {
+		return nil, fmt.Errorf("unexpected %q", tokens[p.pos])
+	}
+
+	return e, nil
+}
+
+// Splits an operand into numbers, symbols, operators and parentheses
+func tokenize(in string) ([]string, error) {
+	var tokens []string
+
+	for i := 0; i < len(in); {
+		c := rune(in[i])
+		start := i
+
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+			continue
+		case c == '\'':
+			if i+2 >= len(in) || in[i+2] != '\'' {
+				return nil, fmt.Errorf("invalid character literal")
+			}
+			i += 3
+		case strings.HasPrefix(in[i:], "<<") || strings.HasPrefix(in[i:], ">>"):
+			i += 2
+		case strings.ContainsRune("+-*/&|()", c):
+			i++
+		case isSymbolChar(c):
+			for i < len(in) && isSymbolChar(rune(in[i])) {
+				i++
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q", c)
+		}
+
+		tokens = append(tokens, in[start:i])
+	}
+
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+// Parses the operators of the given precedence level and above
+func (p *exprParser) parse(level int) (*expr, error) {
+	if level == len(precedence) {
+		return p.parseTerm()
+	}
+
+	left, err := p.parse(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.pos < len(p.tokens) && isOperator(p.tokens[p.pos], level) {
+		op := p.tokens[p.pos]
+		p.pos++
+
+		right, err := p.parse(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &expr{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func isOperator(token string, level int) bool {
+	for _, op := range precedence[level] {
+		if token == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parses a number, a symbol, or an expression in parentheses
+func (p *exprParser) parseTerm() (*expr, error) {
+	if p.pos == len(p.tokens) {
+		return nil, fmt.Errorf("missing operand")
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch {
+	case token == "(":
+		e, err := p.parse(0)
+		if err != nil {
+			return nil, err
+		}
+		if p.pos == len(p.tokens) || p.tokens[p.pos] != ")" {
+			return nil, fmt.Errorf("missing \")\"")
+		}
+		p.pos++
+		return e, nil
+	case strings.HasPrefix(token, "'"):
+		return &expr{value: int(token[1])}, nil
+	case isSymbol(token):
+		return &expr{symbol: token}, nil
+	}
+
+	value, err := parseNumber(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &expr{value: value}, nil
+}
+
+// Parses a decimal, hexadecimal ("0x") or binary ("0b") number
+func parseNumber(token string) (int, error) {
+	base, digits := 10, token
+	switch {
+	case strings.HasPrefix(token, "0x"), strings.HasPrefix(token, "0X"):
+		base, digits = 16, token[2:]
+	case strings.HasPrefix(token, "0b"), strings.HasPrefix(token, "0B"):
+		base, digits = 2, token[2:]
+	}
+
+	n, err := strconv.ParseInt(digits, base, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", token)
+	}
+
+	return int(n), nil
+}
+
+// Evaluates the operand, looking up the address of its symbols
+func (e *expr) eval(lookup func(symbol string) (int, error)) (int, error) {
+	if e.op == "" {
+		if e.symbol != "" {
+			return lookup(e.symbol)
+		}
+		return e.value, nil
+	}
+
+	x, err := e.left.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	y, err := e.right.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+
+	switch e.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return x / y, nil
+	case "&":
+		return x & y, nil
+	case "|":
+		return x | y, nil
+	case "<<", ">>":
+		if y < 0 || y > 15 {
+			return 0, fmt.Errorf("shift by %d, out of range 0-15", y)
+		}
+		if e.op == "<<" {
+			return x << y, nil
+		}
+		return x >> y, nil
+	}
+
+	return 0, fmt.Errorf("unknown operator %q", e.op)
+}
@@ -0,0 +1,99 @@
+package assembler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssembleOperands(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"@0x4000", 16384},
+		{"@0X7fff", 32767},
+		{"@0b1010", 10},
+		{"@'A'", 65},
+		{"@' '", 32},
+		{"@'/'", 47},
+		{"@SCREEN+32", 16416},
+		{"@SCREEN + 32*2", 16448},
+		{"@(SCREEN+32)*0", 0},
+		{"@KBD-SCREEN", 8192},
+		{"@SCREEN/2-1", 8191},
+		{"@1<<14|5", 16389},
+		{"@0xFF&0x0F", 15},
+		{"@SCREEN>>1+1", 4096}, // Shifts bind looser than "+", as in C
+		{"@'a'-'A'", 32},
+		{"@END+1", 3}, // Labels declared later
+		{"@END-1 // comment", 1},
+	}
+
+	for _, tt := range tests {
+		out, err := Assemble(strings.NewReader(tt.in + "\n@x\n(END)\n@END\n0;JMP\n"))
+		if err != nil {
+			t.Errorf("Assemble(%q) error = %s", tt.in, err)
+			continue
+		}
+		if want, _ := encodeA(tt.in, tt.want); out[0] != want {
+			t.Errorf("Assemble(%q) = %s, want %s (%d)", tt.in, out[0], want, tt.want)
+		}
+	}
+}
+
+func TestAssembleOperandErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"@SCREEN*2", `A instruction "@SCREEN*2" out of range 0-32767`},
+		{"@1-2", `A instruction "@1-2" out of range 0-32767`},
+		{"@0x8000", `A instruction "@0x8000" out of range 0-32767`},
+		{"@1/0", `invalid A instruction "@1/0": division by zero`},
+		{"@1<<16", `invalid A instruction "@1<<16": shift by 16, out of range 0-15`},
+		{"@(1+2", `invalid A instruction "@(1+2": missing ")"`},
+		{"@1+", `invalid A instruction "@1+": missing operand`},
+		{"@1 2", `invalid A instruction "@1 2": unexpected "2"`},
+		{"@0xZ", `invalid A instruction "@0xZ": invalid number "0xZ"`},
+		{"@'AB'", `invalid A instruction "@'AB'": invalid character literal`},
+		{"@1%2", `invalid A instruction "@1%2": unexpected '%'`},
+		{"@", `invalid A instruction "@": missing operand`},
+		{"@LOOP+1", `invalid A instruction "@LOOP+1": undefined symbol "LOOP" in expression`},
+		{"@x\n@R15+x", `invalid A instruction "@R15+x": undefined symbol "x" in expression`}, // A variable
+	}
+
+	for _, tt := range tests {
+		_, err := Assemble(strings.NewReader(tt.in + "\n"))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Assemble(%q) error = %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestLinkOperands(t *testing.T) {
+	a := assembleObject(t, "a.asm", "@TABLE+1\nD=M\n@.x\nM=D\n@SCREEN+32\nM=1\n")
+	b := assembleObject(t, "b.asm", "(TABLE)\n@0\n@.x\n")
+
+	// Only the symbols and the expressions over them are left to the linker
+	if len(a.Refs) != 2 {
+		t.Errorf("object refers to %d symbols, want 2", len(a.Refs))
+	}
+
+	got, err := Link([]*Object{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{7, 16, 16416} // TABLE is 6, the variable 16
+	for i, adr := range want {
+		if code, _ := encodeA("", adr); got[2*i] != code {
+			t.Errorf("A instruction %d = %s, want %s (%d)", i, got[2*i], code, adr)
+		}
+	}
+
+	c := assembleObject(t, "c.asm", "@MISSING+2\n0;JMP\n@1<<TABLE\n@.y+1\nD=M\n")
+	wantErr := "c.asm: MISSING: undefined label\nc.asm: .y: undefined symbol in expression"
+	if _, err := Link([]*Object{c, b}); err == nil || err.Error() != wantErr {
+		t.Errorf("Link() error = %v, want %q", err, wantErr)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package assembler
 
 // This file contains helper functions that aid the other assembler components.
 
@@ -7,9 +7,10 @@ import (
 	"strings"
 )
 
+// Removes the comment ending the line. A single "/" is the division of
+// A instruction expressions.
 func removeInlineComment(in string) string {
-	for strings.ContainsAny(in, markComment) {
-		i := strings.IndexAny(in, markComment)
+	if i := strings.Index(in, markComment); i != -1 {
 		in = in[:i]
 	}
 
@@ -0,0 +1,146 @@
+package assembler
+
+// This file contains the linker, joining objects into a single program.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A symbol that can't be linked
+type SymbolError struct {
+	File   string // The object referring to, or declaring, the symbol
+	Symbol string
+	Msg    string
+}
+
+func (e *SymbolError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.File, e.Symbol, e.Msg)
+}
+
+// Every symbol that can't be linked, in link order
+type LinkErrors []*SymbolError
+
+func (e LinkErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Links objs, in order, into a single program: the code of each object follows
+// the code of the previous one, so the first one starts at address 0. Returns
+// its machine language instructions, as Assemble does.
+//
+// A symbol is resolved to a builtin symbol, to a local label of its object,
+// then to a label exported by any object. Expressions are evaluated once their
+// symbols are resolved. Symbols that are neither get a variable address from
+// 16, in order of first reference; the variables starting with "." belong to
+// their object, the others are shared by every object. A jump to a symbol
+// that isn't a label, an expression over such a symbol, or a label exported
+// twice, are returned as LinkErrors.
+func Link(objs []*Object) ([]string, error) {
+	var errs LinkErrors
+
+	// Where each object starts, and the global labels
+	bases := make([]int, len(objs))
+	labels := map[string]int{}
+	owners := map[string]string{}
+	size := 0
+	for i, obj := range objs {
+		bases[i] = size
+		size += len(obj.Code)
+
+		for _, label := range sortedKeys(obj.Labels) {
+			if owner, found := owners[label]; found {
+				errs = append(errs, &SymbolError{obj.File, label, fmt.Sprintf("label already declared in %s", owner)})
+				continue
+			}
+			labels[label] = bases[i] + obj.Labels[label]
+			owners[label] = obj.File
+		}
+	}
+	if size > maxAddress+1 {
+		return nil, fmt.Errorf("%d instructions don't fit in the %d words of ROM32K", size, maxAddress+1)
+	}
+
+	variables := map[string]int{}
+	next := 16 // Variable memory starts at 16
+
+	var code []string
+	for i, obj := range objs {
+		out := append([]string(nil), obj.Code...)
+
+		for _, ref := range obj.Refs {
+			in := markAInstruction + ref.Symbol
+			e, err := parseOperand(ref.Symbol)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid A instruction %q: %s", obj.File, in, err)
+			}
+
+			adr, err := e.eval(func(symbol string) (int, error) {
+				if adr, found := builtinAddress(symbol); found {
+					return adr, nil
+				}
+				if local, found := obj.Locals[symbol]; found {
+					return bases[i] + local, nil
+				}
+				if global, found := labels[symbol]; found {
+					return global, nil
+				}
+				if ref.Jump {
+					return 0, &SymbolError{obj.File, symbol, "undefined label"}
+				}
+				if !isSymbol(ref.Symbol) {
+					return 0, &SymbolError{obj.File, symbol, "undefined symbol in expression"}
+				}
+
+				// Local variables are keyed by object
+				key := symbol
+				if isLocal(key) {
+					key = fmt.Sprintf("%d%s", i, key)
+				}
+				v, found := variables[key]
+				if !found {
+					v = next
+					variables[key] = v
+					next++
+				}
+				return v, nil
+			})
+			if symErr, ok := err.(*SymbolError); ok {
+				errs = append(errs, symErr)
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("%s: invalid A instruction %q: %s", obj.File, in, err)
+			}
+
+			a, err := encodeA(in, adr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", obj.File, err)
+			}
+			out[ref.At] = a
+		}
+
+		code = append(code, out...)
+	}
+
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	return code, nil
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,204 @@
+package assembler
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func assembleObject(t *testing.T, file string, src string) *Object {
+	t.Helper()
+
+	obj, err := AssembleObject(strings.NewReader(src), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return obj
+}
+
+func openObject(t *testing.T, path string) *Object {
+	t.Helper()
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return assembleObject(t, filepath.Base(path), string(src))
+}
+
+// A program linked on its own is the program assembled directly
+func TestLinkSingleObject(t *testing.T) {
+	tests := []string{
+		"testdata/Add.asm",
+		"testdata/Max.asm",
+		"testdata/Rect.asm",
+		"../../../project4/Mult.asm",
+		"../../../project4/Fill.asm",
+	}
+
+	for _, path := range tests {
+		name := strings.TrimSuffix(filepath.Base(path), ".asm")
+
+		t.Run(name, func(t *testing.T) {
+			got, err := Link([]*Object{openObject(t, path)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := os.ReadFile(filepath.Join("testdata", name+".hack"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Join(got, "\n")+"\n" != string(want) {
+				t.Errorf("linked output differs from testdata/%s.hack", name)
+			}
+		})
+	}
+}
+
+// Main.asm calls MULT of Mult.asm. Both declare a local label ".END".
+func TestLinkLibrary(t *testing.T) {
+	objs := []*Object{openObject(t, "testdata/link/Main.asm"), openObject(t, "testdata/link/Mult.asm")}
+
+	got, err := Link(objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[int]string{
+		0:  "0000000000000110", // @.RET of Main.asm
+		2:  "0000000000010000", // @ret, the first variable
+		4:  "0000000000001000", // @MULT, after the 8 instructions of Main.asm
+		6:  "0000000000000110", // @.END of Main.asm
+		12: "0000000000010001", // @.i of Mult.asm, the second variable
+		16: "0000000000011010", // @.END of Mult.asm
+		24: "0000000000001110", // @.LOOP of Mult.asm
+		26: "0000000000010000", // @ret
+	}
+	if len(got) != 29 {
+		t.Fatalf("linked %d instructions, want 29", len(got))
+	}
+	for i, code := range want {
+		if got[i] != code {
+			t.Errorf("instruction %d = %s, want %s", i, got[i], code)
+		}
+	}
+}
+
+func TestLinkLocalVariables(t *testing.T) {
+	a := assembleObject(t, "a.asm", "@.x\nM=1\n@shared\nM=1\n")
+	b := assembleObject(t, "b.asm", "@shared\nM=1\n@.x\nM=1\n")
+
+	got, err := Link([]*Object{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"0000000000010000", "0000000000010001", "0000000000010001", "0000000000010010"}
+	for i, code := range want {
+		if got[2*i] != code {
+			t.Errorf("A instruction %d = %s, want %s", i, got[2*i], code)
+		}
+	}
+}
+
+func TestLinkErrors(t *testing.T) {
+	a := assembleObject(t, "a.asm", "(F)\n@G\n0;JMP\n@.H\nD;JGT\n@LOOP\nD=D-1\nD;JGT\n")
+	b := assembleObject(t, "b.asm", "(F)\n(.H)\n@F\n0;JMP\n")
+
+	_, err := Link([]*Object{a, b})
+	errs, ok := err.(LinkErrors)
+	if !ok {
+		t.Fatalf("Link() error = %v, want LinkErrors", err)
+	}
+
+	want := []string{
+		"b.asm: F: label already declared in a.asm",
+		"a.asm: G: undefined label",
+		"a.asm: .H: undefined label",
+		"a.asm: LOOP: undefined label",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Link() errors =\n%s\nwant %d errors", errs, len(want))
+	}
+	for i, e := range errs {
+		if e.Error() != want[i] {
+			t.Errorf("error %d = %q, want %q", i, e.Error(), want[i])
+		}
+	}
+}
+
+// Undefined symbols are variables, whatever their case, unless jumped to
+func TestLinkVariables(t *testing.T) {
+	// The statics of translated VM code, the address of a pointer, and a
+	// jump behind a label, A coming from elsewhere
+	a := assembleObject(t, "a.asm", "@Main.0\nM=D\n@ret\nA=M\n0;JMP\n@x\n(.IN)\nD;JGT\n@Main.0\nD=M\n")
+
+	for _, ref := range a.Refs {
+		if ref.Jump {
+			t.Errorf("reference to %q at %d taken for a jump", ref.Symbol, ref.At)
+		}
+	}
+
+	got, err := Link([]*Object{a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]int{0: 16, 2: 17, 5: 18, 7: 16}
+	for at, adr := range want {
+		if code, _ := encodeA("", adr); got[at] != code {
+			t.Errorf("instruction %d = %s, want %s (%d)", at, got[at], code, adr)
+		}
+	}
+}
+
+func TestAssembleObjectErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"(L)\n(L)\n", `x.asm: label "L" declared twice`},
+		{"@32768", `x.asm: A instruction "@32768" out of range 0-32767`},
+		{"D=D*A", `x.asm: invalid computation in C instruction "D=D*A"`},
+	}
+
+	for _, tt := range tests {
+		_, err := AssembleObject(strings.NewReader(tt.in), "x.asm")
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("AssembleObject(%q) error = %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestObjectReadWrite(t *testing.T) {
+	obj := openObject(t, "testdata/link/Mult.asm")
+
+	var buf bytes.Buffer
+	if err := obj.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadObject(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := Link([]*Object{obj})
+	got, err := Link([]*Object{read})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Error("object read back links differently")
+	}
+
+	for _, in := range []string{`{"format": "hobj/0"}`, `{"format": "hobj/1", "refs": [{"at": 3}]}`, "@0"} {
+		if _, err := ReadObject(strings.NewReader(in)); err == nil {
+			t.Errorf("ReadObject(%q) returned no error", in)
+		}
+	}
+}
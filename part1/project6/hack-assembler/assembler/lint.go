@@ -0,0 +1,182 @@
+package assembler
+
+// This file contains the linter, reporting valid instructions that likely
+// don't do what was meant.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A lint rule, which can be disabled by name
+type LintRule struct {
+	Name string
+	Doc  string
+}
+
+// Every lint rule
+var LintRules = []LintRule{
+	{"a-and-m", "A written from M along with D, then M used, e.g. \"AD=M+1 M=D\": the two M are different words"},
+	{"jump-a", "A written by a jump, e.g. \"A=A+1;JMP\": the target is ambiguous"},
+	{"m-after-a", "M used right after arithmetic on A, e.g. \"@SCREEN A=D+A M=-1\": M isn't the word of the symbol"},
+	{"no-halt", "The program doesn't end with an \"(END) @END 0;JMP\" loop, jumping to itself"},
+	{"unreachable", "Code after an unconditional jump, with no label to jump to it"},
+}
+
+// A hazard found by the linter
+type Hazard struct {
+	Line int // Line of the instruction in the assembly file
+	Rule string
+	Msg  string
+}
+
+func (h Hazard) String() string {
+	return fmt.Sprintf("line %d: %s (%s)", h.Line, h.Msg, h.Rule)
+}
+
+// An instruction, with its position
+type lintInstruction struct {
+	line    int
+	text    string
+	c       asmInC // Empty for A instructions
+	isA     bool
+	labeled bool     // Follows a label, so can be jumped to
+	labels  []string // The labels declared right before it
+}
+
+func (in lintInstruction) writes(reg string) bool {
+	return !in.isA && strings.Contains(in.c.dest, reg)
+}
+
+func (in lintInstruction) reads(reg string) bool {
+	return !in.isA && strings.Contains(in.c.comp, reg)
+}
+
+// Lints the Hack assembly read from r, skipping the rules named in disabled.
+// Returns an error for an unknown rule, or an invalid instruction.
+func Lint(r io.Reader, disabled []string) ([]Hazard, error) {
+	enabled := map[string]bool{}
+	for _, rule := range LintRules {
+		enabled[rule.Name] = true
+	}
+	for _, name := range disabled {
+		if !enabled[name] {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+		enabled[name] = false
+	}
+
+	ins, err := readLintInput(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var hazards []Hazard
+	report := func(rule string, in lintInstruction, format string, a ...interface{}) {
+		if enabled[rule] {
+			hazards = append(hazards, Hazard{in.line, rule, fmt.Sprintf(format, a...)})
+		}
+	}
+
+	for i, in := range ins {
+		if in.writes("A") && in.reads("M") && !in.writes("M") && in.c.dest != "A" {
+			if next, found := nextUseOfM(ins, i); found {
+				report("a-and-m", in, "%q reads M at the address A held before, but %q at line %d uses M at the new A", in.text, next.text, next.line)
+			}
+		}
+		if in.writes("A") && in.c.jump != "" {
+			report("jump-a", in, "%q writes A and jumps: the target is ambiguous", in.text)
+		}
+
+		if i >= 2 && !in.labeled && !ins[i-1].labeled && (in.reads("M") || in.writes("M")) {
+			load, arith := ins[i-2], ins[i-1]
+			if load.isA && arith.writes("A") && arith.reads("A") {
+				report("m-after-a", in, "%q uses M at the address computed by %q, not at %q", in.text, arith.text, load.text)
+			}
+		}
+
+		if i >= 1 && !in.labeled && isUnconditional(ins[i-1]) {
+			report("unreachable", in, "%q is unreachable, after the jump at line %d", in.text, ins[i-1].line)
+		}
+	}
+
+	if n := len(ins); n != 0 && !isUnconditional(ins[n-1]) {
+		report("no-halt", ins[n-1], "the program falls off its end after %q, without an \"(END) @END 0;JMP\" loop", ins[n-1].text)
+	} else if n != 0 && !isHaltLoop(ins[:n]) {
+		report("no-halt", ins[n-1], "the program ends with the jump %q, not an \"(END) @END 0;JMP\" loop", ins[n-1].text)
+	}
+
+	return hazards, nil
+}
+
+// Returns the first instruction after ins[i] using M at the address ins[i]
+// leaves in A: within its block, before a label, an A instruction, a jump or
+// A written again
+func nextUseOfM(ins []lintInstruction, i int) (lintInstruction, bool) {
+	for _, in := range ins[i+1:] {
+		if in.labeled || in.isA {
+			break
+		}
+		if in.reads("M") || in.writes("M") {
+			return in, true
+		}
+		if in.writes("A") || in.c.jump != "" {
+			break
+		}
+	}
+
+	return lintInstruction{}, false
+}
+
+func isUnconditional(in lintInstruction) bool {
+	return !in.isA && in.c.jump == "JMP"
+}
+
+// Returns whether ins end with a loop jumping to itself: an A instruction
+// loading its own label, then an unconditional jump keeping A
+func isHaltLoop(ins []lintInstruction) bool {
+	n := len(ins)
+	if n < 2 || !ins[n-2].isA || ins[n-1].labeled || ins[n-1].writes("A") || !isUnconditional(ins[n-1]) {
+		return false
+	}
+
+	for _, label := range ins[n-2].labels {
+		if ins[n-2].text == markAInstruction+label {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reads the instructions of r with their line numbers, checking C
+// instructions
+func readLintInput(r io.Reader) ([]lintInstruction, error) {
+	var ins []lintInstruction
+	var labels []string
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(removeInlineComment(scanner.Text()))
+
+		switch {
+		case len(line) == 0:
+			continue
+		case strings.HasPrefix(line, "(") && strings.HasSuffix(line, ")"):
+			labels = append(labels, line[1:len(line)-1])
+			continue
+		case strings.HasPrefix(line, markAInstruction):
+			ins = append(ins, lintInstruction{line: n, text: line, isA: true, labeled: len(labels) != 0, labels: labels})
+		default:
+			if _, err := translateC(line); err != nil {
+				return nil, fmt.Errorf("line %d: %s", n, err)
+			}
+			ins = append(ins, lintInstruction{line: n, text: line, c: parseC(line), labeled: len(labels) != 0, labels: labels})
+		}
+		labels = nil
+	}
+
+	return ins, scanner.Err()
+}
@@ -0,0 +1,99 @@
+package assembler
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"a-and-m", "@R0\nAD=M+1\nAM=M+1\nA=M\n(END)\n@END\n0;JMP\n",
+			[]string{`line 2: "AD=M+1" reads M at the address A held before, but "AM=M+1" at line 3 uses M at the new A (a-and-m)`}},
+		{"a-and-m without M", "@R0\nAD=M+1\nD=D+A\n@R1\nM=D\n@R0\nAD=M\nA=A+1\nM=0\n(END)\n@END\n0;JMP\n", nil},
+		{"jump-a", "@R0\nA=A+1;JMP\n(END)\n@END\n0;JMP\n",
+			[]string{`line 2: "A=A+1;JMP" writes A and jumps: the target is ambiguous (jump-a)`}},
+		{"m-after-a", "@SCREEN\nA=D+A\nM=-1\n@SP\nA=M-1\nM=D\n(END)\n@END\n0;JMP\n",
+			[]string{`line 3: "M=-1" uses M at the address computed by "A=D+A", not at "@SCREEN" (m-after-a)`}},
+		{"no-halt", "@R0\nD=M\n",
+			[]string{`line 2: the program falls off its end after "D=M", without an "(END) @END 0;JMP" loop (no-halt)`}},
+		{"no-halt after a loop", "(LOOP)\n@R0\nM=M+1\n@LOOP\n0;JMP\n",
+			[]string{`line 5: the program ends with the jump "0;JMP", not an "(END) @END 0;JMP" loop (no-halt)`}},
+		{"no-halt to another label", "(END)\n@R0\n@END\n0;JMP\n",
+			[]string{`line 4: the program ends with the jump "0;JMP", not an "(END) @END 0;JMP" loop (no-halt)`}},
+		{"no-halt writing A", "(END)\n@END\nA=0;JMP\n",
+			[]string{`line 3: "A=0;JMP" writes A and jumps: the target is ambiguous (jump-a)`,
+				`line 3: the program ends with the jump "A=0;JMP", not an "(END) @END 0;JMP" loop (no-halt)`}},
+		{"unreachable", "@L\n0;JMP\n@R0\nM=0\n(L)\n@L\n0;JMP\n",
+			[]string{`line 3: "@R0" is unreachable, after the jump at line 2 (unreachable)`}},
+		{"labels make code reachable", "(L)\n@L\n0;JMP\n// comment\n(M)\n\n@M\n0;JMP\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hazards, err := Lint(strings.NewReader(tt.in), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(hazards) != len(tt.want) {
+				t.Fatalf("Lint() = %v, want %q", hazards, tt.want)
+			}
+			for i, h := range hazards {
+				if h.String() != tt.want[i] {
+					t.Errorf("hazard %d = %q, want %q", i, h, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLintDisable(t *testing.T) {
+	in := "@R0\nA=A+1;JMP\n@R1\n"
+
+	hazards, err := Lint(strings.NewReader(in), []string{"jump-a", "unreachable"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hazards) != 1 || hazards[0].Rule != "no-halt" {
+		t.Errorf("Lint() = %v, want only no-halt", hazards)
+	}
+
+	if _, err := Lint(strings.NewReader(in), []string{"jump"}); err == nil || err.Error() != `unknown lint rule "jump"` {
+		t.Errorf("Lint() error = %v, want the unknown rule", err)
+	}
+	if _, err := Lint(strings.NewReader("D=D*A\n"), nil); err == nil || err.Error() != `line 1: invalid computation in C instruction "D=D*A"` {
+		t.Errorf("Lint() error = %v, want the invalid instruction", err)
+	}
+}
+
+// The project 4 programs are free of hazards. Fill never halts: it polls the
+// keyboard forever.
+func TestLintPrograms(t *testing.T) {
+	tests := []struct {
+		path     string
+		disabled []string
+	}{
+		{"../../../project4/Mult.asm", nil},
+		{"../../../project4/Fill.asm", []string{"no-halt"}},
+		{"testdata/Max.asm", nil},
+		{"testdata/Rect.asm", nil},
+	}
+
+	for _, tt := range tests {
+		inFile, err := os.Open(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hazards, err := Lint(inFile, tt.disabled)
+		inFile.Close()
+		if err != nil || len(hazards) != 0 {
+			t.Errorf("Lint(%s) = %v, %v, want no hazard", tt.path, hazards, err)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package assembler
 
 // This file contains the data models
 
@@ -81,7 +81,8 @@ func (store dataStore) populateCompPatterns() {
 
 type symbolStore struct {
 	store dataStore
-	used  int // Keeps tracks of the number of memory assigned to variables
+	used  int             // Keeps tracks of the number of memory assigned to variables
+	vars  map[string]bool // The symbols assigned memory, as opposed to labels
 }
 
 // Populate the data store with builtin variables (e.g. R0, SCREEN)
@@ -0,0 +1,189 @@
+package assembler
+
+// This file contains the relocatable object format (.hobj): a program
+// assembled on its own, with its symbols left for the linker to resolve.
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const objectFormat = "hobj/1"
+
+// An assembled file whose symbolic A instructions are left unresolved.
+// Symbols starting with "." are local to the file, labels and variables alike;
+// the other labels are exported to the other objects linked with it. Built-in
+// symbols (R0-R15, SP, SCREEN...) are resolved when assembling.
+type Object struct {
+	Format string         `json:"format"`
+	File   string         `json:"file"`   // The assembly file, e.g. "Mult.asm"
+	Code   []string       `json:"code"`   // Instructions, "0" for unresolved A instructions
+	Labels map[string]int `json:"labels"` // Exported labels, by instruction number
+	Locals map[string]int `json:"locals"` // Local labels, by instruction number
+	Refs   []Ref          `json:"refs"`
+}
+
+// An A instruction referring to a symbol, patched by the linker
+type Ref struct {
+	At     int    `json:"at"`     // Instruction number
+	Symbol string `json:"symbol"` // The operand: a symbol, or an expression
+	// A jump follows while A still holds the address: the symbol has to be a
+	// label, as do the symbols of an expression. Other symbols not declared as
+	// labels are variables.
+	Jump bool `json:"jump,omitempty"`
+}
+
+// Returns whether symbol is local to its file
+func isLocal(symbol string) bool {
+	return strings.HasPrefix(symbol, ".")
+}
+
+// Assembles the Hack assembly read from r into a relocatable object. file
+// names the object in link errors.
+func AssembleObject(r io.Reader, file string) (*Object, error) {
+	obj := &Object{Format: objectFormat, File: file, Labels: map[string]int{}, Locals: map[string]int{}, Refs: []Ref{}}
+
+	var inLines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(removeInlineComment(scanner.Text()))
+		if len(line) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(line, "(") && strings.HasSuffix(line, ")") {
+			label := line[1 : len(line)-1]
+			labels := obj.Labels
+			if isLocal(label) {
+				labels = obj.Locals
+			}
+			if _, found := labels[label]; found {
+				return nil, fmt.Errorf("%s: label %q declared twice", file, label)
+			}
+			labels[label] = len(inLines)
+			continue
+		}
+
+		inLines = append(inLines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Where other code can jump in, with another address in A
+	targets := map[int]bool{}
+	for _, labels := range []map[string]int{obj.Labels, obj.Locals} {
+		for _, at := range labels {
+			targets[at] = true
+		}
+	}
+
+	for i, in := range inLines {
+		var code string
+		var err error
+
+		if !strings.HasPrefix(in, markAInstruction) {
+			code, err = translateC(in)
+		} else if code, err = translateObjectA(in); code == "" && err == nil {
+			code = fmt.Sprintf("%016d", 0)
+			obj.Refs = append(obj.Refs, Ref{At: i, Symbol: in[1:], Jump: jumpsTo(inLines, i, targets)})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err)
+		}
+
+		obj.Code = append(obj.Code, code)
+	}
+
+	return obj, nil
+}
+
+// Returns whether the address loaded by the A instruction at i is jumped to:
+// a jump follows before A is written again, or a label lets other code in
+func jumpsTo(inLines []string, i int, targets map[int]bool) bool {
+	for j := i + 1; j < len(inLines) && !targets[j]; j++ {
+		if strings.HasPrefix(inLines[j], markAInstruction) {
+			return false
+		}
+
+		c := parseC(inLines[j])
+		if len(c.jump) != 0 {
+			return true
+		}
+		if strings.Contains(c.dest, "A") {
+			return false
+		}
+	}
+
+	return false
+}
+
+// Translates an A instruction of an object. Returns an empty string if it
+// refers to symbols other than the builtin ones, left to the linker.
+func translateObjectA(in string) (string, error) {
+	if _, err := strconv.Atoi(in[1:]); err == nil {
+		return translateA(in)
+	}
+
+	e, err := parseOperand(in[1:])
+	if err != nil {
+		return "", fmt.Errorf("invalid A instruction %q: %s", in, err)
+	}
+
+	resolved := true
+	adr, err := e.eval(func(symbol string) (int, error) {
+		adr, found := builtinAddress(symbol)
+		resolved = resolved && found
+		return adr, nil
+	})
+	switch {
+	case !resolved:
+		return "", nil
+	case err != nil:
+		return "", fmt.Errorf("invalid A instruction %q: %s", in, err)
+	}
+
+	return encodeA(in, adr)
+}
+
+// Returns the address of a builtin symbol
+func builtinAddress(symbol string) (int, bool) {
+	v, found := builtins.store[symbol]
+	if !found {
+		return 0, false
+	}
+	adr, _ := strconv.Atoi(v)
+
+	return adr, true
+}
+
+// Writes the object as JSON
+func (obj *Object) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(obj)
+}
+
+// Reads an object written by Write
+func ReadObject(r io.Reader) (*Object, error) {
+	var obj Object
+	if err := json.NewDecoder(r).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("invalid object: %s", err)
+	}
+	if obj.Format != objectFormat {
+		return nil, fmt.Errorf("invalid object: format %q, want %q", obj.Format, objectFormat)
+	}
+
+	for _, ref := range obj.Refs {
+		if ref.At < 0 || ref.At >= len(obj.Code) {
+			return nil, fmt.Errorf("invalid object: reference to %q out of the code", ref.Symbol)
+		}
+	}
+
+	return &obj, nil
+}
@@ -1,4 +1,4 @@
-package main
+package assembler
 
 // This file contains the C instruction parser logic.
 
@@ -0,0 +1,25 @@
+package assembler
+
+import "testing"
+
+func TestParseC(t *testing.T) {
+	tests := []struct {
+		in   string
+		want asmInC
+	}{
+		{"D=A", asmInC{dest: "D", comp: "A"}},
+		{"M=D+M", asmInC{dest: "M", comp: "D+M"}},
+		{"AMD=M-1", asmInC{dest: "AMD", comp: "M-1"}},
+		{"D;JGT", asmInC{comp: "D", jump: "JGT"}},
+		{"0;JMP", asmInC{comp: "0", jump: "JMP"}},
+		{"D=D-A;JNE", asmInC{dest: "D", comp: "D-A", jump: "JNE"}},
+		{"md=m+1", asmInC{dest: "MD", comp: "M+1"}},
+		{"!D", asmInC{comp: "!D"}},
+	}
+
+	for _, tt := range tests {
+		if got := parseC(tt.in); got != tt.want {
+			t.Errorf("parseC(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package assembler
+
+// This file contains symbol processing logic.
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Calculates the address that a jump label points to, then adds the label and
+// its address to the variable data store.
+func processLabel(buf *[]string, in string) {
+	labelName := in[1 : len(in)-1] // Unwrap the parenthesises
+
+	_, found := varStore.store[labelName]
+	if !found {
+		// The label itself is not added to the data store. If current
+		// instruction count is n, then the label should points to the nth
+		// instruction. (Array index starts with 0)
+		adr := strconv.Itoa(len(*buf))
+		// Adds label and corresponding address to data store
+		varStore.store[labelName] = adr
+	}
+}
+
+// Takes an A instruction and returns it with its address resolved. The
+// operand can be a number, a symbol, or a constant expression over them, see
+// parseOperand.
+// Example
+// in = @R1
+// Out = @1
+// in = @SCREEN+32
+// Out = @16416
+func resolveSymbol(in string) (string, error) {
+	symbol := in[1:] // Removes the "@"
+
+	// Check if symbol is pure number, if it is, it is a memory location and not
+	// a symbol, returns it as is.
+	if _, err := strconv.Atoi(symbol); err == nil {
+		return in, nil
+	}
+
+	if isSymbol(symbol) {
+		return fmt.Sprintf("@%d", symbolAddress(symbol)), nil
+	}
+
+	e, err := parseOperand(symbol)
+	if err != nil {
+		return "", fmt.Errorf("invalid A instruction %q: %s", in, err)
+	}
+	// Labels are all known by now. Unlike a lone symbol, a symbol of an
+	// expression has to be a label or a builtin symbol: a variable's address
+	// isn't a constant of the program.
+	adr, err := e.eval(func(symbol string) (int, error) {
+		v, found := varStore.store[symbol]
+		if !found || varStore.vars[symbol] {
+			return 0, fmt.Errorf("undefined symbol %q in expression", symbol)
+		}
+		adr, _ := strconv.Atoi(v)
+
+		return adr, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid A instruction %q: %s", in, err)
+	}
+	if adr < 0 || adr > maxAddress {
+		return "", fmt.Errorf("A instruction %q out of range 0-%d", in, maxAddress)
+	}
+
+	return fmt.Sprintf("@%d", adr), nil
+}
+
+// Returns the address of symbol, assigning it the next free address if it
+// isn't known yet
+func symbolAddress(symbol string) int {
+	v, found := varStore.store[symbol]
+	if !found {
+		// Assign an address to this symbol
+		adr := varStore.malloc()
+		// Adds the symbol and its address to the data store
+		varStore.store[symbol] = strconv.Itoa(adr)
+		varStore.vars[symbol] = true
+
+		return adr
+	}
+
+	adr, _ := strconv.Atoi(v)
+
+	return adr
+}
@@ -0,0 +1,53 @@
+package assembler
+
+import "testing"
+
+func TestResolveSymbol(t *testing.T) {
+	resetSymbols()
+
+	var buf []string
+	buf = append(buf, "@i", "M=0")
+	processLabel(&buf, "(LOOP)")
+
+	// Resolved in order, so variables get consecutive addresses
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"@42", "@42"},
+		{"@0", "@0"},
+		{"@R0", "@0"},
+		{"@R15", "@15"},
+		{"@SP", "@0"},
+		{"@LCL", "@1"},
+		{"@ARG", "@2"},
+		{"@THIS", "@3"},
+		{"@THAT", "@4"},
+		{"@SCREEN", "@16384"},
+		{"@KBD", "@24576"},
+		{"@LOOP", "@2"},
+		{"@i", "@16"},
+		{"@sum", "@17"},
+		{"@i", "@16"},
+		{"@Sum", "@18"}, // Symbols are case-sensitive
+	}
+
+	for _, tt := range tests {
+		if got, err := resolveSymbol(tt.in); err != nil || got != tt.want {
+			t.Errorf("resolveSymbol(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestProcessLabelKeepsFirstDeclaration(t *testing.T) {
+	resetSymbols()
+
+	buf := []string{"@0"}
+	processLabel(&buf, "(A)")
+	buf = append(buf, "@1")
+	processLabel(&buf, "(A)")
+
+	if got, _ := resolveSymbol("@A"); got != "@1" {
+		t.Errorf("resolveSymbol(\"@A\") = %q, want \"@1\"", got)
+	}
+}
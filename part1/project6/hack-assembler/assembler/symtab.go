@@ -0,0 +1,52 @@
+package assembler
+
+// This file contains the symbol file (.sym): the labels of a program, for the
+// tools working on its machine language.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Writes labels, one "LABEL ADDRESS" line each, by address
+func WriteSymbols(w io.Writer, labels map[string]int) error {
+	names := sortedKeys(labels)
+	sort.SliceStable(names, func(i, j int) bool {
+		return labels[names[i]] < labels[names[j]]
+	})
+
+	out := bufio.NewWriter(w)
+	for _, name := range names {
+		fmt.Fprintf(out, "%s %d\n", name, labels[name])
+	}
+
+	return out.Flush()
+}
+
+// Reads labels written by WriteSymbols. Blank lines and comments are skipped.
+func ReadSymbols(r io.Reader) (map[string]int, error) {
+	labels := map[string]int{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(removeInlineComment(scanner.Text()))
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) != 2 || !isSymbol(fields[0]) {
+			return nil, fmt.Errorf("line %d: \"LABEL ADDRESS\" expected", n)
+		}
+		adr, err := strconv.Atoi(fields[1])
+		if err != nil || adr < 0 || adr > maxAddress+1 {
+			return nil, fmt.Errorf("line %d: invalid address %q", n, fields[1])
+		}
+		labels[fields[0]] = adr
+	}
+
+	return labels, scanner.Err()
+}
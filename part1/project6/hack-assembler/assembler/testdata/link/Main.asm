@@ -0,0 +1,13 @@
+// Computes R2 = R0 * R1 by calling MULT, linked from Mult.asm. MULT returns
+// to the address held in the shared variable "ret".
+
+    @.RET
+    D=A
+    @ret
+    M=D
+    @MULT
+    0;JMP
+(.RET)
+(.END)
+    @.END
+    0;JMP
@@ -0,0 +1,27 @@
+// Multiplies R0 and R1 and stores the result in R2, then jumps to the address
+// held in "ret". Labels and variables starting with "." are local to the file.
+
+(MULT)
+    @R2
+    M=0
+    @R1
+    D=M
+    @.i
+    M=D
+(.LOOP)
+    @.i
+    D=M
+    @.END
+    D;JLE
+    @R0
+    D=M
+    @R2
+    M=D+M
+    @.i
+    M=M-1
+    @.LOOP
+    0;JMP
+(.END)
+    @ret
+    A=M
+    0;JMP
@@ -0,0 +1,165 @@
+// Package cfg builds the control-flow graph of a Hack program: its basic
+// blocks, the jumps between them, and its loops.
+package cfg
+
+import (
+	"fmt"
+	"nand2tetris/hack-assembler/assembler"
+	"sort"
+	"strconv"
+)
+
+// A straight run of instructions, only entered at its first instruction and
+// only left after its last
+type Block struct {
+	ID     int
+	Start  int      // Address of the first instruction
+	End    int      // Address past the last instruction
+	Labels []string // Labels of the first instruction
+	Succs  []Edge
+	// Ends with a jump to an address computed at run time, e.g. the return
+	// address of a VM function, so its successors aren't known
+	Indirect bool
+}
+
+// Returns the number of instructions of the block
+func (b *Block) Len() int {
+	return b.End - b.Start
+}
+
+// A control transfer from one block to another
+type Edge struct {
+	To   int    // Block ID
+	Jump string // Jump mnemonic, e.g. "JGT", or "" when falling through
+	Back bool   // Goes back to the header of a loop
+}
+
+// A natural loop: the blocks of a cycle entered only through its header
+type Loop struct {
+	Header int
+	Blocks []int // Block IDs, the header included, in order
+}
+
+// The control-flow graph of a program
+type Graph struct {
+	Code   []string // Assembly of every instruction, with the label of jump targets
+	Blocks []*Block
+	Loops  []Loop
+}
+
+// A decoded instruction
+type instruction struct {
+	isA  bool
+	adr  int    // Address loaded by an A instruction
+	jump uint16 // Jump bits of a C instruction
+}
+
+// Builds the control-flow graph of the machine language program code, whose
+// labels, from the assembler's symbol table, are optional. The labels mark the
+// targets of computed jumps, and name the blocks.
+//
+// The target of a jump is known when the instruction before it loads it, as in
+// "@LOOP D;JGT". Other jumps are indirect.
+func Build(code []string, labels map[string]int) (*Graph, error) {
+	g := &Graph{Code: make([]string, len(code))}
+
+	ins := make([]instruction, len(code))
+	for i, c := range code {
+		word, err := strconv.ParseUint(c, 2, 16)
+		if err != nil || len(c) != 16 {
+			return nil, fmt.Errorf("instruction %d: invalid instruction %q", i, c)
+		}
+		ins[i] = instruction{isA: word&0x8000 == 0, adr: int(word), jump: uint16(word & 0x07)}
+
+		if g.Code[i], err = assembler.Disassemble(c); err != nil {
+			return nil, fmt.Errorf("instruction %d: %s", i, err)
+		}
+	}
+
+	// The labels of every address, in order
+	names := map[int][]string{}
+	for _, name := range sortedKeys(labels) {
+		names[labels[name]] = append(names[labels[name]], name)
+	}
+
+	// Known targets, by address of the jump
+	targets := map[int]int{}
+	leaders := map[int]bool{0: true}
+	for adr := range names {
+		leaders[adr] = true
+	}
+	for i, in := range ins {
+		if in.isA || in.jump == 0 {
+			continue
+		}
+		leaders[i+1] = true
+
+		if i > 0 && ins[i-1].isA && len(names[i]) == 0 {
+			target := ins[i-1].adr
+			targets[i] = target
+			leaders[target] = true
+			if len(names[target]) != 0 {
+				g.Code[i-1] = "@" + names[target][0]
+			}
+		}
+	}
+
+	// The blocks, split at the leaders
+	blockAt := map[int]int{}
+	for i := range ins {
+		if leaders[i] || i == 0 {
+			b := &Block{ID: len(g.Blocks), Start: i, Labels: names[i]}
+			blockAt[i] = b.ID
+			g.Blocks = append(g.Blocks, b)
+		}
+		g.Blocks[len(g.Blocks)-1].End = i + 1
+	}
+
+	for _, b := range g.Blocks {
+		last := ins[b.End-1]
+		next, falls := blockAt[b.End]
+
+		if !last.isA && last.jump != 0 {
+			jump := jumpMnemonic(last.jump)
+			if target, found := targets[b.End-1]; !found {
+				b.Indirect = true
+			} else if to, found := blockAt[target]; found {
+				b.Succs = append(b.Succs, Edge{To: to, Jump: jump})
+			}
+			// Jumps past the program halt it, as does an unconditional
+			// jump to nowhere
+			falls = falls && jump != "JMP"
+		}
+		if falls {
+			b.Succs = append(b.Succs, Edge{To: next})
+		}
+	}
+
+	g.findLoops()
+
+	return g, nil
+}
+
+func jumpMnemonic(bits uint16) string {
+	return []string{"", "JGT", "JEQ", "JGE", "JLT", "JNE", "JLE", "JMP"}[bits]
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Returns the number of edges of the graph
+func (g *Graph) Edges() int {
+	n := 0
+	for _, b := range g.Blocks {
+		n += len(b.Succs)
+	}
+
+	return n
+}
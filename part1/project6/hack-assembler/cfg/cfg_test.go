@@ -0,0 +1,147 @@
+package cfg
+
+import (
+	"bytes"
+	"flag"
+	"nand2tetris/hack-assembler/assembler"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Updates the golden files in testdata")
+
+func build(t *testing.T, src string) *Graph {
+	t.Helper()
+
+	code, labels, err := assembler.AssembleWithLabels(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := Build(code, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+// The blocks of a graph, as "ID:start-end->succ,succ"
+func shape(g *Graph) []string {
+	var out []string
+	for _, b := range g.Blocks {
+		var succs []string
+		for _, e := range b.Succs {
+			s := strings.TrimPrefix(e.Jump, "J")
+			if e.Back {
+				s += "^"
+			}
+			succs = append(succs, strings.TrimSpace(s+" "+string(rune('0'+e.To))))
+		}
+		out = append(out, string(rune('0'+b.ID))+":"+strings.Join(succs, ","))
+	}
+
+	return out
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		shape []string
+		loops []Loop
+	}{
+		{
+			"straight", "@1\nD=A\n@R0\nM=D\n",
+			[]string{"0:"}, nil,
+		},
+		{
+			"halting loop", "@1\nD=A\n(END)\n@END\n0;JMP\n",
+			[]string{"0:1", "1:MP^ 1"}, []Loop{{1, []int{1}}},
+		},
+		{
+			"countdown", "@10\nD=A\n(LOOP)\nD=D-1\n@LOOP\nD;JGT\n@R0\nM=D\n",
+			[]string{"0:1", "1:GT^ 1,2", "2:"}, []Loop{{1, []int{1}}},
+		},
+		{
+			// A jump target without a label starts a block too
+			"if", "@R0\nD=M\n@6\nD;JEQ\nD=D-1\n@R1\nM=D\n",
+			[]string{"0:EQ 2,1", "1:2", "2:"}, nil,
+		},
+		{
+			// Computed jumps land on labels only
+			"indirect", "@RET\nD=A\n@R15\nM=D\n@F\n0;JMP\n(RET)\n@RET\n0;JMP\n(F)\n@R15\nA=M\n0;JMP\n",
+			[]string{"0:MP 2", "1:MP^ 1", "2:"}, []Loop{{1, []int{1}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := build(t, tt.src)
+
+			if got := shape(g); !reflect.DeepEqual(got, tt.shape) {
+				t.Errorf("blocks = %q, want %q", got, tt.shape)
+			}
+			if !reflect.DeepEqual(g.Loops, tt.loops) {
+				t.Errorf("loops = %v, want %v", g.Loops, tt.loops)
+			}
+		})
+	}
+
+	if g := build(t, "@R15\nA=M\n0;JMP\n"); !g.Blocks[0].Indirect {
+		t.Error("computed jump not marked indirect")
+	}
+}
+
+// Fill loops over the screen within the loop listening to the keyboard
+func TestBuildFill(t *testing.T) {
+	src, err := os.ReadFile("../../../project4/Fill.asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := build(t, string(src))
+
+	want := []Loop{{0, []int{0, 1, 2, 3, 4, 5}}, {1, []int{1, 2, 3, 4, 5}}}
+	if !reflect.DeepEqual(g.Loops, want) {
+		t.Errorf("loops = %v, want %v", g.Loops, want)
+	}
+	if len(g.Blocks) != 6 || g.Edges() != 9 {
+		t.Errorf("%d blocks and %d edges, want 6 and 9", len(g.Blocks), g.Edges())
+	}
+	if b := g.Blocks[4]; b.Len() != 9 || !reflect.DeepEqual(b.Labels, []string{"SET_PIXEL_BLACK"}) {
+		t.Errorf("block 4 = %d instructions %v, want 9 at SET_PIXEL_BLACK", b.Len(), b.Labels)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	src, err := os.ReadFile("../assembler/testdata/Max.asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := build(t, string(src))
+
+	var buf bytes.Buffer
+	if err := g.WriteDOT(&buf, "Max"); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile("testdata/Max.dot", buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile("testdata/Max.dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(want) {
+		t.Errorf("output differs from testdata/Max.dot:\n%s", buf.String())
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	if _, err := Build([]string{"0000000000000001", "12"}, nil); err == nil || err.Error() != `instruction 1: invalid instruction "12"` {
+		t.Errorf("Build() error = %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package cfg
+
+// This file contains the Graphviz DOT output.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Writes the graph in the DOT language of Graphviz, as a digraph named name.
+// Every block is labeled with its address range, its instruction count and its
+// code. Loop headers are drawn bold, back edges red, and indirect jumps as
+// dashed edges to a "?" node.
+func (g *Graph) WriteDOT(w io.Writer, name string) error {
+	headers := map[int]bool{}
+	for _, loop := range g.Loops {
+		headers[loop.Header] = true
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "digraph %s {\n", quote(name))
+	fmt.Fprintln(out, "\tnode [shape=box, fontname=\"monospace\"];")
+
+	indirect := false
+	for _, b := range g.Blocks {
+		var label strings.Builder
+		fmt.Fprintf(&label, "B%d [%d-%d] %d instruction(s)\\l", b.ID, b.Start, b.End-1, b.Len())
+		for _, l := range b.Labels {
+			fmt.Fprintf(&label, "(%s)\\l", escape(l))
+		}
+		for _, in := range g.Code[b.Start:b.End] {
+			fmt.Fprintf(&label, "    %s\\l", escape(in))
+		}
+
+		style := ""
+		if headers[b.ID] {
+			style = ", style=bold"
+		}
+		fmt.Fprintf(out, "\tb%d [label=\"%s\"%s];\n", b.ID, label.String(), style)
+
+		for _, e := range b.Succs {
+			var attrs []string
+			if e.Jump != "" {
+				attrs = append(attrs, fmt.Sprintf("label=%q", e.Jump))
+			}
+			if e.Back {
+				attrs = append(attrs, "color=red")
+			}
+			fmt.Fprintf(out, "\tb%d -> b%d", b.ID, e.To)
+			if len(attrs) != 0 {
+				fmt.Fprintf(out, " [%s]", strings.Join(attrs, ", "))
+			}
+			fmt.Fprintln(out, ";")
+		}
+		if b.Indirect {
+			fmt.Fprintf(out, "\tb%d -> indirect [style=dashed];\n", b.ID)
+			indirect = true
+		}
+	}
+	if indirect {
+		fmt.Fprintln(out, "\tindirect [label=\"?\", shape=circle];")
+	}
+
+	fmt.Fprintln(out, "}")
+
+	return out.Flush()
+}
+
+// Escapes s for a DOT string
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+func quote(s string) string {
+	return `"` + escape(s) + `"`
+}
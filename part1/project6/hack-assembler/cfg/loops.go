@@ -0,0 +1,179 @@
+package cfg
+
+// This file contains the loop detection, from the dominators of the blocks.
+
+import "sort"
+
+// Finds the natural loops of the graph, and marks their back edges. The
+// program is entered at block 0, and at the labeled blocks not reached from
+// it, which only computed jumps reach, e.g. the return addresses of VM calls.
+func (g *Graph) findLoops() {
+	n := len(g.Blocks)
+	if n == 0 {
+		return
+	}
+
+	// Block n is a virtual root, jumping to every entry
+	preds := make([][]int, n+1)
+	for _, b := range g.Blocks {
+		for _, e := range b.Succs {
+			preds[e.To] = append(preds[e.To], b.ID)
+		}
+	}
+	var roots []int
+	reached := make([]bool, n)
+	var reach func(id int)
+	reach = func(id int) {
+		reached[id] = true
+		for _, e := range g.Blocks[id].Succs {
+			if !reached[e.To] {
+				reach(e.To)
+			}
+		}
+	}
+	for _, b := range g.Blocks {
+		if !reached[b.ID] && (b.ID == 0 || len(b.Labels) != 0) {
+			roots = append(roots, b.ID)
+			reach(b.ID)
+		}
+	}
+	for _, r := range roots {
+		preds[r] = append(preds[r], n)
+	}
+
+	idom := g.dominators(roots, preds)
+
+	// A back edge goes to a block dominating its source
+	bodies := map[int]map[int]bool{}
+	for _, b := range g.Blocks {
+		for i, e := range b.Succs {
+			if idom[b.ID] < 0 || !dominates(idom, e.To, b.ID, n) {
+				continue
+			}
+			b.Succs[i].Back = true
+
+			body := bodies[e.To]
+			if body == nil {
+				body = map[int]bool{e.To: true}
+				bodies[e.To] = body
+			}
+			// The body holds the blocks reaching the source without going
+			// through the header
+			stack := []int{b.ID}
+			for len(stack) != 0 {
+				id := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if body[id] || idom[id] < 0 {
+					continue
+				}
+				body[id] = true
+				for _, p := range preds[id] {
+					if p != n {
+						stack = append(stack, p)
+					}
+				}
+			}
+		}
+	}
+
+	for header, body := range bodies {
+		loop := Loop{Header: header}
+		for id := range body {
+			loop.Blocks = append(loop.Blocks, id)
+		}
+		sort.Ints(loop.Blocks)
+		g.Loops = append(g.Loops, loop)
+	}
+	sort.Slice(g.Loops, func(i, j int) bool {
+		return g.Loops[i].Header < g.Loops[j].Header
+	})
+}
+
+// Returns the immediate dominator of every block, the virtual root len(Blocks)
+// for the entries, or -1 for the blocks not reached from them. Uses the
+// algorithm of Cooper, Harvey and Kennedy.
+func (g *Graph) dominators(roots []int, preds [][]int) []int {
+	n := len(g.Blocks)
+
+	// Reverse postorder from the virtual root
+	order := make([]int, n+1) // Postorder number of every block
+	for i := range order {
+		order[i] = -1
+	}
+	var post []int
+	visited := make([]bool, n+1)
+	var visit func(id int)
+	visit = func(id int) {
+		visited[id] = true
+		var succs []int
+		if id == n {
+			succs = roots
+		} else {
+			for _, e := range g.Blocks[id].Succs {
+				succs = append(succs, e.To)
+			}
+		}
+		for _, s := range succs {
+			if !visited[s] {
+				visit(s)
+			}
+		}
+		order[id] = len(post)
+		post = append(post, id)
+	}
+	visit(n)
+
+	idom := make([]int, n+1)
+	for i := range idom {
+		idom[i] = -1
+	}
+	idom[n] = n
+
+	intersect := func(a, b int) int {
+		for a != b {
+			for order[a] < order[b] {
+				a = idom[a]
+			}
+			for order[b] < order[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := len(post) - 2; i >= 0; i-- {
+			id := post[i]
+			d := -1
+			for _, p := range preds[id] {
+				if idom[p] < 0 {
+					continue
+				}
+				if d < 0 {
+					d = p
+				} else {
+					d = intersect(p, d)
+				}
+			}
+			if d != idom[id] {
+				idom[id] = d
+				changed = true
+			}
+		}
+	}
+
+	return idom
+}
+
+// Returns whether block a dominates block b
+func dominates(idom []int, a, b int, root int) bool {
+	for b != root {
+		if b == a {
+			return true
+		}
+		b = idom[b]
+	}
+
+	return false
+}
@@ -0,0 +1,35 @@
+package main
+
+// This file defines all the hard-coded data/values
+
+const (
+	helpMsg = `Nand2Tetris Hack Control-Flow Graph
+Usage:
+	hack-cfg [-h/--help] [-v/--verbose] [-s/--symbols FILE] [-o/--out FILE]
+	         PROGRAM
+
+Flags:
+	-h/--help           Shows this help message and exits.
+	-v/--verbose        Logs every block and loop. (Default: off)
+	-s/--symbols FILE   Labels of a machine language program, as written by
+	                    "hack-assembler --symbols". (Default: "Xxx.sym" next
+	                    to "Xxx.hack", if any)
+	-o/--out FILE       Graphviz output. (Default: "Xxx.dot" next to the
+	                    program)
+
+Positional Argument:
+	PROGRAM             Hack program, in assembly (.asm) or machine language
+	                    (.hack). Required.
+
+Description:
+	Splits the program into basic blocks, and writes its control-flow graph in
+	the DOT language of Graphviz, e.g. for "dot -Tsvg Xxx.dot". Every block
+	shows its addresses, instruction count and code; loop headers are bold,
+	the jumps back to them red.
+
+	A jump goes to the address loaded by the A instruction before it, as in
+	"@LOOP D;JGT". Other jumps, e.g. returns from VM functions, are computed
+	at run time, and drawn as dashed edges to a "?" node. The labels of the
+	program mark where computed jumps can land.
+`
+)
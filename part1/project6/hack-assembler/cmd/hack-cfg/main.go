@@ -0,0 +1,139 @@
+package main
+
+// This file contains the main application logic, logger, and file I/O
+// control.
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"nand2tetris/hack-assembler/assembler"
+	"nand2tetris/hack-assembler/cfg"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, helpMsg)
+		os.Exit(0)
+	}
+
+	var verbose bool
+	flag.BoolVar(&verbose, "verbose", false, "Logs every block and loop")
+	flag.BoolVar(&verbose, "v", false, "Logs every block and loop")
+
+	var symPath string
+	flag.StringVar(&symPath, "symbols", "", "Labels of a machine language program")
+	flag.StringVar(&symPath, "s", "", "Labels of a machine language program")
+
+	var outPath string
+	flag.StringVar(&outPath, "out", "", "Graphviz output")
+	flag.StringVar(&outPath, "o", "", "Graphviz output")
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+	}
+
+	inPath := flag.Arg(0)
+	ext := filepath.Ext(inPath)
+	if ext != ".asm" && ext != ".hack" {
+		log.Fatalln("[!] Error: Hack assembly (.asm) or machine language (.hack) file expected")
+	}
+	if outPath == "" {
+		outPath = strings.TrimSuffix(inPath, ext) + ".dot"
+	}
+
+	inFile, err := os.Open(inPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to open %q: %s", inPath, err)
+	}
+	defer inFile.Close()
+
+	var code []string
+	var labels map[string]int
+	if ext == ".asm" {
+		if code, labels, err = assembler.AssembleWithLabels(inFile); err != nil {
+			log.Fatalf("[!] Error: %s", err)
+		}
+	} else {
+		code = readLines(inFile, inPath)
+		labels = readSymbols(symPath, strings.TrimSuffix(inPath, ext)+".sym", verbose)
+	}
+
+	g, err := cfg.Build(code, labels)
+	if err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
+
+	if verbose {
+		for _, b := range g.Blocks {
+			log.Printf("[i] Block %d %v: %d instruction(s) at %d-%d\n", b.ID, b.Labels, b.Len(), b.Start, b.End-1)
+		}
+		for _, loop := range g.Loops {
+			log.Printf("[i] Loop at block %d, over blocks %v\n", loop.Header, loop.Blocks)
+		}
+		log.Printf("[i] %d blocks, %d edges, %d loops\n", len(g.Blocks), g.Edges(), len(g.Loops))
+		log.Printf("[i] Writing output to %q\n", outPath)
+	}
+
+	outFile, err := os.Create(outPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to create %q: %s", outPath, err)
+	}
+	defer outFile.Close()
+
+	name := strings.TrimSuffix(filepath.Base(inPath), ext)
+	if err := g.WriteDOT(outFile, name); err != nil {
+		log.Fatalf("[!] Error: Unable to write %q: %s", outPath, err)
+	}
+
+	log.Printf("[i] Control-flow graph output to %q successful\n", outPath)
+}
+
+// Reads the non-blank lines of a machine language program
+func readLines(inFile *os.File, inPath string) []string {
+	var lines []string
+
+	scanner := bufio.NewScanner(inFile)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("[!] Error: Unable to read %q: %s", inPath, err)
+	}
+
+	return lines
+}
+
+// Reads the labels of symPath, or of defaultPath if it exists
+func readSymbols(symPath string, defaultPath string, verbose bool) map[string]int {
+	if symPath == "" {
+		if _, err := os.Stat(defaultPath); err != nil {
+			return nil
+		}
+		symPath = defaultPath
+	}
+
+	if verbose {
+		log.Printf("[i] Reading labels from %q\n", symPath)
+	}
+	symFile, err := os.Open(symPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to open %q: %s", symPath, err)
+	}
+	defer symFile.Close()
+
+	labels, err := assembler.ReadSymbols(symFile)
+	if err != nil {
+		log.Fatalf("[!] Error: %q: %s", symPath, err)
+	}
+
+	return labels
+}
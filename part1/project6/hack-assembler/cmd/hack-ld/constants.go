@@ -0,0 +1,32 @@
+package main
+
+// This file defines all the hard-coded data/values
+
+const (
+	helpMsg = `Nand2Tetris Hack Linker
+Usage:
+	hack-ld [-h/--help] [-v/--verbose] [-o/--out FILE] OBJECT...
+
+Flags:
+	-h/--help           Shows this help message and exits.
+	-v/--verbose        Enables verbosity. (Default: off)
+	-o/--out FILE       Machine language output. (Default: "Xxx.hack" next to
+	                    the first object "Xxx.hobj")
+
+Positional Argument:
+	OBJECT              Relocatable object (.hobj) written by
+	                    "hack-assembler --object". At least one is required.
+
+Description:
+	The Hack linker joins objects into a single Hack machine language program
+	(.hack). The objects are laid out in the order given, so the first one
+	starts at address 0 and should hold the entry point.
+
+	Labels starting with "." are resolved within their object, the others
+	across every object. Other symbols are variables, given RAM addresses from
+	16: those starting with "." are local to their object, the others are
+	shared. Every undefined label, jumped to or used in an expression but
+	declared nowhere, and every label declared by two objects is reported, and
+	nothing is written.
+`
+)
@@ -0,0 +1,95 @@
+package main
+
+// This file contains the main application logic, logger, and file I/O
+// control.
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"nand2tetris/hack-assembler/assembler"
+	"os"
+	"strings"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, helpMsg)
+		os.Exit(0)
+	}
+
+	var verbose bool
+	flag.BoolVar(&verbose, "verbose", false, "Enables verbosity")
+	flag.BoolVar(&verbose, "v", false, "Enables verbosity")
+
+	var outPath string
+	flag.StringVar(&outPath, "out", "", "Machine language output")
+	flag.StringVar(&outPath, "o", "", "Machine language output")
+
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+	}
+
+	var objs []*assembler.Object
+	for _, inPath := range flag.Args() {
+		if !strings.HasSuffix(inPath, ".hobj") {
+			log.Fatalf("[!] Error: Hack object file (.hobj) expected, got %q", inPath)
+		}
+		if verbose {
+			log.Printf("[i] Reading object %q\n", inPath)
+		}
+		objs = append(objs, readObject(inPath))
+	}
+	if outPath == "" {
+		outPath = strings.TrimSuffix(flag.Arg(0), ".hobj") + ".hack"
+	}
+
+	outLines, err := assembler.Link(objs)
+	if errs, ok := err.(assembler.LinkErrors); ok {
+		for _, e := range errs {
+			log.Printf("[!] Error: %s", e)
+		}
+		log.Fatalf("[!] Error: %d symbol(s) can't be linked", len(errs))
+	} else if err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
+
+	if verbose {
+		log.Printf("[i] %d instructions linked from %d object(s)\n", len(outLines), len(objs))
+		log.Printf("[i] Writing output to %q\n", outPath)
+	}
+	writeOutput(outLines, outPath)
+
+	log.Printf("[i] Link output to %q successful\n", outPath)
+}
+
+// Reads the object at inPath
+func readObject(inPath string) *assembler.Object {
+	inFile, err := os.Open(inPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to open %q: %s", inPath, err)
+	}
+	defer inFile.Close()
+
+	obj, err := assembler.ReadObject(inFile)
+	if err != nil {
+		log.Fatalf("[!] Error: %q: %s", inPath, err)
+	}
+
+	return obj
+}
+
+// Write the contents of buf to outPath line-by-line
+func writeOutput(buf []string, outPath string) {
+	outFile, err := os.Create(outPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to create %q: %s", outPath, err)
+	}
+	defer outFile.Close()
+
+	for _, code := range buf {
+		outFile.WriteString(code + "\n")
+	}
+}
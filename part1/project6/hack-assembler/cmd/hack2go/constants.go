@@ -0,0 +1,38 @@
+package main
+
+// This file defines all the hard-coded data/values
+
+const (
+	helpMsg = `Nand2Tetris Hack to Go Translator
+Usage:
+	hack2go [-h/--help] [-v/--verbose] [-s/--symbols FILE] [-p/--package NAME]
+	        [-o/--out FILE] PROGRAM
+
+Flags:
+	-h/--help           Shows this help message and exits.
+	-v/--verbose        Enables verbosity. (Default: off)
+	-s/--symbols FILE   Labels of a machine language program, as written by
+	                    "hack-assembler --symbols". (Default: "Xxx.sym" next
+	                    to "Xxx.hack", if any)
+	-p/--package NAME   Go package of the output. (Default: "main")
+	-o/--out FILE       Go output. (Default: "Xxx.go" next to the program)
+
+Positional Argument:
+	PROGRAM             Hack program, in assembly (.asm) or machine language
+	                    (.hack). Required.
+
+Description:
+	Translates the program ahead of time to Go: every basic block becomes Go
+	code, and the jumps between blocks gotos. Jumps computed at run time, e.g.
+	returns from VM functions, go through a switch over the blocks. The labels
+	of the program mark where they land; without them, the code reached only
+	by computed jumps is run one instruction at a time.
+
+	The output defines a Computer with the API of the hack-emulator's cpu
+	package (RAM, A, D, PC, Cycles, Run, Step, Halted, Read, Write, SetKey),
+	giving the same results cycle for cycle. Package main also gets a main
+	function, running the program from the RAM words given as ADDRESS=VALUE
+	arguments until it halts, or for at most "-cycles N", and printing the
+	words of RAM that aren't 0.
+`
+)
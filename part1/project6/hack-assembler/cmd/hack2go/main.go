@@ -0,0 +1,137 @@
+package main
+
+// This file contains the main application logic, logger, and file I/O
+// control.
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/token"
+	"log"
+	"nand2tetris/hack-assembler/assembler"
+	"nand2tetris/hack-assembler/hackgo"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, helpMsg)
+		os.Exit(0)
+	}
+
+	var verbose bool
+	flag.BoolVar(&verbose, "verbose", false, "Enables verbosity")
+	flag.BoolVar(&verbose, "v", false, "Enables verbosity")
+
+	var symPath string
+	flag.StringVar(&symPath, "symbols", "", "Labels of a machine language program")
+	flag.StringVar(&symPath, "s", "", "Labels of a machine language program")
+
+	var pkg string
+	flag.StringVar(&pkg, "package", "main", "Go package of the output")
+	flag.StringVar(&pkg, "p", "main", "Go package of the output")
+
+	var outPath string
+	flag.StringVar(&outPath, "out", "", "Go output")
+	flag.StringVar(&outPath, "o", "", "Go output")
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+	}
+	if !token.IsIdentifier(pkg) {
+		log.Fatalf("[!] Error: Invalid Go package name %q", pkg)
+	}
+
+	inPath := flag.Arg(0)
+	ext := filepath.Ext(inPath)
+	if ext != ".asm" && ext != ".hack" {
+		log.Fatalln("[!] Error: Hack assembly (.asm) or machine language (.hack) file expected")
+	}
+	if outPath == "" {
+		outPath = strings.TrimSuffix(inPath, ext) + ".go"
+	}
+
+	inFile, err := os.Open(inPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to open %q: %s", inPath, err)
+	}
+	defer inFile.Close()
+
+	var code []string
+	var labels map[string]int
+	if ext == ".asm" {
+		if code, labels, err = assembler.AssembleWithLabels(inFile); err != nil {
+			log.Fatalf("[!] Error: %s", err)
+		}
+	} else {
+		code = readLines(inFile, inPath)
+		labels = readSymbols(symPath, strings.TrimSuffix(inPath, ext)+".sym", verbose)
+	}
+
+	if verbose {
+		log.Printf("[i] Translating %d instruction(s) and %d label(s) to package %s\n", len(code), len(labels), pkg)
+	}
+
+	var src bytes.Buffer
+	if err := hackgo.Translate(&src, code, labels, pkg); err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
+
+	if verbose {
+		log.Printf("[i] Writing output to %q\n", outPath)
+	}
+	if err := os.WriteFile(outPath, src.Bytes(), 0644); err != nil {
+		log.Fatalf("[!] Error: Unable to write %q: %s", outPath, err)
+	}
+
+	log.Printf("[i] Go output to %q successful\n", outPath)
+}
+
+// Reads the non-blank lines of a machine language program
+func readLines(inFile *os.File, inPath string) []string {
+	var lines []string
+
+	scanner := bufio.NewScanner(inFile)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("[!] Error: Unable to read %q: %s", inPath, err)
+	}
+
+	return lines
+}
+
+// Reads the labels of symPath, or of defaultPath if it exists
+func readSymbols(symPath string, defaultPath string, verbose bool) map[string]int {
+	if symPath == "" {
+		if _, err := os.Stat(defaultPath); err != nil {
+			return nil
+		}
+		symPath = defaultPath
+	}
+
+	if verbose {
+		log.Printf("[i] Reading labels from %q\n", symPath)
+	}
+	symFile, err := os.Open(symPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to open %q: %s", symPath, err)
+	}
+	defer symFile.Close()
+
+	labels, err := assembler.ReadSymbols(symFile)
+	if err != nil {
+		log.Fatalf("[!] Error: %q: %s", symPath, err)
+	}
+
+	return labels
+}
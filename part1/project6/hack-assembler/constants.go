@@ -5,11 +5,26 @@ package main
 const (
 	helpMsg = `Nand2Tetris Hack Assembler
 Usage:
-	hack-assembler [-h/--help] [-v/--verbose] ASSEMBLY
+	hack-assembler [-h/--help] [-v/--verbose] [-m/--mem] [-s/--symbols]
+	               [-c/--object] [-l/--lint] [-d/--disable RULES] ASSEMBLY
 
 Flags:
 	-h/--help           Shows this help message and exits.
 	-v/--verbose        Enables verbosity. (Default: off)
+	-m/--mem            Also writes a memory file (.mem) for Verilog's
+	                    $readmemb, holding the program padded with zeros to the
+	                    32K words of ROM32K. (Default: off)
+	-s/--symbols        Also writes the labels of the program to a symbol file
+	                    (.sym), one "LABEL ADDRESS" line each, for the tools
+	                    working on machine language such as hack-cfg.
+	                    (Default: off)
+	-c/--object         Writes a relocatable object (.hobj) instead of machine
+	                    language, to be linked with others by hack-ld.
+	                    (Default: off)
+	-l/--lint           Reports hazards instead of assembling: valid code that
+	                    likely doesn't do what was meant. Exits with status 1
+	                    if any is found. (Default: off)
+	-d/--disable RULES  Comma-separated lint rules to skip. (Default: none)
 
 Positional Argument:
 	ASSEMBLY            File containing Hack assembly code. File is expected to
@@ -21,60 +36,35 @@ Description:
 	follow the Hack computer and language specification defined in the Nand2Tetris
 	courseware.
 
+	A instructions also take hexadecimal ("@0x4000"), binary ("@0b1010") and
+	character ("@'A'") numbers, and constant expressions over numbers and
+	symbols, with "+ - * / & | << >>" and parentheses, e.g. "@SCREEN+32".
+	Operators take the precedence of C. Expressions are evaluated once the labels
+	are known, and must fit in the 15 bits of the instruction. Their symbols have
+	to be labels or predefined symbols: a variable in an expression is an error.
+
+	Lint rules:
+	    a-and-m         A written from M along with D, then M used, e.g.
+	                    "AD=M+1 M=D": the two M are different words.
+	    jump-a          A written by a jump, e.g. "A=A+1;JMP": the target is
+	                    ambiguous.
+	    m-after-a       M used right after arithmetic on A, e.g. "@SCREEN
+	                    A=D+A M=-1": M isn't the word of the symbol.
+	    no-halt         The program doesn't end with an "(END) @END 0;JMP"
+	                    loop jumping to itself, e.g. falls off its end or loops
+	                    back to an earlier label.
+	    unreachable     Code after an unconditional jump, with no label to jump
+	                    to it.
+
+	Objects leave the labels and variables for hack-ld to resolve. Labels and
+	variables starting with "." are local to their file; the other labels can
+	be jumped to from the other linked files, and the other variables are
+	shared by them.
+
 	This assembler is project #6 of the Nand2Tetris (https://www.nand2tetris.org)
 	courseware and book "The Elements of Computing Systems" by Noam Nisan and
 	Shimon Schocken. This implementation is written in GO by
 	tera-si (https://github.com/tera-si).
 `
-	markComment      = "//" // Afaik, there aren't multi-line comment in Hack asm
-	markAInstruction = "@"
-	opcodeA          = "0"
-
-	markDestComp = "="   // Separates the dest and comp in a C instruction
-	markCompJmp  = ";"   // Separates the comp and jump in a C instruction
-	opcodeC      = "111" // Technically it's just "1" with two unused "11" bits
-
-	// Builtin variables. R0-15 will be populated when assembler initialises
-	screenAdr = "16384"
-	kbdAdr    = "24576"
-	spAdr     = "0"
-	lclAdr    = "1"
-	argAdr    = "2"
-	thisAdr   = "3"
-	thatAdr   = "4"
-
-	// Jump bits for C instructions
-	jgtBin = "001"
-	jeqBin = "010"
-	jgeBin = "011"
-	jltBin = "100"
-	jneBin = "101"
-	jleBin = "110"
-	jmpBin = "111"
-
-	// Destination index for C instructions
-	// For example, if destination is M (001), the 3rd bit should be switched on
-	iM = 2
-	iD = 1
-	iA = 0
-
-	// Computation bits for C instructions
-	comp0  = "101010" // 0
-	comp1  = "111111" // 1
-	comp2  = "111010" // -1
-	comp3  = "001100" // D
-	comp4  = "110000" // A or M
-	comp5  = "001101" // !D
-	comp6  = "110001" // !A or !M
-	comp7  = "001111" // -D
-	comp8  = "110011" // -A or -M
-	comp9  = "011111" // D+1
-	comp10 = "110111" // A+1 or M+1
-	comp11 = "001110" // D-1
-	comp12 = "110010" // A-1 or M-1
-	comp13 = "000010" // D+A or D+M
-	comp14 = "010011" // D-A or D-M
-	comp15 = "000111" // A-D or M-D
-	comp16 = "000000" // D&A or D&M
-	comp17 = "010101" // D|A or D|M
+	romSize = 32768 // Words of ROM32K, the size of a memory file
 )
@@ -0,0 +1,3 @@
+module nand2tetris/hack-assembler
+
+go 1.18
@@ -0,0 +1,406 @@
+// Package hackgo translates Hack machine language programs ahead of time to Go
+// source, for running them faster than any interpreter can.
+//
+// Every basic block of the program becomes straight Go code, and the blocks
+// jump to each other with goto. Jumps to addresses computed at run time go
+// through a switch over the blocks. The generated Computer has the API of the
+// hack-emulator's cpu package, and gives the same results cycle for cycle.
+package hackgo
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"nand2tetris/hack-assembler/cfg"
+	"strconv"
+	"strings"
+)
+
+const (
+	ramSize = 24577
+	kbdAdr  = 24576
+)
+
+// How the computer can be halted at an address, as by the emulator's Halted
+const (
+	haltNever  = iota
+	haltAlways // At the "@END" of "(END) @END 0;JMP"
+	haltIfA    // At an unconditional jump keeping A, if it jumps back
+)
+
+// Go conditions of the conditional jumps, on the ALU output, by jump bits
+var jumpConds = []string{
+	"", "int16(%s) > 0", "%s == 0", "int16(%s) >= 0",
+	"int16(%s) < 0", "%s != 0", "int16(%s) <= 0",
+}
+
+// The state of a translation
+type translator struct {
+	out   *bytes.Buffer
+	g     *cfg.Graph
+	rom   []uint16
+	halts []uint8
+	// Block IDs by address of their first instruction
+	blockAt map[int]int
+	// Variables of Run used by the blocks
+	usesOut, usesAdr bool
+}
+
+// Translates the machine language program code, one string of 16 "0" and "1"
+// per instruction, to the Go source of package pkg. The labels of the
+// program, from the assembler's symbol table, are optional: they mark where
+// computed jumps land, and get their own block instead of being run one
+// instruction at a time.
+//
+// Package main also gets a main function running the program from the RAM
+// words given as ADDRESS=VALUE arguments, and printing the RAM afterwards.
+func Translate(w io.Writer, code []string, labels map[string]int, pkg string) error {
+	g, err := cfg.Build(code, labels)
+	if err != nil {
+		return err
+	}
+
+	t := &translator{g: g, blockAt: map[int]int{}}
+	for _, c := range code {
+		word, _ := strconv.ParseUint(c, 2, 16)
+		t.rom = append(t.rom, uint16(word))
+	}
+	t.findHalts()
+	for _, b := range g.Blocks {
+		t.blockAt[b.Start] = b.ID
+	}
+
+	// The blocks first, to know the variables they use
+	var blocks, src bytes.Buffer
+	t.out = &blocks
+	for _, b := range g.Blocks {
+		t.block(b)
+	}
+	t.out = &src
+
+	t.header(pkg)
+	t.run(blocks.Bytes())
+	t.halted()
+	if pkg == "main" {
+		t.out.WriteString(mainFunc)
+	}
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated invalid Go: %s", err)
+	}
+	_, err = w.Write(out)
+
+	return err
+}
+
+// Finds the addresses where the computer halts, as the emulator does
+func (t *translator) findHalts() {
+	t.halts = make([]uint8, len(t.rom))
+	for i, in := range t.rom {
+		switch {
+		case int(in) == i && in&0x8000 == 0:
+			if i+1 < len(t.rom) && isUnconditionalJump(t.rom[i+1]) && t.rom[i+1]&0x20 == 0 {
+				t.halts[i] = haltAlways
+			}
+		case isUnconditionalJump(in) && in&0x20 == 0:
+			t.halts[i] = haltIfA
+		}
+	}
+}
+
+func isUnconditionalJump(in uint16) bool {
+	return in&0x8000 != 0 && in&0x07 == 0x07
+}
+
+func (t *translator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(t.out, format, args...)
+}
+
+// Writes the package clause, the types and the interpreter running the
+// instructions outside of the blocks
+func (t *translator) header(pkg string) {
+	t.printf("// Code generated by hack2go. DO NOT EDIT.\n\n")
+	t.printf("package %s\n\n", pkg)
+	if pkg == "main" {
+		t.printf("import (\n\"flag\"\n\"fmt\"\n\"os\"\n\"strconv\"\n\"strings\"\n)\n\n")
+	}
+	t.out.WriteString(runtime)
+
+	t.printf("\n// The program\nvar rom = []uint16{")
+	for i, in := range t.rom {
+		if i%8 == 0 {
+			t.printf("\n")
+		}
+		t.printf("0x%04X, ", in)
+	}
+	t.printf("\n}\n")
+}
+
+// Writes Run: the dispatch switch over the blocks, then the blocks
+func (t *translator) run(blocks []byte) {
+	t.printf(`
+// Runs the program until it halts, or for at most maxCycles instructions if
+// maxCycles > 0. Returns whether it halted.
+func (c *Computer) Run(maxCycles int) bool {
+	limit := c.Cycles + maxCycles
+`)
+	if t.usesOut {
+		t.printf("var out uint16\n")
+	}
+	if t.usesAdr {
+		t.printf("var adr uint16\n")
+	}
+
+	t.printf("\ndispatch:\nswitch c.PC {\n")
+	for _, b := range t.g.Blocks {
+		t.printf("case %d:\ngoto b%d\n", b.Start, b.ID)
+	}
+	t.printf(`default:
+	goto step
+}
+
+// Anywhere else, e.g. after a computed jump into a block, or with too few
+// cycles left for a whole block: one instruction at a time
+step:
+if c.Halted() {
+	return true
+}
+if maxCycles > 0 && c.Cycles >= limit {
+	return false
+}
+c.Step()
+goto dispatch
+`)
+	t.out.Write(blocks)
+	t.printf("}\n")
+}
+
+// Writes the code of block b
+func (t *translator) block(b *cfg.Block) {
+	t.printf("\n// Block %d", b.ID)
+	if len(b.Labels) != 0 {
+		t.printf(" (%s)", strings.Join(b.Labels, ") ("))
+	}
+	t.printf("\nb%d:\n", b.ID)
+	if t.haltCheck(b.Start, -1) == "true" {
+		t.printf("c.PC = %d\nreturn true\n", b.Start)
+		return
+	}
+	t.printf("if maxCycles > 0 && c.Cycles+%d > limit {\nc.PC = %d\ngoto step\n}\n", b.Len(), b.Start)
+	t.printf("c.Cycles += %d\n", b.Len())
+
+	a := -1 // A, when known
+	for adr := b.Start; adr < b.End; adr++ {
+		in := t.rom[adr]
+		t.printf("// %s\n", t.g.Code[adr])
+
+		if halts := t.haltCheck(adr, a); halts != "" {
+			refund := ""
+			if left := b.End - adr; left != 0 {
+				refund = fmt.Sprintf("c.Cycles -= %d\n", left)
+			}
+			if halts == "true" {
+				t.printf("%sc.PC = %d\nreturn true\n", refund, adr)
+				return
+			}
+			t.printf("if %s {\n%sc.PC = %d\nreturn true\n}\n", halts, refund, adr)
+		}
+
+		if in&0x8000 == 0 {
+			t.printf("c.A = %d\n", in)
+			a = int(in)
+			continue
+		}
+		if t.instruction(adr, a) {
+			return
+		}
+		if in&0x20 != 0 {
+			a = -1
+		}
+	}
+
+	// Falls through to the next block, written right after this one
+	if b.End == len(t.rom) {
+		t.printf("c.PC = %d\ngoto dispatch\n", b.End)
+	}
+}
+
+// Returns the condition halting the computer before the instruction at adr,
+// "true" if it always does, or "" if it never does. a is the value of A, or
+// -1 if it isn't known.
+func (t *translator) haltCheck(adr int, a int) string {
+	switch t.halts[adr] {
+	case haltAlways:
+		return "true"
+	case haltIfA:
+		// At the jump: back to itself, or to the "@END" before it
+		endBefore := adr > 0 && t.rom[adr-1] == uint16(adr-1)
+		if a >= 0 {
+			if a == adr || (endBefore && a == adr-1) {
+				return "true"
+			}
+			return ""
+		}
+		if endBefore {
+			return fmt.Sprintf("c.A == %d || c.A == %d", adr, adr-1)
+		}
+		return fmt.Sprintf("c.A == %d", adr)
+	}
+
+	return ""
+}
+
+// Writes the code of C instruction at adr, a being the value of A, or -1 if
+// it isn't known. Returns whether the instruction always jumps, ending the
+// block.
+func (t *translator) instruction(adr int, a int) bool {
+	in := t.rom[adr]
+	asm := t.g.Code[adr]
+	dest, jump := in>>3&7, in&7
+
+	comp := asm
+	if i := strings.Index(comp, "="); i >= 0 {
+		comp = comp[i+1:]
+	}
+	if i := strings.Index(comp, ";"); i >= 0 {
+		comp = comp[:i]
+	}
+	expr := t.expression(comp, a)
+
+	// M is written, and the jump taken, at the address held in A before the
+	// instruction
+	target := "c.A"
+	if a >= 0 {
+		target = strconv.Itoa(a)
+	}
+	write := func(v string) {
+		if a >= kbdAdr {
+			return
+		}
+		if a >= 0 {
+			if mem := fmt.Sprintf("c.RAM[%d]", a); v != mem {
+				t.printf("%s = %s\n", mem, v)
+			}
+		} else {
+			t.printf("c.Write(c.A, %s)\n", v)
+		}
+	}
+
+	if jump != 0 && dest&4 != 0 && a < 0 {
+		t.usesAdr = true
+		t.printf("adr = c.A\n")
+		target = "adr"
+	}
+
+	value := expr // The ALU output, once computed
+	switch {
+	case dest == 0 && (jump == 0 || jump == 7):
+		// Only a jump, if any
+	case dest == 0 && comp != "0" && comp != "1" && comp != "-1":
+		// Tested as is
+	case dest == 1 && (jump == 0 || jump == 7):
+		write(expr)
+	case dest == 2:
+		if expr != "c.D" {
+			t.printf("c.D = %s\n", expr)
+		}
+		value = "c.D"
+	case dest == 4:
+		if expr != "c.A" {
+			t.printf("c.A = %s\n", expr)
+		}
+		value = "c.A"
+	default:
+		t.usesOut = true
+		t.printf("out = %s\n", expr)
+		t.assign(dest, write)
+		value = "out"
+	}
+	if jump == 0 {
+		return false
+	}
+
+	goTo := fmt.Sprintf("c.PC = %s\ngoto dispatch\n", target)
+	if a >= 0 {
+		if id, found := t.blockAt[a]; found {
+			goTo = fmt.Sprintf("goto b%d\n", id)
+		}
+	}
+	if jump == 7 {
+		t.printf("%s", goTo)
+		return true
+	}
+	t.printf("if %s {\n%s}\n", fmt.Sprintf(jumpConds[jump], value), goTo)
+
+	return false
+}
+
+// Writes the assignments of out to the destinations of a C instruction, M
+// first, while A still holds its address
+func (t *translator) assign(dest uint16, write func(v string)) {
+	if dest&1 != 0 {
+		write("out")
+	}
+	if dest&2 != 0 {
+		t.printf("c.D = out\n")
+	}
+	if dest&4 != 0 {
+		t.printf("c.A = out\n")
+	}
+}
+
+// Returns the Go expression of computation mnemonic comp, e.g. "D+M", a being
+// the value of A, or -1 if it isn't known
+func (t *translator) expression(comp string, a int) string {
+	if comp == "-1" {
+		return "0xFFFF"
+	}
+
+	var expr strings.Builder
+	for _, r := range comp {
+		switch r {
+		case 'D':
+			expr.WriteString("c.D")
+		case 'A':
+			expr.WriteString("c.A")
+		case 'M':
+			switch {
+			case a < 0 || a >= ramSize:
+				expr.WriteString("c.Read(c.A)")
+			default:
+				fmt.Fprintf(&expr, "c.RAM[%d]", a)
+			}
+		case '!':
+			expr.WriteString("^")
+		case '+', '-', '&', '|':
+			if expr.Len() == 0 {
+				expr.WriteRune(r)
+			} else {
+				fmt.Fprintf(&expr, " %c ", r)
+			}
+		default:
+			expr.WriteRune(r)
+		}
+	}
+
+	return expr.String()
+}
+
+// Writes Halted, with the addresses where the computer halts
+func (t *translator) halted() {
+	t.printf(`
+// Returns whether the computer is stuck in the loop ending Hack programs,
+// "(END) @END 0;JMP", or a jump to itself, or has run past the end of the
+// program
+func (c *Computer) Halted() bool {
+	switch c.PC {
+`)
+	for adr := range t.halts {
+		if cond := t.haltCheck(adr, -1); cond != "" {
+			t.printf("case %d:\nreturn %s\n", adr, cond)
+		}
+	}
+	t.printf("}\n\nreturn int(c.PC) >= len(rom)\n}\n")
+}
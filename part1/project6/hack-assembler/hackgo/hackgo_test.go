@@ -0,0 +1,255 @@
+package hackgo
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math/rand"
+	"nand2tetris/hack-assembler/assembler"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Updates the golden files in testdata")
+
+// Assembles the program at path, keeping its labels
+func assemble(t *testing.T, path string) ([]string, map[string]int) {
+	t.Helper()
+
+	inFile, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inFile.Close()
+
+	code, labels, err := assembler.AssembleWithLabels(inFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code, labels
+}
+
+func TestTranslate(t *testing.T) {
+	code, labels := assemble(t, "../assembler/testdata/Max.asm")
+
+	var buf bytes.Buffer
+	if err := Translate(&buf, code, labels, "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile("testdata/Max.go", buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile("testdata/Max.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(want) {
+		t.Errorf("output differs from testdata/Max.go:\n%s", buf.String())
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code []string
+		err  string
+	}{
+		{"not binary", []string{"0000000000000002"}, `instruction 0: invalid instruction "0000000000000002"`},
+		{"too short", []string{"0101"}, `instruction 0: invalid instruction "0101"`},
+		{"no mnemonic", []string{"1110000001001000"}, "instruction 0: instruction \"1110000001001000\" has no computation mnemonic"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Translate(&bytes.Buffer{}, tt.code, nil, "main")
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// The test of a translated package, run by "go test" against the emulator
+const checkTest = `package %s
+
+import (
+	"math/rand"
+	"nand2tetris/hack-emulator/cpu"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for run := 0; run < 5; run++ {
+		ref, err := cpu.New(rom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c Computer
+		for adr := uint16(0); adr < 24; adr++ {
+			v := uint16(r.Intn(40))
+			c.Write(adr, v)
+			ref.Write(adr, v)
+		}
+
+		for i := 0; i < 40; i++ {
+			if i == 20 {
+				c.SetKey(75)
+				ref.SetKey(75)
+			}
+			n := 1 + r.Intn(60)
+			if i == 39 {
+				n = 100000
+			}
+
+			halted, want := c.Run(n), ref.Run(n)
+			if halted != want || c.A != ref.A || c.D != ref.D || c.PC != ref.PC || c.Cycles != ref.Cycles {
+				t.Fatalf("run %%d: halted, A, D, PC, cycles = %%v %%d %%d %%d %%d, want %%v %%d %%d %%d %%d",
+					run, halted, c.A, c.D, c.PC, c.Cycles, want, ref.A, ref.D, ref.PC, ref.Cycles)
+			}
+			if c.RAM != ref.RAM {
+				t.Fatalf("run %%d: RAM differs after %%d cycles", run, c.Cycles)
+			}
+		}
+	}
+}
+`
+
+// Returns a random assembly program, with labels to jump to directly, and
+// computed jumps landing anywhere
+func randomProgram(r *rand.Rand) string {
+	comps := []string{
+		"0", "1", "-1", "D", "A", "M", "!D", "!A", "!M", "-D", "-A", "-M",
+		"D+1", "A+1", "M+1", "D-1", "A-1", "M-1", "D+A", "D+M", "D-A", "D-M",
+		"A-D", "M-D", "D&A", "D&M", "D|A", "D|M",
+	}
+	dests := []string{"", "M=", "D=", "MD=", "A=", "AM=", "AD=", "AMD="}
+	jumps := []string{"", ";JGT", ";JEQ", ";JGE", ";JLT", ";JNE", ";JLE", ";JMP"}
+
+	size := 1 + r.Intn(48)
+	var src strings.Builder
+	for i := 0; i < size; i++ {
+		if r.Intn(8) == 0 {
+			fmt.Fprintf(&src, "(L%d)\n", i)
+		}
+		switch r.Intn(10) {
+		case 0, 1:
+			fmt.Fprintf(&src, "@%d\n", r.Intn(size+2))
+		case 2:
+			fmt.Fprintf(&src, "@%d\n", []int{0x4000, 0x5FFF, 0x6000, 0x6001, 0x7FFF}[r.Intn(5)])
+		case 3:
+			// A label, declared or not: "(Lx)" lines are placed at random
+			fmt.Fprintf(&src, "@%d\n", r.Intn(24))
+		default:
+			jump := ""
+			if r.Intn(3) == 0 {
+				jump = jumps[r.Intn(len(jumps))]
+			}
+			fmt.Fprintf(&src, "%s%s%s\n", dests[r.Intn(len(dests))], comps[r.Intn(len(comps))], jump)
+		}
+	}
+	if r.Intn(2) == 0 {
+		src.WriteString("(END)\n@END\n0;JMP\n")
+	}
+
+	return src.String()
+}
+
+// Translates the test programs and random ones, and checks them against the
+// emulator, with "go test" in a temporary module
+func TestRunDifferential(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds Go programs")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go command")
+	}
+	emulator, err := filepath.Abs("../../../project5/hack-emulator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(emulator, "go.mod")); err != nil {
+		t.Skip("no hack-emulator module")
+	}
+
+	dir := t.TempDir()
+	mod := fmt.Sprintf("module hackgotest\n\ngo 1.18\n\nrequire nand2tetris/hack-emulator v0.0.0\n\nreplace nand2tetris/hack-emulator => %s\n", emulator)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(mod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(pkg string, code []string, labels map[string]int) {
+		t.Helper()
+
+		var buf bytes.Buffer
+		if err := Translate(&buf, code, labels, pkg); err != nil {
+			t.Fatalf("%s: %s", pkg, err)
+		}
+		if err := os.Mkdir(filepath.Join(dir, pkg), 0755); err != nil {
+			t.Fatal(err)
+		}
+		files := map[string]string{pkg + ".go": buf.String()}
+		if pkg != "main" {
+			files["check_test.go"] = fmt.Sprintf(checkTest, pkg)
+		}
+		for name, src := range files {
+			if err := os.WriteFile(filepath.Join(dir, pkg, name), []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// The assembly programs with their labels, the others without
+	for _, name := range []string{"Add", "Max", "Rect"} {
+		code, labels := assemble(t, "../assembler/testdata/"+name+".asm")
+		write(strings.ToLower(name), code, labels)
+	}
+	for _, name := range []string{"Mult", "Fill"} {
+		data, err := os.ReadFile("../assembler/testdata/" + name + ".hack")
+		if err != nil {
+			t.Fatal(err)
+		}
+		write(strings.ToLower(name), strings.Fields(string(data)), nil)
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 24; i++ {
+		code, labels, err := assembler.AssembleWithLabels(strings.NewReader(randomProgram(r)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 0 {
+			labels = nil
+		}
+		write(fmt.Sprintf("random%d", i), code, labels)
+	}
+	code, labels := assemble(t, "../assembler/testdata/Max.asm")
+	write("main", code, labels)
+
+	run := func(args ...string) string {
+		t.Helper()
+
+		cmd := exec.Command(goTool, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOWORK=off")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("go %s: %s\n%s", strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+
+	run("vet", "./...")
+	run("test", "-vet=off", "./...")
+	if out, want := run("run", "./main", "0=3", "1=8"), "RAM[0] = 3\nRAM[1] = 8\nRAM[2] = 8\n"; out != want {
+		t.Errorf("Max output = %q, want %q", out, want)
+	}
+}
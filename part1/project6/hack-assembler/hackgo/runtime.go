@@ -0,0 +1,145 @@
+package hackgo
+
+// This file contains the code written as is in every translation: the
+// computer, and its interpreter for the instructions run outside of the
+// blocks.
+
+const runtime = `const (
+	ROMSize   = 32768
+	RAMSize   = 24577 // 16K of RAM, the 8K screen, and the keyboard
+	ScreenAdr = 16384
+	KbdAdr    = 24576
+)
+
+// The Hack computer running the program. Words are kept unsigned: arithmetic
+// wraps around as in the 16-bit hardware.
+type Computer struct {
+	RAM    [RAMSize]uint16
+	A, D   uint16
+	PC     uint16
+	Cycles int // Instructions executed
+}
+
+// Returns the word at address adr. Addresses past the keyboard read as 0.
+func (c *Computer) Read(adr uint16) uint16 {
+	if int(adr) < RAMSize {
+		return c.RAM[adr]
+	}
+
+	return 0
+}
+
+// Writes the word at address adr. The keyboard, and addresses past it, can't
+// be written.
+func (c *Computer) Write(adr uint16, v uint16) {
+	if adr < KbdAdr {
+		c.RAM[adr] = v
+	}
+}
+
+// Sets the key held down, as read at address KbdAdr. 0 means no key.
+func (c *Computer) SetKey(key uint16) {
+	c.RAM[KbdAdr] = key
+}
+
+// Executes one instruction
+func (c *Computer) Step() {
+	var in uint16
+	if int(c.PC) < len(rom) {
+		in = rom[c.PC]
+	}
+	c.Cycles++
+
+	// A instruction
+	if in&0x8000 == 0 {
+		c.A = in
+		c.PC++
+		return
+	}
+
+	adr := c.A
+	y := c.A
+	if in&0x1000 != 0 {
+		y = c.Read(adr)
+	}
+	out := ALU(c.D, y, in>>6)
+
+	if in&0x08 != 0 {
+		c.Write(adr, out)
+	}
+	if in&0x10 != 0 {
+		c.D = out
+	}
+	if in&0x20 != 0 {
+		c.A = out
+	}
+
+	neg, zero := int16(out) < 0, out == 0
+	if (in&0x04 != 0 && neg) || (in&0x02 != 0 && zero) || (in&0x01 != 0 && !neg && !zero) {
+		c.PC = adr
+	} else {
+		c.PC++
+	}
+}
+
+// Computes the ALU output for inputs x and y, the control bits being the 6
+// lowest bits of c: zx, nx, zy, ny, f, no
+func ALU(x, y uint16, c uint16) uint16 {
+	if c&0x20 != 0 {
+		x = 0
+	}
+	if c&0x10 != 0 {
+		x = ^x
+	}
+	if c&0x08 != 0 {
+		y = 0
+	}
+	if c&0x04 != 0 {
+		y = ^y
+	}
+
+	var out uint16
+	if c&0x02 != 0 {
+		out = x + y
+	} else {
+		out = x & y
+	}
+	if c&0x01 != 0 {
+		out = ^out
+	}
+
+	return out
+}
+`
+
+const mainFunc = `
+// Runs the program from the RAM words given as ADDRESS=VALUE arguments, and
+// prints the words of RAM that aren't 0 once it halts
+func main() {
+	cycles := flag.Int("cycles", 0, "Instructions run at most, 0 for no limit")
+	flag.Parse()
+
+	var c Computer
+	for _, arg := range flag.Args() {
+		adr, v, found := strings.Cut(arg, "=")
+		a, err := strconv.ParseUint(adr, 10, 16)
+		n, err2 := strconv.ParseInt(v, 0, 32)
+		if !found || err != nil || err2 != nil || a >= KbdAdr || n < -32768 || n > 65535 {
+			fmt.Fprintf(os.Stderr, "invalid RAM word %q, ADDRESS=VALUE expected\n", arg)
+			os.Exit(2)
+		}
+		c.RAM[a] = uint16(n)
+	}
+
+	halted := c.Run(*cycles)
+	for adr, v := range c.RAM {
+		if v != 0 {
+			fmt.Printf("RAM[%d] = %d\n", adr, int16(v))
+		}
+	}
+	if !halted {
+		fmt.Fprintf(os.Stderr, "no halt within %d cycles\n", *cycles)
+		os.Exit(1)
+	}
+}
+`
@@ -8,23 +8,12 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"nand2tetris/hack-assembler/assembler"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
-var (
-	varStore  = symbolStore{store: dataStore{}, used: 15} // variable memory starts at 16
-	jmpStore  = dataStore{}
-	compStore = dataStore{}
-)
-
-func init() {
-	// initialises data stores
-	varStore.populateBuiltinVars()
-	jmpStore.populateJMP()
-	compStore.populateCompPatterns()
-}
-
 func main() {
 	flag.Usage = func() {
 		fmt.Fprint(os.Stderr, helpMsg)
@@ -35,6 +24,26 @@ func main() {
 	flag.BoolVar(&verbose, "verbose", false, "Enables verbosity")
 	flag.BoolVar(&verbose, "v", false, "Enables verbosity")
 
+	var mem bool
+	flag.BoolVar(&mem, "mem", false, "Also writes a memory file for $readmemb")
+	flag.BoolVar(&mem, "m", false, "Also writes a memory file for $readmemb")
+
+	var object bool
+	flag.BoolVar(&object, "object", false, "Writes a relocatable object instead")
+	flag.BoolVar(&object, "c", false, "Writes a relocatable object instead")
+
+	var symbols bool
+	flag.BoolVar(&symbols, "symbols", false, "Also writes the labels to a symbol file")
+	flag.BoolVar(&symbols, "s", false, "Also writes the labels to a symbol file")
+
+	var lint bool
+	flag.BoolVar(&lint, "lint", false, "Reports hazards instead of assembling")
+	flag.BoolVar(&lint, "l", false, "Reports hazards instead of assembling")
+
+	var disable string
+	flag.StringVar(&disable, "disable", "", "Comma-separated lint rules to skip")
+	flag.StringVar(&disable, "d", "", "Comma-separated lint rules to skip")
+
 	flag.Parse()
 
 	if flag.NArg() != 1 {
@@ -47,76 +56,157 @@ func main() {
 	}
 	// Build the output file name
 	outPath := strings.Split(inPath, ".asm")[0] + ".hack"
+	if object {
+		if mem {
+			log.Fatalln("[!] Error: -m/--mem takes a program, not an object")
+		}
+		outPath = strings.Split(inPath, ".asm")[0] + ".hobj"
+	}
 
-	// Using array allows convenient access to the "instruction - instruction
-	// number" mapping, and the "total instructions" count. This array will be
-	// processed and all comments will be removed. Once done so, the array
-	// element index == instruction number, and array element value == assembly
-	// instruction.
-	var inLines []string
 	if verbose {
 		log.Printf("[i] Reading from assembly file %q\n", inPath)
 	}
-	readInput(&inLines, inPath)
+	inFile, err := os.Open(inPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to open %q: %s", inPath, err)
+	}
+	defer inFile.Close()
 
-	if verbose {
-		log.Printf("[i] %d lines read after ignoring comments\n", len(inLines))
-		log.Println("[i] Beginning assembly process")
+	if lint {
+		lintFile(inFile, inPath, disable)
+		return
+	}
+
+	if object {
+		writeObject(inFile, inPath, outPath, verbose)
+		return
 	}
 
-	var outLines []string
-	assemble(&inLines, &outLines)
+	outLines, labels, err := assembler.AssembleWithLabels(inFile)
+	if err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
 
 	if verbose {
-		log.Printf("[i] %d/%d lines assembled\n", len(outLines), len(inLines))
+		log.Printf("[i] %d instructions assembled\n", len(outLines))
 		log.Printf("[i] Writing output to %q\n", outPath)
 	}
 	writeOutput(&outLines, outPath)
 
+	if mem {
+		memPath := strings.TrimSuffix(outPath, ".hack") + ".mem"
+		if verbose {
+			log.Printf("[i] Writing memory file to %q\n", memPath)
+		}
+		writeMemFile(&outLines, inPath, memPath)
+	}
+
+	if symbols {
+		symPath := strings.TrimSuffix(outPath, ".hack") + ".sym"
+		if verbose {
+			log.Printf("[i] Writing %d labels to %q\n", len(labels), symPath)
+		}
+		writeSymbols(labels, symPath)
+	}
+
 	log.Printf("[i] Assembly output to %q successful\n", outPath)
 }
 
-// Read the contents of inPath line-by-line and store each line as an array
-// element of buffer
-func readInput(buf *[]string, inPath string) {
-	inFile, err := os.Open(inPath)
+// Write the contents of buf to outPath line-by-line
+func writeOutput(buf *[]string, outPath string) {
+	outFile, err := os.Create(outPath)
 	if err != nil {
-		log.Fatalf("[!] Error: Unable to open %q: %s", inPath, err)
+		log.Fatalf("[!] Error: Unable to create %q: %s", outPath, err)
 	}
-	defer inFile.Close()
+	defer outFile.Close()
 
-	scanner := bufio.NewScanner(inFile)
+	for _, code := range *buf {
+		outFile.WriteString(code + "\n")
+	}
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = removeInlineComment(line)
-		line = strings.TrimSpace(line)
+// Write labels to symPath, for the tools working on machine language
+func writeSymbols(labels map[string]int, symPath string) {
+	symFile, err := os.Create(symPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to create %q: %s", symPath, err)
+	}
+	defer symFile.Close()
 
-		// Entire line is comment or white space, don't add to buffer
-		if len(line) == 0 {
-			continue
-		}
+	if err := assembler.WriteSymbols(symFile, labels); err != nil {
+		log.Fatalf("[!] Error: Unable to write %q: %s", symPath, err)
+	}
+}
 
-		if strings.HasPrefix(line, "(") && strings.HasSuffix(line, ")") {
-			// Line is a jump label. Process it and don't add to instruction
-			// list
-			processLabel(buf, line)
-			continue
-		}
+// Lint inFile, and exit with status 1 if any hazard is found
+func lintFile(inFile *os.File, inPath string, disable string) {
+	var disabled []string
+	if disable != "" {
+		disabled = strings.Split(disable, ",")
+	}
 
-		*buf = append(*buf, line)
+	hazards, err := assembler.Lint(inFile, disabled)
+	if err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
+
+	for _, h := range hazards {
+		log.Printf("[!] %s:%d: %s (%s)", inPath, h.Line, h.Msg, h.Rule)
+	}
+	if len(hazards) != 0 {
+		log.Fatalf("[!] Error: %d hazard(s) found", len(hazards))
 	}
+
+	log.Printf("[i] No hazard found in %q\n", inPath)
 }
 
-// Write the contents of buf to outPath line-by-line
-func writeOutput(buf *[]string, outPath string) {
+// Assemble inFile into a relocatable object, written to outPath
+func writeObject(inFile *os.File, inPath string, outPath string, verbose bool) {
+	obj, err := assembler.AssembleObject(inFile, filepath.Base(inPath))
+	if err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
+
+	if verbose {
+		log.Printf("[i] %d instructions assembled, %d references left to the linker\n", len(obj.Code), len(obj.Refs))
+		log.Printf("[i] Writing object to %q\n", outPath)
+	}
 	outFile, err := os.Create(outPath)
 	if err != nil {
 		log.Fatalf("[!] Error: Unable to create %q: %s", outPath, err)
 	}
 	defer outFile.Close()
 
+	if err := obj.Write(outFile); err != nil {
+		log.Fatalf("[!] Error: Unable to write %q: %s", outPath, err)
+	}
+
+	log.Printf("[i] Object output to %q successful\n", outPath)
+}
+
+// Write the contents of buf to memPath as a memory file for Verilog's
+// $readmemb, padded with zeros to fill ROM32K
+func writeMemFile(buf *[]string, inPath string, memPath string) {
+	if len(*buf) > romSize {
+		log.Fatalf("[!] Error: %d instructions don't fit in the %d words of ROM32K", len(*buf), romSize)
+	}
+
+	memFile, err := os.Create(memPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to create %q: %s", memPath, err)
+	}
+	defer memFile.Close()
+
+	out := bufio.NewWriter(memFile)
+	fmt.Fprintf(out, "// %s, %d instruction(s), for $readmemb\n", inPath, len(*buf))
 	for _, code := range *buf {
-		outFile.WriteString(code + "\n")
+		out.WriteString(code + "\n")
+	}
+	for i := len(*buf); i < romSize; i++ {
+		out.WriteString(strings.Repeat("0", 16) + "\n")
+	}
+
+	if err := out.Flush(); err != nil {
+		log.Fatalf("[!] Error: Unable to write %q: %s", memPath, err)
 	}
 }
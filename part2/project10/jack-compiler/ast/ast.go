@@ -0,0 +1,162 @@
+// Package ast holds the typed syntax tree of a Jack class, as built by the
+// parser. Every node records the line it starts on; the file is recorded once,
+// in the class.
+package ast
+
+// A Jack class, the contents of one .jack file
+type Class struct {
+	File string
+	Name string
+	Line int
+	Vars []*ClassVarDec
+	Subs []*Subroutine
+}
+
+// "static" or "field" variables, e.g. "field int x, y;"
+type ClassVarDec struct {
+	Kind  string // "static" or "field"
+	Type  string // "int", "char", "boolean" or a class name
+	Names []string
+	Line  int
+}
+
+type Subroutine struct {
+	Kind   string // "constructor", "function" or "method"
+	Return string // "void", "int", "char", "boolean" or a class name
+	Name   string
+	Params []*Param
+	Locals []*VarDec
+	Body   []Stmt
+	Line   int
+	End    int // Line of the closing "}"
+}
+
+type Param struct {
+	Type string
+	Name string
+	Line int
+}
+
+// Local variables, e.g. "var Array a, b;"
+type VarDec struct {
+	Type  string
+	Names []string
+	Line  int
+}
+
+// Any statement
+type Stmt interface {
+	StmtLine() int
+}
+
+// "let x = e;" or "let x[i] = e;"
+type LetStmt struct {
+	Name  string
+	Index Expr // nil without an index
+	Value Expr
+	Line  int
+}
+
+type IfStmt struct {
+	Cond    Expr
+	Then    []Stmt
+	HasElse bool
+	Else    []Stmt
+	Line    int
+}
+
+type WhileStmt struct {
+	Cond Expr
+	Body []Stmt
+	Line int
+}
+
+type DoStmt struct {
+	Call *CallExpr
+	Line int
+}
+
+type ReturnStmt struct {
+	Value Expr // nil in void subroutines
+	Line  int
+}
+
+func (s *LetStmt) StmtLine() int    { return s.Line }
+func (s *IfStmt) StmtLine() int     { return s.Line }
+func (s *WhileStmt) StmtLine() int  { return s.Line }
+func (s *DoStmt) StmtLine() int     { return s.Line }
+func (s *ReturnStmt) StmtLine() int { return s.Line }
+
+// Any expression. Jack has no operator precedence: "a + b * c" is
+// BinaryExpr{BinaryExpr{a + b} * c}.
+type Expr interface {
+	ExprLine() int
+}
+
+type IntConst struct {
+	Value int
+	Line  int
+}
+
+type StringConst struct {
+	Value string
+	Line  int
+}
+
+// "true", "false", "null" or "this"
+type KeywordConst struct {
+	Value string
+	Line  int
+}
+
+// A variable, e.g. "x"
+type VarRef struct {
+	Name string
+	Line int
+}
+
+// An array element, e.g. "a[i]"
+type IndexExpr struct {
+	Name  string
+	Index Expr
+	Line  int
+}
+
+// A subroutine call: "f(...)", "x.f(...)" or "Class.f(...)"
+type CallExpr struct {
+	Receiver string // Variable or class name before the ".", empty if none
+	Name     string
+	Args     []Expr
+	Line     int
+}
+
+// An expression in parentheses, kept to reproduce the parse tree
+type ParenExpr struct {
+	Inner Expr
+	Line  int
+}
+
+// "-x" or "~x"
+type UnaryExpr struct {
+	Op      string
+	Operand Expr
+	Line    int
+}
+
+// "x op y", op being one of "+-*/&|<>="
+type BinaryExpr struct {
+	Op    string
+	Left  Expr
+	Right Expr
+	Line  int
+}
+
+func (e *IntConst) ExprLine() int     { return e.Line }
+func (e *StringConst) ExprLine() int  { return e.Line }
+func (e *KeywordConst) ExprLine() int { return e.Line }
+func (e *VarRef) ExprLine() int       { return e.Line }
+func (e *IndexExpr) ExprLine() int    { return e.Line }
+func (e *CallExpr) ExprLine() int     { return e.Line }
+func (e *ParenExpr) ExprLine() int    { return e.Line }
+func (e *UnaryExpr) ExprLine() int    { return e.Line }
+func (e *BinaryExpr) ExprLine() int   { return e.Line }
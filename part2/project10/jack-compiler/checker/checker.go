@@ -0,0 +1,479 @@
+// Package checker finds the semantic errors of a Jack program, which the
+// compiler itself lets through: calls to missing subroutines or with the wrong
+// number or types of arguments, undeclared variables, unreachable code and
+// missing returns. The classes of a program are checked together, along with
+// the declarations of the Jack OS.
+package checker
+
+import (
+	"fmt"
+	"nand2tetris/jack-compiler/ast"
+	"nand2tetris/jack-compiler/compiler"
+	"strings"
+)
+
+// A semantic error in a class
+type Problem struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Msg)
+}
+
+// Checks the classes of a program. Classes of the program replace the OS
+// classes of the same name, e.g. when writing the OS itself.
+func Check(classes []*ast.Class) []Problem {
+	c := checker{classes: map[string]*ast.Class{}, subs: map[string]map[string]*ast.Subroutine{}}
+
+	for name, class := range osClasses {
+		c.declare(class)
+		c.classes[name] = class
+	}
+	declared := map[string]*ast.Class{}
+	for _, class := range classes {
+		if prev, found := declared[class.Name]; found {
+			c.class = class
+			c.report(class.Line, "class %s is already declared in %s", class.Name, prev.File)
+			continue
+		}
+		declared[class.Name] = class
+		c.classes[class.Name] = class
+		c.declare(class)
+	}
+
+	for _, class := range classes {
+		if declared[class.Name] == class {
+			c.checkClass(class)
+		}
+	}
+
+	return c.problems
+}
+
+// Jack's primitive types, which can be mixed freely
+var primitives = map[string]bool{"int": true, "char": true, "boolean": true}
+
+// Types are "int", "char", "boolean", class names, "void" for the value of void
+// subroutines, "null", or "" when unknown, e.g. for array elements. Unknown
+// types match any other.
+type checker struct {
+	classes  map[string]*ast.Class
+	subs     map[string]map[string]*ast.Subroutine // By class, then name
+	class    *ast.Class
+	sub      *ast.Subroutine
+	fields   *compiler.SymbolTable
+	locals   *compiler.SymbolTable
+	problems []Problem
+}
+
+func (c *checker) report(line int, format string, a ...interface{}) {
+	c.problems = append(c.problems, Problem{File: c.class.File, Line: line, Msg: fmt.Sprintf(format, a...)})
+}
+
+// Records the subroutines of a class. Duplicates are reported when checking
+// the class.
+func (c *checker) declare(class *ast.Class) {
+	subs := map[string]*ast.Subroutine{}
+	for _, sub := range class.Subs {
+		if _, found := subs[sub.Name]; !found {
+			subs[sub.Name] = sub
+		}
+	}
+	c.subs[class.Name] = subs
+}
+
+func (c *checker) checkClass(class *ast.Class) {
+	c.class = class
+	c.fields = compiler.NewSymbolTable()
+
+	for _, dec := range class.Vars {
+		c.checkType(dec.Type, dec.Line)
+		kind := compiler.Static
+		if dec.Kind == "field" {
+			kind = compiler.Field
+		}
+		for _, name := range dec.Names {
+			if !c.fields.Define(name, dec.Type, kind, dec.Line) {
+				c.report(dec.Line, "%s is already declared", name)
+			}
+		}
+	}
+
+	for _, sub := range class.Subs {
+		if c.subs[class.Name][sub.Name] != sub {
+			c.report(sub.Line, "subroutine %s.%s is already declared", class.Name, sub.Name)
+		}
+		c.checkSubroutine(sub)
+	}
+}
+
+func (c *checker) checkType(typ string, line int) {
+	if !primitives[typ] && c.classes[typ] == nil {
+		c.report(line, "unknown type %s", typ)
+	}
+}
+
+func (c *checker) checkSubroutine(sub *ast.Subroutine) {
+	c.sub = sub
+	c.locals = compiler.NewSymbolTable()
+
+	if sub.Return != "void" {
+		c.checkType(sub.Return, sub.Line)
+	}
+	if sub.Kind == "constructor" && sub.Return != c.class.Name {
+		c.report(sub.Line, "constructor %s must return %s", c.name(sub), c.class.Name)
+	}
+
+	for _, p := range sub.Params {
+		c.checkType(p.Type, p.Line)
+		if !c.locals.Define(p.Name, p.Type, compiler.Arg, p.Line) {
+			c.report(p.Line, "%s is already declared", p.Name)
+		}
+	}
+	for _, dec := range sub.Locals {
+		c.checkType(dec.Type, dec.Line)
+		for _, name := range dec.Names {
+			if !c.locals.Define(name, dec.Type, compiler.Var, dec.Line) {
+				c.report(dec.Line, "%s is already declared", name)
+			}
+		}
+	}
+
+	if c.statements(sub.Body) {
+		c.report(sub.End, "missing return at the end of %s", c.name(sub))
+	}
+}
+
+// Returns the full name of a subroutine of the current class, e.g. "Main.main"
+func (c *checker) name(sub *ast.Subroutine) string {
+	return c.class.Name + "." + sub.Name
+}
+
+// Returns the variable called name, reporting it if it's undeclared or can't
+// be used in the current subroutine
+func (c *checker) lookup(name string, line int) *compiler.Symbol {
+	sym := c.locals.Lookup(name)
+	if sym == nil {
+		sym = c.fields.Lookup(name)
+	}
+
+	switch {
+	case sym == nil:
+		c.report(line, "undeclared variable %s", name)
+	case sym.Kind == compiler.Field && c.sub.Kind == "function":
+		c.report(line, "field %s used in function %s", name, c.name(c.sub))
+	}
+
+	return sym
+}
+
+// Checks statements, and returns whether their end can be reached. Code after
+// a return, or after an if whose branches both return, or after "while (true)"
+// can't: Jack has no break. A last return is not reported, as Jack requires
+// one at the end of every subroutine, e.g. after the endless loop of Sys.init.
+func (c *checker) statements(stmts []ast.Stmt) bool {
+	end, reported := true, false
+
+	for i, stmt := range stmts {
+		_, isReturn := stmt.(*ast.ReturnStmt)
+		if !end && !reported && !(isReturn && i == len(stmts)-1) {
+			c.report(stmt.StmtLine(), "unreachable code")
+			reported = true
+		}
+		if !c.statement(stmt) {
+			end = false
+		}
+	}
+
+	return end
+}
+
+// Checks a statement, and returns whether the next one can be reached from it
+func (c *checker) statement(stmt ast.Stmt) bool {
+	switch s := stmt.(type) {
+	case *ast.LetStmt:
+		sym := c.lookup(s.Name, s.Line)
+		if s.Index != nil {
+			if sym != nil {
+				c.checkArray(sym, s.Line)
+			}
+			c.checkOperand(c.expression(s.Index), "[]", s.Line)
+			c.expression(s.Value)
+			return true
+		}
+
+		typ := c.expression(s.Value)
+		if sym != nil && !assignable(typ, sym.Type) {
+			c.report(s.Line, "can't assign %s to %s, of type %s", article(typ), s.Name, sym.Type)
+		}
+
+	case *ast.IfStmt:
+		c.checkCondition(s.Cond)
+		then := c.statements(s.Then)
+		if s.HasElse {
+			return c.statements(s.Else) || then
+		}
+
+	case *ast.WhileStmt:
+		c.checkCondition(s.Cond)
+		c.statements(s.Body)
+		return !isTrue(s.Cond)
+
+	case *ast.DoStmt:
+		c.call(s.Call)
+
+	case *ast.ReturnStmt:
+		switch {
+		case s.Value == nil && c.sub.Return != "void":
+			c.report(s.Line, "%s must return %s", c.name(c.sub), article(c.sub.Return))
+		case s.Value != nil && c.sub.Return == "void":
+			c.expression(s.Value)
+			c.report(s.Line, "%s is void and can't return a value", c.name(c.sub))
+		case s.Value != nil:
+			if typ := c.expression(s.Value); !assignable(typ, c.sub.Return) {
+				c.report(s.Line, "%s must return %s, not %s", c.name(c.sub), article(c.sub.Return), article(typ))
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func isTrue(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.KeywordConst:
+		return e.Value == "true"
+	case *ast.ParenExpr:
+		return isTrue(e.Inner)
+	}
+
+	return false
+}
+
+func (c *checker) checkCondition(cond ast.Expr) {
+	if typ := c.expression(cond); isObject(typ) {
+		c.report(cond.ExprLine(), "condition is %s, not a boolean", article(typ))
+	}
+}
+
+func (c *checker) checkArray(sym *compiler.Symbol, line int) {
+	if sym.Type != "Array" {
+		c.report(line, "%s is %s, not an Array", sym.Name, article(sym.Type))
+	}
+}
+
+// Reports objects used with arithmetic or logic operators. Arrays are allowed:
+// they are often used as plain addresses.
+func (c *checker) checkOperand(typ string, op string, line int) {
+	if isObject(typ) && typ != "Array" && typ != "null" {
+		c.report(line, "operator %s used on %s", op, article(typ))
+	}
+}
+
+// Checks an expression, and returns its type
+func (c *checker) expression(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.IntConst:
+		return "int"
+
+	case *ast.StringConst:
+		return "String"
+
+	case *ast.KeywordConst:
+		switch e.Value {
+		case "true", "false":
+			return "boolean"
+		case "null":
+			return "null"
+		}
+		if c.sub.Kind == "function" {
+			c.report(e.Line, "this used in function %s", c.name(c.sub))
+			return ""
+		}
+		return c.class.Name
+
+	case *ast.VarRef:
+		if sym := c.lookup(e.Name, e.Line); sym != nil {
+			return sym.Type
+		}
+
+	case *ast.IndexExpr:
+		if sym := c.lookup(e.Name, e.Line); sym != nil {
+			c.checkArray(sym, e.Line)
+		}
+		c.checkOperand(c.expression(e.Index), "[]", e.Line)
+
+	case *ast.CallExpr:
+		typ := c.call(e)
+		if typ == "void" {
+			name := e.Name
+			if e.Receiver != "" {
+				name = e.Receiver + "." + name
+			}
+			c.report(e.Line, "%s is void and has no value", name)
+			return ""
+		}
+		return typ
+
+	case *ast.ParenExpr:
+		return c.expression(e.Inner)
+
+	case *ast.UnaryExpr:
+		typ := c.expression(e.Operand)
+		c.checkOperand(typ, e.Op, e.Line)
+		if e.Op == "~" && typ == "boolean" {
+			return typ
+		}
+		return "int"
+
+	case *ast.BinaryExpr:
+		left, right := c.expression(e.Left), c.expression(e.Right)
+		switch e.Op {
+		case "=":
+			if !assignable(left, right) && !assignable(right, left) {
+				c.report(e.Line, "comparing %s with %s", article(left), article(right))
+			}
+			return "boolean"
+		case "<", ">":
+			c.checkOperand(left, e.Op, e.Line)
+			c.checkOperand(right, e.Op, e.Line)
+			return "boolean"
+		case "&", "|":
+			c.checkOperand(left, e.Op, e.Line)
+			c.checkOperand(right, e.Op, e.Line)
+			if left == "boolean" && right == "boolean" {
+				return "boolean"
+			}
+			return "int"
+		default:
+			c.checkOperand(left, e.Op, e.Line)
+			c.checkOperand(right, e.Op, e.Line)
+			return "int"
+		}
+	}
+
+	return ""
+}
+
+// Checks a call, and returns the type of its value. "f()" calls method f on
+// this object, "x.f()" method f of the class of variable x, and "C.f()"
+// function (or constructor) f of class C.
+func (c *checker) call(call *ast.CallExpr) string {
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		args[i] = c.expression(arg)
+	}
+
+	var class string
+	var sub *ast.Subroutine
+
+	switch sym := c.findVariable(call.Receiver); {
+	case call.Receiver == "":
+		class, sub = c.class.Name, c.subs[c.class.Name][call.Name]
+		if sub == nil {
+			c.report(call.Line, "undeclared subroutine %s.%s", class, call.Name)
+			return ""
+		}
+		if sub.Kind != "method" {
+			c.report(call.Line, "%s %s.%s must be called as %s.%s()", sub.Kind, class, sub.Name, class, sub.Name)
+		} else if c.sub.Kind == "function" {
+			c.report(call.Line, "method %s.%s called from function %s", class, sub.Name, c.name(c.sub))
+		}
+
+	case sym != nil:
+		c.lookup(sym.Name, call.Line)
+		class = sym.Type
+		if primitives[class] {
+			c.report(call.Line, "%s is %s, not an object", sym.Name, article(class))
+			return ""
+		}
+		if c.classes[class] == nil {
+			// The unknown type is reported with the variable
+			return ""
+		}
+		if sub = c.subs[class][call.Name]; sub == nil {
+			c.report(call.Line, "undeclared subroutine %s.%s", class, call.Name)
+			return ""
+		}
+		if sub.Kind != "method" {
+			c.report(call.Line, "%s %s.%s called on object %s", sub.Kind, class, sub.Name, sym.Name)
+		}
+
+	default:
+		class = call.Receiver
+		if c.classes[class] == nil {
+			c.report(call.Line, "undeclared class or variable %s", class)
+			return ""
+		}
+		if sub = c.subs[class][call.Name]; sub == nil {
+			c.report(call.Line, "undeclared subroutine %s.%s", class, call.Name)
+			return ""
+		}
+		if sub.Kind == "method" {
+			c.report(call.Line, "method %s.%s called without an object", class, sub.Name)
+		}
+	}
+
+	if len(args) != len(sub.Params) {
+		c.report(call.Line, "%s.%s takes %d argument(s), not %d", class, sub.Name, len(sub.Params), len(args))
+		return sub.Return
+	}
+	for i, p := range sub.Params {
+		if !assignable(args[i], p.Type) {
+			c.report(call.Line, "argument %d of %s.%s must be %s, not %s", i+1, class, sub.Name, article(p.Type), article(args[i]))
+		}
+	}
+
+	return sub.Return
+}
+
+// Returns the variable called name without reporting anything, as a
+// receiver may be a class name
+func (c *checker) findVariable(name string) *compiler.Symbol {
+	if name == "" {
+		return nil
+	}
+	if sym := c.locals.Lookup(name); sym != nil {
+		return sym
+	}
+
+	return c.fields.Lookup(name)
+}
+
+func isObject(typ string) bool {
+	return typ != "" && !primitives[typ]
+}
+
+// Returns whether a value of type from can be stored in a variable of type to.
+// Primitive types mix, null is any object (or 0), and Arrays are untyped
+// pointers which mix with anything.
+func assignable(from, to string) bool {
+	switch {
+	case from == "" || to == "" || from == to:
+		return true
+	case primitives[from] && primitives[to]:
+		return true
+	case from == "null" || from == "Array" || to == "Array":
+		return true
+	}
+
+	return false
+}
+
+// Returns a type with an article for messages, e.g. "an int"
+func article(typ string) string {
+	switch {
+	case typ == "":
+		return "unknown"
+	case typ == "null" || typ == "void":
+		return typ
+	case strings.ContainsRune("aeiouAEIOU", rune(typ[0])):
+		return "an " + typ
+	}
+
+	return "a " + typ
+}
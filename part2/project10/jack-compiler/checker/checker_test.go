@@ -0,0 +1,199 @@
+package checker
+
+import (
+	"nand2tetris/jack-compiler/ast"
+	"nand2tetris/jack-compiler/parser"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Checks the classes in srcs, named File0.jack, File1.jack...
+func check(t *testing.T, srcs ...string) []string {
+	t.Helper()
+
+	var classes []*ast.Class
+	for i, src := range srcs {
+		class, err := parser.Parse("File"+string(rune('0'+i))+".jack", src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		classes = append(classes, class)
+	}
+
+	var got []string
+	for _, p := range Check(classes) {
+		got = append(got, p.String())
+	}
+
+	return got
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		srcs []string
+		want []string
+	}{
+		{
+			name: "calls across classes",
+			srcs: []string{`class Main {
+  function void main() {
+    var Point p;
+    let p = Point.new(1, 2);
+    do p.move(3);
+    do p.move(1, 2);
+    do Point.move(1);
+    do p.new(1, 2);
+    do p.jump();
+    do Pont.new(1, 2);
+    do Point.new(p, 2);
+    return;
+  }
+}`, `class Point {
+  field int x, y;
+  constructor Point new(int ax, int ay) { let x = ax; let y = ay; return this; }
+  method void move(int dx) { let x = x + dx; return; }
+}`},
+			want: []string{
+				"File0.jack:6: Point.move takes 1 argument(s), not 2",
+				"File0.jack:7: method Point.move called without an object",
+				"File0.jack:8: constructor Point.new called on object p",
+				"File0.jack:9: undeclared subroutine Point.jump",
+				"File0.jack:10: undeclared class or variable Pont",
+				"File0.jack:11: argument 1 of Point.new must be an int, not a Point",
+			},
+		},
+		{
+			name: "unreachable code and missing returns",
+			srcs: []string{`class A {
+  function int f(int x) {
+    if (x > 0) { return 1; } else { return 2; }
+    let x = 1;
+    return x;
+  }
+  function int g(int x) {
+    if (x > 0) { return 1; }
+  }
+  function void h() {
+    while (true) { }
+    return;
+  }
+  function void i() {
+    do A.h();
+  }
+}`},
+			want: []string{
+				"File0.jack:4: unreachable code",
+				"File0.jack:9: missing return at the end of A.g",
+				"File0.jack:16: missing return at the end of A.i",
+			},
+		},
+		{
+			name: "variables",
+			srcs: []string{`class A {
+  field int n;
+  static String s;
+  function void f(int a, int a) {
+    var char c;
+    let n = 1;
+    let b = c;
+    let c = s[1];
+    do g();
+    return;
+  }
+  method void g() { return; }
+}`},
+			want: []string{
+				"File0.jack:4: a is already declared",
+				"File0.jack:6: field n used in function A.f",
+				"File0.jack:7: undeclared variable b",
+				"File0.jack:8: s is a String, not an Array",
+				"File0.jack:9: method A.g called from function A.f",
+			},
+		},
+		{
+			name: "types",
+			srcs: []string{`class A {
+  field Array a;
+  field Thing t;
+  method boolean f(String s) {
+    var int n;
+    let n = s;
+    let n = a[2] + s;
+    let a = n;
+    let s = null;
+    if (s) { return Output.printInt(n); }
+    if (s = 1) { return false; }
+    return n;
+  }
+  method int g() { return; }
+  method void h() { return 1; }
+  constructor B new() { return this; }
+}`},
+			want: []string{
+				"File0.jack:3: unknown type Thing",
+				"File0.jack:6: can't assign a String to n, of type int",
+				"File0.jack:7: operator + used on a String",
+				"File0.jack:10: condition is a String, not a boolean",
+				"File0.jack:10: Output.printInt is void and has no value",
+				"File0.jack:11: comparing a String with an int",
+				"File0.jack:14: A.g must return an int",
+				"File0.jack:15: A.h is void and can't return a value",
+				"File0.jack:16: unknown type B",
+				"File0.jack:16: constructor A.new must return A",
+				"File0.jack:16: A.new must return a B, not an A",
+			},
+		},
+		{
+			name: "duplicates",
+			srcs: []string{
+				"class A {\n function void f() { return; }\n function void f() { return; }\n}",
+				"class A {\n}",
+			},
+			want: []string{
+				"File1.jack:1: class A is already declared in File0.jack",
+				"File0.jack:3: subroutine A.f is already declared",
+			},
+		},
+		{
+			name: "OS classes replaced",
+			srcs: []string{`class Math {
+  function int abs(int x, int y) { return x; }
+  function void main() { do Math.abs(1, 2); do Math.sqrt(4); return; }
+}`},
+			want: []string{
+				"File0.jack:3: undeclared subroutine Math.sqrt",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := check(t, test.srcs...)
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckSquare(t *testing.T) {
+	paths, _ := filepath.Glob("../testdata/Square/*.jack")
+	if len(paths) == 0 {
+		t.Fatal("no test classes")
+	}
+
+	var classes []*ast.Class
+	for _, path := range paths {
+		class, err := parser.ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		classes = append(classes, class)
+	}
+
+	for _, p := range Check(classes) {
+		t.Errorf("unexpected problem %s", p)
+	}
+}
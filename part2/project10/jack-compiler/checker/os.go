@@ -0,0 +1,86 @@
+package checker
+
+// This file declares the subroutines of the Jack OS, so that calls to them are
+// checked like calls to the program's own classes.
+
+import (
+	"nand2tetris/jack-compiler/ast"
+	"nand2tetris/jack-compiler/parser"
+)
+
+var osAPI = []string{`class Math {
+	function void init() {}
+	function int abs(int x) {}
+	function int multiply(int x, int y) {}
+	function int divide(int x, int y) {}
+	function int min(int x, int y) {}
+	function int max(int x, int y) {}
+	function int sqrt(int x) {}
+}`, `class String {
+	constructor String new(int maxLength) {}
+	method void dispose() {}
+	method int length() {}
+	method char charAt(int j) {}
+	method void setCharAt(int j, char c) {}
+	method String appendChar(char c) {}
+	method void eraseLastChar() {}
+	method int intValue() {}
+	method void setInt(int j) {}
+	function char backSpace() {}
+	function char doubleQuote() {}
+	function char newLine() {}
+}`, `class Array {
+	function Array new(int size) {}
+	method void dispose() {}
+}`, `class Output {
+	function void init() {}
+	function void moveCursor(int i, int j) {}
+	function void printChar(char c) {}
+	function void printString(String s) {}
+	function void printInt(int i) {}
+	function void println() {}
+	function void backSpace() {}
+}`, `class Screen {
+	function void init() {}
+	function void clearScreen() {}
+	function void setColor(boolean b) {}
+	function void drawPixel(int x, int y) {}
+	function void drawLine(int x1, int y1, int x2, int y2) {}
+	function void drawRectangle(int x1, int y1, int x2, int y2) {}
+	function void drawCircle(int x, int y, int r) {}
+}`, `class Keyboard {
+	function void init() {}
+	function char keyPressed() {}
+	function char readChar() {}
+	function String readLine(String message) {}
+	function int readInt(String message) {}
+}`, `class Memory {
+	function void init() {}
+	function int peek(int address) {}
+	function void poke(int address, int value) {}
+	function Array alloc(int size) {}
+	function void deAlloc(Array o) {}
+}`, `class Sys {
+	function void init() {}
+	function void halt() {}
+	function void error(int errorCode) {}
+	function void wait(int duration) {}
+}`,
+}
+
+// The OS classes, by name
+var osClasses = parseOS()
+
+func parseOS() map[string]*ast.Class {
+	classes := map[string]*ast.Class{}
+
+	for _, src := range osAPI {
+		class, err := parser.Parse("<os>", src)
+		if err != nil {
+			panic(err)
+		}
+		classes[class.Name] = class
+	}
+
+	return classes
+}
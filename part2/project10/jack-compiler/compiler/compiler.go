@@ -0,0 +1,321 @@
+// Package compiler generates the VM code of Jack classes, in the format read
+// by vm-translator.
+package compiler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"nand2tetris/jack-compiler/ast"
+	"strconv"
+)
+
+// Returned when a class can't be compiled, e.g. because of an undeclared
+// variable
+type CompileError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Writes the VM code of class to w
+func Compile(class *ast.Class, w io.Writer) error {
+	c := compiler{class: class, out: bufio.NewWriter(w), symbols: NewSymbolTable()}
+
+	for _, dec := range class.Vars {
+		kind := Static
+		if dec.Kind == "field" {
+			kind = Field
+		}
+		for _, name := range dec.Names {
+			if !c.symbols.Define(name, dec.Type, kind, dec.Line) {
+				return c.errorf(dec.Line, "%s is already declared", name)
+			}
+		}
+	}
+
+	for _, sub := range class.Subs {
+		if err := c.subroutine(sub); err != nil {
+			return err
+		}
+	}
+
+	return c.out.Flush()
+}
+
+type compiler struct {
+	class   *ast.Class
+	out     *bufio.Writer
+	symbols *SymbolTable // Statics and fields
+	scope   scope
+	labels  int // Labels generated in the current subroutine
+}
+
+func (c *compiler) errorf(line int, format string, a ...interface{}) error {
+	return &CompileError{File: c.class.File, Line: line, Msg: fmt.Sprintf(format, a...)}
+}
+
+func (c *compiler) emit(format string, a ...interface{}) {
+	fmt.Fprintf(c.out, format+"\n", a...)
+}
+
+func (c *compiler) subroutine(sub *ast.Subroutine) error {
+	locals := NewSymbolTable()
+	c.scope = scope{class: c.symbols, sub: locals}
+	c.labels = 0
+
+	// Methods receive the object as argument 0
+	if sub.Kind == "method" {
+		locals.Define("this", c.class.Name, Arg, sub.Line)
+	}
+	for _, p := range sub.Params {
+		if !locals.Define(p.Name, p.Type, Arg, p.Line) {
+			return c.errorf(p.Line, "%s is already declared", p.Name)
+		}
+	}
+	for _, dec := range sub.Locals {
+		for _, name := range dec.Names {
+			if !locals.Define(name, dec.Type, Var, dec.Line) {
+				return c.errorf(dec.Line, "%s is already declared", name)
+			}
+		}
+	}
+
+	c.emit("function %s.%s %d", c.class.Name, sub.Name, locals.Count(Var))
+
+	switch sub.Kind {
+	case "constructor":
+		c.emit("push constant %d", c.symbols.Count(Field))
+		c.emit("call Memory.alloc 1")
+		c.emit("pop pointer 0")
+	case "method":
+		c.emit("push argument 0")
+		c.emit("pop pointer 0")
+	}
+
+	return c.statements(sub.Body)
+}
+
+// Returns a new label of the subroutine, e.g. "WHILE_END3"
+func (c *compiler) label(prefix string) string {
+	return prefix + strconv.Itoa(c.labels)
+}
+
+func (c *compiler) statements(stmts []ast.Stmt) error {
+	for _, stmt := range stmts {
+		if err := c.statement(stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *compiler) statement(stmt ast.Stmt) error {
+	switch s := stmt.(type) {
+	case *ast.LetStmt:
+		sym := c.scope.lookup(s.Name)
+		if sym == nil {
+			return c.errorf(s.Line, "undeclared variable %s", s.Name)
+		}
+
+		if s.Index == nil {
+			if err := c.expression(s.Value); err != nil {
+				return err
+			}
+			c.emit("pop %s %d", segments[sym.Kind], sym.Index)
+			return nil
+		}
+
+		// The address is computed before the value, which may use "that"
+		// itself
+		c.emit("push %s %d", segments[sym.Kind], sym.Index)
+		if err := c.expression(s.Index); err != nil {
+			return err
+		}
+		c.emit("add")
+		if err := c.expression(s.Value); err != nil {
+			return err
+		}
+		c.emit("pop temp 0")
+		c.emit("pop pointer 1")
+		c.emit("push temp 0")
+		c.emit("pop that 0")
+
+	case *ast.IfStmt:
+		elseLabel, endLabel := c.label("IF_ELSE"), c.label("IF_END")
+		c.labels++
+
+		if err := c.expression(s.Cond); err != nil {
+			return err
+		}
+		c.emit("not")
+		c.emit("if-goto %s", elseLabel)
+		if err := c.statements(s.Then); err != nil {
+			return err
+		}
+		if s.HasElse {
+			c.emit("goto %s", endLabel)
+		}
+		c.emit("label %s", elseLabel)
+		if s.HasElse {
+			if err := c.statements(s.Else); err != nil {
+				return err
+			}
+			c.emit("label %s", endLabel)
+		}
+
+	case *ast.WhileStmt:
+		expLabel, endLabel := c.label("WHILE_EXP"), c.label("WHILE_END")
+		c.labels++
+
+		c.emit("label %s", expLabel)
+		if err := c.expression(s.Cond); err != nil {
+			return err
+		}
+		c.emit("not")
+		c.emit("if-goto %s", endLabel)
+		if err := c.statements(s.Body); err != nil {
+			return err
+		}
+		c.emit("goto %s", expLabel)
+		c.emit("label %s", endLabel)
+
+	case *ast.DoStmt:
+		if err := c.call(s.Call); err != nil {
+			return err
+		}
+		// The value of the call is dropped
+		c.emit("pop temp 0")
+
+	case *ast.ReturnStmt:
+		if s.Value == nil {
+			c.emit("push constant 0")
+		} else if err := c.expression(s.Value); err != nil {
+			return err
+		}
+		c.emit("return")
+	}
+
+	return nil
+}
+
+var binaryOps = map[string]string{
+	"+": "add",
+	"-": "sub",
+	"*": "call Math.multiply 2",
+	"/": "call Math.divide 2",
+	"&": "and",
+	"|": "or",
+	"<": "lt",
+	">": "gt",
+	"=": "eq",
+}
+
+func (c *compiler) expression(expr ast.Expr) error {
+	switch e := expr.(type) {
+	case *ast.IntConst:
+		c.emit("push constant %d", e.Value)
+
+	case *ast.StringConst:
+		runes := []rune(e.Value)
+		c.emit("push constant %d", len(runes))
+		c.emit("call String.new 1")
+		for _, r := range runes {
+			c.emit("push constant %d", r)
+			c.emit("call String.appendChar 2")
+		}
+
+	case *ast.KeywordConst:
+		switch e.Value {
+		case "true":
+			c.emit("push constant 0")
+			c.emit("not")
+		case "this":
+			c.emit("push pointer 0")
+		default: // false, null
+			c.emit("push constant 0")
+		}
+
+	case *ast.VarRef:
+		sym := c.scope.lookup(e.Name)
+		if sym == nil {
+			return c.errorf(e.Line, "undeclared variable %s", e.Name)
+		}
+		c.emit("push %s %d", segments[sym.Kind], sym.Index)
+
+	case *ast.IndexExpr:
+		sym := c.scope.lookup(e.Name)
+		if sym == nil {
+			return c.errorf(e.Line, "undeclared variable %s", e.Name)
+		}
+		c.emit("push %s %d", segments[sym.Kind], sym.Index)
+		if err := c.expression(e.Index); err != nil {
+			return err
+		}
+		c.emit("add")
+		c.emit("pop pointer 1")
+		c.emit("push that 0")
+
+	case *ast.CallExpr:
+		return c.call(e)
+
+	case *ast.ParenExpr:
+		return c.expression(e.Inner)
+
+	case *ast.UnaryExpr:
+		if err := c.expression(e.Operand); err != nil {
+			return err
+		}
+		if e.Op == "-" {
+			c.emit("neg")
+		} else {
+			c.emit("not")
+		}
+
+	case *ast.BinaryExpr:
+		if err := c.expression(e.Left); err != nil {
+			return err
+		}
+		if err := c.expression(e.Right); err != nil {
+			return err
+		}
+		c.emit("%s", binaryOps[e.Op])
+	}
+
+	return nil
+}
+
+// Compiles a call. "f()" calls method f on this object, "x.f()" method f of
+// the class of variable x on x, and "C.f()" function (or constructor) f of
+// class C.
+func (c *compiler) call(call *ast.CallExpr) error {
+	nArgs := len(call.Args)
+	var name string
+
+	switch sym := c.scope.lookup(call.Receiver); {
+	case call.Receiver == "":
+		c.emit("push pointer 0")
+		name = c.class.Name + "." + call.Name
+		nArgs++
+	case sym != nil:
+		c.emit("push %s %d", segments[sym.Kind], sym.Index)
+		name = sym.Type + "." + call.Name
+		nArgs++
+	default:
+		name = call.Receiver + "." + call.Name
+	}
+
+	for _, arg := range call.Args {
+		if err := c.expression(arg); err != nil {
+			return err
+		}
+	}
+	c.emit("call %s %d", name, nArgs)
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package compiler
+
+import (
+	"bytes"
+	"errors"
+	"nand2tetris/jack-compiler/parser"
+	"strings"
+	"testing"
+)
+
+func compile(t *testing.T, src string) (string, error) {
+	t.Helper()
+
+	class, err := parser.Parse("Test.jack", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = Compile(class, &buf)
+	return buf.String(), err
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{
+			name: "constructor",
+			src: `class P { field int x, y; static int n;
+				constructor P new(int ax) { let x = ax; let n = n + 1; return this; } }`,
+			want: `function P.new 0
+push constant 2
+call Memory.alloc 1
+pop pointer 0
+push argument 0
+pop this 0
+push static 0
+push constant 1
+add
+pop static 0
+push pointer 0
+return`,
+		},
+		{
+			name: "method",
+			src:  `class P { field int x; method void set(int v) { var int t; let t = v; do draw(t); return; } }`,
+			want: `function P.set 1
+push argument 0
+pop pointer 0
+push argument 1
+pop local 0
+push pointer 0
+push local 0
+call P.draw 2
+pop temp 0
+push constant 0
+return`,
+		},
+		{
+			name: "array",
+			src:  `class A { function void f(Array a) { let a[1] = a[2]; return; } }`,
+			want: `function A.f 0
+push argument 0
+push constant 1
+add
+push argument 0
+push constant 2
+add
+pop pointer 1
+push that 0
+pop temp 0
+pop pointer 1
+push temp 0
+pop that 0
+push constant 0
+return`,
+		},
+		{
+			name: "calls",
+			src: `class A { function void f() { var Point p; do p.move(-1);
+				do Output.printString("Hi"); return; } }`,
+			want: `function A.f 1
+push local 0
+push constant 1
+neg
+call Point.move 2
+pop temp 0
+push constant 2
+call String.new 1
+push constant 72
+call String.appendChar 2
+push constant 105
+call String.appendChar 2
+call Output.printString 1
+pop temp 0
+push constant 0
+return`,
+		},
+		{
+			name: "control flow",
+			src: `class A { function int f(boolean b) { var int i;
+				if (b) { let i = 1; } else { let i = 2 * i; }
+				while (~(i = 0)) { let i = i / 2; if (true) { return null; } }
+				return i; } }`,
+			want: `function A.f 1
+push argument 0
+not
+if-goto IF_ELSE0
+push constant 1
+pop local 0
+goto IF_END0
+label IF_ELSE0
+push constant 2
+push local 0
+call Math.multiply 2
+pop local 0
+label IF_END0
+label WHILE_EXP1
+push local 0
+push constant 0
+eq
+not
+not
+if-goto WHILE_END1
+push local 0
+push constant 2
+call Math.divide 2
+pop local 0
+push constant 0
+not
+not
+if-goto IF_ELSE2
+push constant 0
+return
+label IF_ELSE2
+goto WHILE_EXP1
+label WHILE_END1
+push local 0
+return`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := compile(t, test.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(got); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"undeclared", "class A {\n function void f() {\n let x = 1;\n return;\n }\n}", "Test.jack:3: undeclared variable x"},
+		{"undeclared in expression", "class A {\n function int f() {\n return y[1];\n }\n}", "Test.jack:3: undeclared variable y"},
+		{"duplicate field", "class A {\n field int x;\n static int x;\n}", "Test.jack:3: x is already declared"},
+		{"duplicate local", "class A {\n function void f(int x) {\n var int x;\n return;\n }\n}", "Test.jack:3: x is already declared"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := compile(t, test.src)
+			var ce *CompileError
+			if !errors.As(err, &ce) {
+				t.Fatalf("got %v, want a CompileError", err)
+			}
+			if err.Error() != test.want {
+				t.Errorf("got %q, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestSymbolTable(t *testing.T) {
+	st := NewSymbolTable()
+	st.Define("a", "int", Field, 1)
+	st.Define("b", "int", Field, 1)
+	st.Define("s", "Array", Static, 2)
+
+	if st.Define("a", "char", Static, 3) {
+		t.Error("redefined a")
+	}
+	if sym := st.Lookup("b"); sym == nil || sym.Index != 1 || sym.Kind != Field {
+		t.Errorf("b = %+v", sym)
+	}
+	if st.Count(Field) != 2 || st.Count(Static) != 1 || st.Count(Var) != 0 {
+		t.Errorf("counts = %d %d %d", st.Count(Field), st.Count(Static), st.Count(Var))
+	}
+}
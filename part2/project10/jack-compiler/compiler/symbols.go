@@ -0,0 +1,74 @@
+package compiler
+
+// This file contains the symbol tables of a class and of a subroutine.
+
+// Kinds of variables, and the VM segment holding them
+const (
+	Static = "static"
+	Field  = "field"
+	Arg    = "arg"
+	Var    = "var"
+)
+
+var segments = map[string]string{
+	Static: "static",
+	Field:  "this",
+	Arg:    "argument",
+	Var:    "local",
+}
+
+type Symbol struct {
+	Name  string
+	Type  string
+	Kind  string // Static, Field, Arg or Var
+	Index int    // Index in the segment of its kind
+	Line  int
+}
+
+// Variables of one scope: the statics and fields of a class, or the
+// arguments and locals of a subroutine
+type SymbolTable struct {
+	symbols map[string]*Symbol
+	counts  map[string]int
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{symbols: map[string]*Symbol{}, counts: map[string]int{}}
+}
+
+// Declares a variable, numbered after the others of the same kind. Returns
+// false if the name is already declared in this scope.
+func (st *SymbolTable) Define(name string, typ string, kind string, line int) bool {
+	if _, found := st.symbols[name]; found {
+		return false
+	}
+
+	st.symbols[name] = &Symbol{Name: name, Type: typ, Kind: kind, Index: st.counts[kind], Line: line}
+	st.counts[kind]++
+
+	return true
+}
+
+// Returns the variable called name, or nil
+func (st *SymbolTable) Lookup(name string) *Symbol {
+	return st.symbols[name]
+}
+
+// Returns the number of variables of a kind
+func (st *SymbolTable) Count(kind string) int {
+	return st.counts[kind]
+}
+
+// Resolves names in a subroutine first, then in its class
+type scope struct {
+	class *SymbolTable
+	sub   *SymbolTable
+}
+
+func (s scope) lookup(name string) *Symbol {
+	if sym := s.sub.Lookup(name); sym != nil {
+		return sym
+	}
+
+	return s.class.Lookup(name)
+}
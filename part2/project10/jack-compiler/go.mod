@@ -0,0 +1,3 @@
+module nand2tetris/jack-compiler
+
+go 1.18
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"nand2tetris/jack-compiler/ast"
+	"nand2tetris/jack-compiler/checker"
+	"nand2tetris/jack-compiler/compiler"
+	"nand2tetris/jack-compiler/parser"
+	"nand2tetris/jack-compiler/tokenizer"
+	"nand2tetris/jack-compiler/xmlout"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const helpMsg = `Jack Compiler
+Usage:
+	jack-compiler [-h/--help] [-n/--no-check] [-x/--xml] SOURCE
+
+Flags:
+	-h/--help            Shows this help message and exits.
+	-n/--no-check        Skips the semantic checks, compiling the classes as
+	                     long as they parse. (Default: off)
+	-x/--xml             Also writes the tokens (XxxT.xml) and parse tree
+	                     (Xxx.xml) of every class, in the format of the course's
+	                     test files. (Default: off)
+
+Positional Argument:
+	SOURCE               File containing a Jack class, or a directory of such
+	                     files. Files are expected to have ".jack" extension.
+	                     Required.
+
+Description:
+	The Jack compiler reads Jack classes (.jack), and writes their Hack virtual
+	machine code (.vm) next to them. The output is read by vm-translator: a
+	directory of classes compiled together with the OS classes' .vm files can
+	be translated as a whole program.
+
+	Classes are first checked together, along with the declarations of the
+	Jack OS: calls are resolved and their number and types of arguments
+	checked, as well as undeclared variables, unreachable code and missing
+	returns. Problems are reported as "file:line: message", and stop the
+	compilation. A single class is checked with the other classes of its
+	directory.
+
+	This compiler is projects #10 and #11 of the Nand2Tetris
+	(https://www.nand2tetris.org) courseware and book "The Elements of
+	Computing Systems" by Noam Nisan and Shimon Schocken.
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, helpMsg)
+		os.Exit(0)
+	}
+
+	var xml bool
+	flag.BoolVar(&xml, "xml", false, "Also writes the XML files")
+	flag.BoolVar(&xml, "x", false, "Also writes the XML files")
+
+	var noCheck bool
+	flag.BoolVar(&noCheck, "no-check", false, "Skips the semantic checks")
+	flag.BoolVar(&noCheck, "n", false, "Skips the semantic checks")
+
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+	}
+
+	inPath := filepath.Clean(flag.Arg(0))
+	inPaths := resolvePaths(inPath)
+
+	classes := make([]*ast.Class, len(inPaths))
+	tokens := make([][]tokenizer.Token, len(inPaths))
+	for i, path := range inPaths {
+		tokens[i], classes[i] = parse(path)
+	}
+
+	if !noCheck {
+		check(inPath, classes)
+	}
+
+	for i, class := range classes {
+		base := strings.TrimSuffix(inPaths[i], ".jack")
+		var buf bytes.Buffer
+
+		if xml {
+			xmlout.WriteTokens(&buf, tokens[i])
+			writeOutput(buf.Bytes(), base+"T.xml")
+
+			buf.Reset()
+			xmlout.WriteClass(&buf, class)
+			writeOutput(buf.Bytes(), base+".xml")
+			buf.Reset()
+		}
+
+		if err := compiler.Compile(class, &buf); err != nil {
+			log.Fatalf("[!] Error: %s", err)
+		}
+		writeOutput(buf.Bytes(), base+".vm")
+	}
+
+	log.Printf("[i] %d class(es) compiled from %q", len(inPaths), flag.Arg(0))
+}
+
+func parse(path string) ([]tokenizer.Token, *ast.Class) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to open %q: %s", path, err)
+	}
+
+	tokens, err := tokenizer.Tokenize(path, string(src))
+	if err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
+	class, err := parser.ParseTokens(path, tokens)
+	if err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
+
+	return tokens, class
+}
+
+// Runs the semantic checks, and exits on problems. A single class is checked
+// along with the other classes of its directory, which it may use: their own
+// problems aren't reported.
+func check(inPath string, classes []*ast.Class) {
+	program := classes
+	if filepath.Ext(inPath) == ".jack" {
+		others, _ := filepath.Glob(filepath.Join(filepath.Dir(inPath), "*.jack"))
+		for _, path := range others {
+			if filepath.Clean(path) == inPath {
+				continue
+			}
+			// Classes which don't parse are left out
+			if class, err := parser.ParseFile(path); err == nil {
+				program = append(program, class)
+			}
+		}
+	}
+
+	var problems int
+	for _, p := range checker.Check(program) {
+		if filepath.Ext(inPath) == ".jack" && p.File != inPath {
+			continue
+		}
+		log.Printf("[!] %s", p)
+		problems++
+	}
+
+	if problems > 0 {
+		log.Fatalf("[!] Error: %d problem(s) found", problems)
+	}
+}
+
+// Returns the .jack files of inPath, a file or a directory
+func resolvePaths(inPath string) []string {
+	info, err := os.Stat(inPath)
+	if err != nil {
+		log.Fatalf("[!] Error: %s", err)
+	}
+
+	if !info.IsDir() {
+		if filepath.Ext(inPath) != ".jack" {
+			log.Fatalln("[!] Error: Jack file (.jack) expected")
+		}
+		return []string{inPath}
+	}
+
+	inPaths, err := filepath.Glob(filepath.Join(inPath, "*.jack"))
+	if err != nil || len(inPaths) == 0 {
+		log.Fatalf("[!] Error: no .jack files found in %q", inPath)
+	}
+
+	return inPaths
+}
+
+func writeOutput(data []byte, outPath string) {
+	if err := os.WriteFile(outPath, data, 0644); err != nil {
+		log.Fatalf("[!] Error: Unable to create %q: %s", outPath, err)
+	}
+}
@@ -0,0 +1,433 @@
+// Package parser builds the syntax tree of a Jack class by recursive descent,
+// following the grammar of the course.
+package parser
+
+import (
+	"fmt"
+	"nand2tetris/jack-compiler/ast"
+	"nand2tetris/jack-compiler/tokenizer"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Reads and parses the Jack class at path
+func ParseFile(path string) (*ast.Class, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(path, string(src))
+}
+
+// Parses the Jack class src, read from file. Returns a
+// *tokenizer.SyntaxError at the first error.
+func Parse(file string, src string) (*ast.Class, error) {
+	tokens, err := tokenizer.Tokenize(file, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseTokens(file, tokens)
+}
+
+// Parses a Jack class from its tokens
+func ParseTokens(file string, tokens []tokenizer.Token) (class *ast.Class, err error) {
+	p := parser{file: file, tokens: tokens}
+
+	// Errors unwind the recursive descent as panics, and stop here
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*tokenizer.SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			class, err = nil, e
+		}
+	}()
+
+	class = p.class()
+	if p.pos < len(p.tokens) {
+		p.fail("unexpected %s after the end of the class", p.describe(p.peek()))
+	}
+
+	return class, nil
+}
+
+type parser struct {
+	file   string
+	tokens []tokenizer.Token
+	pos    int
+}
+
+func (p *parser) fail(format string, a ...interface{}) {
+	line := 1
+	switch {
+	case p.pos < len(p.tokens):
+		line = p.tokens[p.pos].Line
+	case len(p.tokens) != 0:
+		line = p.tokens[len(p.tokens)-1].Line
+	}
+
+	panic(&tokenizer.SyntaxError{File: p.file, Line: line, Msg: fmt.Sprintf(format, a...)})
+}
+
+// Returns the next token, or a token of no kind at the end of the file
+func (p *parser) peek() tokenizer.Token {
+	if p.pos >= len(p.tokens) {
+		return tokenizer.Token{Kind: -1}
+	}
+
+	return p.tokens[p.pos]
+}
+
+// Returns the token after the next one
+func (p *parser) peek2() tokenizer.Token {
+	if p.pos+1 >= len(p.tokens) {
+		return tokenizer.Token{Kind: -1}
+	}
+
+	return p.tokens[p.pos+1]
+}
+
+func (p *parser) next() tokenizer.Token {
+	t := p.peek()
+	if t.Kind == -1 {
+		p.fail("unexpected end of file")
+	}
+	p.pos++
+
+	return t
+}
+
+func (p *parser) describe(t tokenizer.Token) string {
+	if t.Kind == -1 {
+		return "end of file"
+	}
+
+	return fmt.Sprintf("%s %q", t.Kind, t.Text)
+}
+
+// Consumes the keyword or symbol s
+func (p *parser) expect(s string) tokenizer.Token {
+	t := p.peek()
+	if !t.Is(s) {
+		p.fail("expected %q, found %s", s, p.describe(t))
+	}
+
+	return p.next()
+}
+
+func (p *parser) identifier(what string) string {
+	t := p.peek()
+	if t.Kind != tokenizer.Identifier {
+		p.fail("expected %s, found %s", what, p.describe(t))
+	}
+
+	return p.next().Text
+}
+
+// type: "int", "char", "boolean" or a class name. void is accepted too for
+// return types.
+func (p *parser) typeName(allowVoid bool) string {
+	t := p.peek()
+	if t.Is("int") || t.Is("char") || t.Is("boolean") || (allowVoid && t.Is("void")) {
+		return p.next().Text
+	}
+	if t.Kind == tokenizer.Identifier {
+		return p.next().Text
+	}
+
+	if allowVoid {
+		p.fail("expected a return type, found %s", p.describe(t))
+	}
+	p.fail("expected a type, found %s", p.describe(t))
+	return ""
+}
+
+// class: "class" className "{" classVarDec* subroutineDec* "}"
+func (p *parser) class() *ast.Class {
+	line := p.expect("class").Line
+	class := &ast.Class{File: p.file, Name: p.identifier("a class name"), Line: line}
+	p.expect("{")
+
+	for p.peek().Is("static") || p.peek().Is("field") {
+		class.Vars = append(class.Vars, p.classVarDec())
+	}
+	for p.peek().Is("constructor") || p.peek().Is("function") || p.peek().Is("method") {
+		class.Subs = append(class.Subs, p.subroutine())
+	}
+
+	if !p.peek().Is("}") {
+		p.fail("expected a variable declaration, subroutine or \"}\", found %s", p.describe(p.peek()))
+	}
+	p.next()
+
+	return class
+}
+
+// classVarDec: ("static" | "field") type varName ("," varName)* ";"
+func (p *parser) classVarDec() *ast.ClassVarDec {
+	t := p.next()
+	dec := &ast.ClassVarDec{Kind: t.Text, Type: p.typeName(false), Line: t.Line}
+	dec.Names = p.names()
+
+	return dec
+}
+
+// varName ("," varName)* ";"
+func (p *parser) names() []string {
+	names := []string{p.identifier("a variable name")}
+	for p.peek().Is(",") {
+		p.next()
+		names = append(names, p.identifier("a variable name"))
+	}
+	p.expect(";")
+
+	return names
+}
+
+// subroutineDec: ("constructor" | "function" | "method") ("void" | type)
+// subroutineName "(" parameterList ")" subroutineBody
+func (p *parser) subroutine() *ast.Subroutine {
+	t := p.next()
+	sub := &ast.Subroutine{Kind: t.Text, Line: t.Line}
+	sub.Return = p.typeName(true)
+	sub.Name = p.identifier("a subroutine name")
+
+	p.expect("(")
+	if !p.peek().Is(")") {
+		for {
+			line := p.peek().Line
+			param := &ast.Param{Type: p.typeName(false), Line: line}
+			param.Name = p.identifier("a parameter name")
+			sub.Params = append(sub.Params, param)
+
+			if !p.peek().Is(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	p.expect(")")
+
+	// subroutineBody: "{" varDec* statements "}"
+	p.expect("{")
+	for p.peek().Is("var") {
+		line := p.next().Line
+		dec := &ast.VarDec{Type: p.typeName(false), Line: line}
+		dec.Names = p.names()
+		sub.Locals = append(sub.Locals, dec)
+	}
+	sub.Body = p.statements()
+	sub.End = p.expect("}").Line
+
+	return sub
+}
+
+// statements: statement*, up to the closing "}"
+func (p *parser) statements() []ast.Stmt {
+	var out []ast.Stmt
+
+	for !p.peek().Is("}") {
+		t := p.peek()
+
+		switch {
+		case t.Is("let"):
+			out = append(out, p.letStatement())
+		case t.Is("if"):
+			out = append(out, p.ifStatement())
+		case t.Is("while"):
+			out = append(out, p.whileStatement())
+		case t.Is("do"):
+			out = append(out, p.doStatement())
+		case t.Is("return"):
+			out = append(out, p.returnStatement())
+		case t.Is("var"):
+			p.fail("variables must be declared at the beginning of the subroutine")
+		default:
+			p.fail("expected a statement or \"}\", found %s", p.describe(t))
+		}
+	}
+
+	return out
+}
+
+// "let" varName ("[" expression "]")? "=" expression ";"
+func (p *parser) letStatement() *ast.LetStmt {
+	line := p.next().Line
+	stmt := &ast.LetStmt{Name: p.identifier("a variable name"), Line: line}
+
+	if p.peek().Is("[") {
+		p.next()
+		stmt.Index = p.expression()
+		p.expect("]")
+	}
+	p.expect("=")
+	stmt.Value = p.expression()
+	p.expect(";")
+
+	return stmt
+}
+
+// "if" "(" expression ")" "{" statements "}" ("else" "{" statements "}")?
+func (p *parser) ifStatement() *ast.IfStmt {
+	line := p.next().Line
+	stmt := &ast.IfStmt{Line: line}
+
+	p.expect("(")
+	stmt.Cond = p.expression()
+	p.expect(")")
+	stmt.Then = p.block()
+
+	if p.peek().Is("else") {
+		p.next()
+		stmt.HasElse = true
+		stmt.Else = p.block()
+	}
+
+	return stmt
+}
+
+// "while" "(" expression ")" "{" statements "}"
+func (p *parser) whileStatement() *ast.WhileStmt {
+	line := p.next().Line
+	stmt := &ast.WhileStmt{Line: line}
+
+	p.expect("(")
+	stmt.Cond = p.expression()
+	p.expect(")")
+	stmt.Body = p.block()
+
+	return stmt
+}
+
+// "{" statements "}"
+func (p *parser) block() []ast.Stmt {
+	p.expect("{")
+	stmts := p.statements()
+	p.expect("}")
+
+	return stmts
+}
+
+// "do" subroutineCall ";"
+func (p *parser) doStatement() *ast.DoStmt {
+	line := p.next().Line
+
+	if p.peek().Kind != tokenizer.Identifier {
+		p.fail("expected a subroutine call, found %s", p.describe(p.peek()))
+	}
+	stmt := &ast.DoStmt{Call: p.call(), Line: line}
+	p.expect(";")
+
+	return stmt
+}
+
+// "return" expression? ";"
+func (p *parser) returnStatement() *ast.ReturnStmt {
+	line := p.next().Line
+	stmt := &ast.ReturnStmt{Line: line}
+
+	if !p.peek().Is(";") {
+		stmt.Value = p.expression()
+	}
+	p.expect(";")
+
+	return stmt
+}
+
+const ops = "+-*/&|<>="
+
+// expression: term (op term)*
+func (p *parser) expression() ast.Expr {
+	expr := p.term()
+
+	for {
+		t := p.peek()
+		if t.Kind != tokenizer.Symbol || !strings.Contains(ops, t.Text) {
+			return expr
+		}
+		p.next()
+
+		expr = &ast.BinaryExpr{Op: t.Text, Left: expr, Right: p.term(), Line: expr.ExprLine()}
+	}
+}
+
+// term: integerConstant | stringConstant | keywordConstant | varName |
+// varName "[" expression "]" | subroutineCall | "(" expression ")" |
+// unaryOp term
+func (p *parser) term() ast.Expr {
+	t := p.peek()
+
+	switch {
+	case t.Kind == tokenizer.IntConst:
+		p.next()
+		n, _ := strconv.Atoi(t.Text)
+		return &ast.IntConst{Value: n, Line: t.Line}
+
+	case t.Kind == tokenizer.StringConst:
+		p.next()
+		return &ast.StringConst{Value: t.Text, Line: t.Line}
+
+	case t.Is("true") || t.Is("false") || t.Is("null") || t.Is("this"):
+		p.next()
+		return &ast.KeywordConst{Value: t.Text, Line: t.Line}
+
+	case t.Kind == tokenizer.Identifier:
+		next := p.peek2()
+		switch {
+		case next.Is("["):
+			p.next()
+			p.next()
+			expr := &ast.IndexExpr{Name: t.Text, Index: p.expression(), Line: t.Line}
+			p.expect("]")
+			return expr
+		case next.Is("(") || next.Is("."):
+			return p.call()
+		}
+		p.next()
+		return &ast.VarRef{Name: t.Text, Line: t.Line}
+
+	case t.Is("("):
+		p.next()
+		expr := &ast.ParenExpr{Inner: p.expression(), Line: t.Line}
+		p.expect(")")
+		return expr
+
+	case t.Is("-") || t.Is("~"):
+		p.next()
+		return &ast.UnaryExpr{Op: t.Text, Operand: p.term(), Line: t.Line}
+	}
+
+	p.fail("expected an expression, found %s", p.describe(t))
+	return nil
+}
+
+// subroutineCall: subroutineName "(" expressionList ")" |
+// (className | varName) "." subroutineName "(" expressionList ")"
+func (p *parser) call() *ast.CallExpr {
+	first := p.next()
+	call := &ast.CallExpr{Name: first.Text, Line: first.Line}
+
+	if p.peek().Is(".") {
+		p.next()
+		call.Receiver = first.Text
+		call.Name = p.identifier("a subroutine name")
+	}
+
+	p.expect("(")
+	if !p.peek().Is(")") {
+		for {
+			call.Args = append(call.Args, p.expression())
+			if !p.peek().Is(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	p.expect(")")
+
+	return call
+}
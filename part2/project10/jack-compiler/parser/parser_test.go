@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"errors"
+	"nand2tetris/jack-compiler/ast"
+	"nand2tetris/jack-compiler/tokenizer"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `class Point {
+  field int x, y;
+  static Point origin;
+
+  constructor Point new(int ax, int ay) {
+    let x = ax;
+    let y = ay;
+    return this;
+  }
+
+  method int sum(Array a) {
+    var int i, s;
+    while (i < 10) {
+      let s = s + a[i] * -2;
+      let i = i + 1;
+    }
+    if (~(s = 0)) { do Output.printInt(s); } else { return 0; }
+    return s;
+  }
+}`
+
+	class, err := Parse("Point.jack", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if class.Name != "Point" || class.File != "Point.jack" || len(class.Vars) != 2 || len(class.Subs) != 2 {
+		t.Fatalf("class = %+v", class)
+	}
+	if v := class.Vars[0]; v.Kind != "field" || v.Type != "int" || strings.Join(v.Names, ",") != "x,y" || v.Line != 2 {
+		t.Errorf("field dec = %+v", v)
+	}
+
+	ctor := class.Subs[0]
+	if ctor.Kind != "constructor" || ctor.Return != "Point" || len(ctor.Params) != 2 || ctor.Params[1].Name != "ay" || ctor.End != 9 {
+		t.Errorf("constructor = %+v", ctor)
+	}
+
+	sum := class.Subs[1]
+	if len(sum.Locals) != 1 || len(sum.Body) != 3 {
+		t.Fatalf("sum = %+v", sum)
+	}
+
+	// "s + a[i] * -2" is "(s + a[i]) * (-2)": no precedence in Jack
+	let := sum.Body[0].(*ast.WhileStmt).Body[0].(*ast.LetStmt)
+	mul, ok := let.Value.(*ast.BinaryExpr)
+	if !ok || mul.Op != "*" || let.Line != 14 {
+		t.Fatalf("let value = %#v", let.Value)
+	}
+	if add, ok := mul.Left.(*ast.BinaryExpr); !ok || add.Op != "+" {
+		t.Errorf("left of * = %#v", mul.Left)
+	} else if _, ok := add.Right.(*ast.IndexExpr); !ok {
+		t.Errorf("right of + = %#v", add.Right)
+	}
+	if neg, ok := mul.Right.(*ast.UnaryExpr); !ok || neg.Op != "-" {
+		t.Errorf("right of * = %#v", mul.Right)
+	}
+
+	ifStmt := sum.Body[1].(*ast.IfStmt)
+	if !ifStmt.HasElse || len(ifStmt.Then) != 1 || len(ifStmt.Else) != 1 {
+		t.Errorf("if = %+v", ifStmt)
+	}
+	call := ifStmt.Then[0].(*ast.DoStmt).Call
+	if call.Receiver != "Output" || call.Name != "printInt" || len(call.Args) != 1 {
+		t.Errorf("call = %+v", call)
+	}
+	if not, ok := ifStmt.Cond.(*ast.UnaryExpr); !ok || not.Op != "~" {
+		t.Errorf("if condition = %#v", ifStmt.Cond)
+	} else if _, ok := not.Operand.(*ast.ParenExpr); !ok {
+		t.Errorf("operand of ~ = %#v", not.Operand)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"class Main {\n  function void f() {\n    let x = 1\n  }\n}", 4, `expected ";", found symbol "}"`},
+		{"class Main {\n  function 3 f() { return; }\n}", 2, "expected a return type"},
+		{"class Main {\n  function f() { return; }\n}", 2, "expected a subroutine name"},
+		{"class Main {\n  function void f() {\n    let x = 1;\n    var int y;\n  }\n}", 4, "variables must be declared"},
+		{"class Main {\n  function void f() {\n    do 3;\n  }\n}", 3, "expected a subroutine call"},
+		{"class Main {\n  function void f() {\n    let x = );\n  }\n}", 3, "expected an expression"},
+		{"class Main {\n  function void f() {\n", 2, "found end of file"},
+		{"class Main {\n}\nclass Other {}", 3, "after the end of the class"},
+		{"class Main {\n  int x;\n}", 2, "expected a variable declaration, subroutine"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse("Main.jack", test.src)
+
+		var syntaxErr *tokenizer.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q) = %v, want a *tokenizer.SyntaxError", test.src, err)
+			continue
+		}
+		if syntaxErr.Line != test.line || !strings.Contains(syntaxErr.Msg, test.msg) {
+			t.Errorf("Parse(%q) = %v, want line %d and %q", test.src, err, test.line, test.msg)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Package tokenizer splits Jack source code into tokens.
+package tokenizer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type Kind int
+
+const (
+	Keyword Kind = iota
+	Symbol
+	IntConst
+	StringConst
+	Identifier
+)
+
+// Names of the kinds, as used by the course's XML files
+func (k Kind) String() string {
+	return [...]string{"keyword", "symbol", "integerConstant", "stringConstant", "identifier"}[k]
+}
+
+type Token struct {
+	Kind Kind
+	Text string // String constants are kept without their quotes
+	Line int
+}
+
+func (t Token) String() string {
+	if t.Kind == StringConst {
+		return strconv.Quote(t.Text)
+	}
+
+	return t.Text
+}
+
+// Whether the token is the keyword or symbol s
+func (t Token) Is(s string) bool {
+	return (t.Kind == Keyword || t.Kind == Symbol) && t.Text == s
+}
+
+// Largest integer constant of the Jack language
+const MaxInt = 32767
+
+var keywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`class constructor function method field
+		static var int char boolean void true false null this let do if else
+		while return`) {
+		keywords[k] = true
+	}
+}
+
+const symbols = "{}()[].,;+-*/&|<>=~"
+
+// Describes malformed Jack code, with its position
+type SyntaxError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Splits the Jack source src, read from file, into tokens. Comments and white
+// space are dropped.
+func Tokenize(file string, src string) ([]Token, error) {
+	var out []Token
+	line := 1
+	runes := []rune(src)
+
+	fail := func(format string, a ...interface{}) ([]Token, error) {
+		return nil, &SyntaxError{File: file, Line: line, Msg: fmt.Sprintf(format, a...)}
+	}
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+
+		switch {
+		case c == '\n':
+			line++
+			i++
+
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start := line
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i+1 >= len(runes) {
+				line = start
+				return fail("unterminated comment")
+			}
+			i += 2
+
+		case c == '"':
+			j := i + 1
+			for j < len(runes) && runes[j] != '"' && runes[j] != '\n' {
+				j++
+			}
+			if j >= len(runes) || runes[j] != '"' {
+				return fail("unterminated string constant")
+			}
+			out = append(out, Token{Kind: StringConst, Text: string(runes[i+1 : j]), Line: line})
+			i = j + 1
+
+		case strings.ContainsRune(symbols, c):
+			out = append(out, Token{Kind: Symbol, Text: string(c), Line: line})
+			i++
+
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(runes) && runes[j] >= '0' && runes[j] <= '9' {
+				j++
+			}
+			text := string(runes[i:j])
+			if n, err := strconv.Atoi(text); err != nil || n > MaxInt {
+				return fail("integer constant %s out of range, the largest is %d", text, MaxInt)
+			}
+			if j < len(runes) && isIdentRune(runes[j]) {
+				return fail("identifier can't start with a digit")
+			}
+			out = append(out, Token{Kind: IntConst, Text: text, Line: line})
+			i = j
+
+		case isIdentRune(c):
+			j := i
+			for j < len(runes) && (isIdentRune(runes[j]) || (runes[j] >= '0' && runes[j] <= '9')) {
+				j++
+			}
+			text := string(runes[i:j])
+			kind := Identifier
+			if keywords[text] {
+				kind = Keyword
+			}
+			out = append(out, Token{Kind: kind, Text: text, Line: line})
+			i = j
+
+		default:
+			return fail("unexpected character %q", c)
+		}
+	}
+
+	return out, nil
+}
+
+// Letters and "_". Digits are allowed after the first character.
+func isIdentRune(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
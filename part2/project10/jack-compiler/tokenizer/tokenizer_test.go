@@ -0,0 +1,65 @@
+package tokenizer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	src := `/** doc
+ * comment */
+class Main { // comment
+  let x = a[1] + "hi there";
+  do Foo.bar_2(-32767, true, this);
+}`
+	want := []Token{
+		{Keyword, "class", 3}, {Identifier, "Main", 3}, {Symbol, "{", 3},
+		{Keyword, "let", 4}, {Identifier, "x", 4}, {Symbol, "=", 4}, {Identifier, "a", 4},
+		{Symbol, "[", 4}, {IntConst, "1", 4}, {Symbol, "]", 4}, {Symbol, "+", 4},
+		{StringConst, "hi there", 4}, {Symbol, ";", 4},
+		{Keyword, "do", 5}, {Identifier, "Foo", 5}, {Symbol, ".", 5}, {Identifier, "bar_2", 5},
+		{Symbol, "(", 5}, {Symbol, "-", 5}, {IntConst, "32767", 5}, {Symbol, ",", 5},
+		{Keyword, "true", 5}, {Symbol, ",", 5}, {Keyword, "this", 5}, {Symbol, ")", 5}, {Symbol, ";", 5},
+		{Symbol, "}", 6},
+	}
+
+	got, err := Tokenize("Main.jack", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d tokens, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("token %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		line int
+	}{
+		{"let s = \"unterminated;\n", 1},
+		{"let s = \"two\nlines\";", 1},
+		{"\nlet x = 32768;", 2},
+		{"let 2x = 1;", 1},
+		{"\n\nlet x = #;", 3},
+		{"\n/* unterminated\n\n", 2},
+	}
+
+	for _, test := range tests {
+		_, err := Tokenize("Main.jack", test.src)
+
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Tokenize(%q) = %v, want a *SyntaxError", test.src, err)
+			continue
+		}
+		if syntaxErr.Line != test.line || syntaxErr.File != "Main.jack" {
+			t.Errorf("Tokenize(%q) error at %s:%d, want Main.jack:%d", test.src, syntaxErr.File, syntaxErr.Line, test.line)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Package xmlout writes the token and parse tree XML files of the course
+// (xxxT.xml and xxx.xml), to compare the front end with the official ones.
+package xmlout
+
+import (
+	"bufio"
+	"io"
+	"nand2tetris/jack-compiler/ast"
+	"nand2tetris/jack-compiler/tokenizer"
+	"strconv"
+	"strings"
+)
+
+var escaper = strings.NewReplacer("<", "&lt;", ">", "&gt;", "\"", "&quot;", "&", "&amp;")
+
+// Writes the tokens as an xxxT.xml file
+func WriteTokens(w io.Writer, tokens []tokenizer.Token) error {
+	out := bufio.NewWriter(w)
+
+	out.WriteString("<tokens>\n")
+	for _, t := range tokens {
+		writeLeaf(out, 0, t.Kind.String(), t.Text)
+	}
+	out.WriteString("</tokens>\n")
+
+	return out.Flush()
+}
+
+// Writes the parse tree of class as an xxx.xml file. The punctuation left out
+// of the syntax tree is put back where the grammar has it.
+func WriteClass(w io.Writer, class *ast.Class) error {
+	x := writer{out: bufio.NewWriter(w)}
+
+	x.open("class")
+	x.keyword("class")
+	x.identifier(class.Name)
+	x.symbol("{")
+
+	for _, dec := range class.Vars {
+		x.open("classVarDec")
+		x.keyword(dec.Kind)
+		x.typeName(dec.Type)
+		x.names(dec.Names)
+		x.close("classVarDec")
+	}
+
+	for _, sub := range class.Subs {
+		x.subroutine(sub)
+	}
+
+	x.symbol("}")
+	x.close("class")
+
+	return x.out.Flush()
+}
+
+type writer struct {
+	out   *bufio.Writer
+	depth int
+}
+
+func writeLeaf(out *bufio.Writer, depth int, tag string, text string) {
+	out.WriteString(strings.Repeat("  ", depth))
+	out.WriteString("<" + tag + "> " + escaper.Replace(text) + " </" + tag + ">\n")
+}
+
+func (x *writer) open(tag string) {
+	x.out.WriteString(strings.Repeat("  ", x.depth) + "<" + tag + ">\n")
+	x.depth++
+}
+
+func (x *writer) close(tag string) {
+	x.depth--
+	x.out.WriteString(strings.Repeat("  ", x.depth) + "</" + tag + ">\n")
+}
+
+func (x *writer) keyword(s string)    { writeLeaf(x.out, x.depth, "keyword", s) }
+func (x *writer) symbol(s string)     { writeLeaf(x.out, x.depth, "symbol", s) }
+func (x *writer) identifier(s string) { writeLeaf(x.out, x.depth, "identifier", s) }
+
+// Primitive types are keywords, class names identifiers
+func (x *writer) typeName(s string) {
+	switch s {
+	case "int", "char", "boolean", "void":
+		x.keyword(s)
+	default:
+		x.identifier(s)
+	}
+}
+
+// varName ("," varName)* ";"
+func (x *writer) names(names []string) {
+	for i, name := range names {
+		if i != 0 {
+			x.symbol(",")
+		}
+		x.identifier(name)
+	}
+	x.symbol(";")
+}
+
+func (x *writer) subroutine(sub *ast.Subroutine) {
+	x.open("subroutineDec")
+	x.keyword(sub.Kind)
+	x.typeName(sub.Return)
+	x.identifier(sub.Name)
+
+	x.symbol("(")
+	x.open("parameterList")
+	for i, param := range sub.Params {
+		if i != 0 {
+			x.symbol(",")
+		}
+		x.typeName(param.Type)
+		x.identifier(param.Name)
+	}
+	x.close("parameterList")
+	x.symbol(")")
+
+	x.open("subroutineBody")
+	x.symbol("{")
+	for _, dec := range sub.Locals {
+		x.open("varDec")
+		x.keyword("var")
+		x.typeName(dec.Type)
+		x.names(dec.Names)
+		x.close("varDec")
+	}
+	x.statements(sub.Body)
+	x.symbol("}")
+	x.close("subroutineBody")
+
+	x.close("subroutineDec")
+}
+
+func (x *writer) statements(stmts []ast.Stmt) {
+	x.open("statements")
+
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.LetStmt:
+			x.open("letStatement")
+			x.keyword("let")
+			x.identifier(s.Name)
+			if s.Index != nil {
+				x.symbol("[")
+				x.expression(s.Index)
+				x.symbol("]")
+			}
+			x.symbol("=")
+			x.expression(s.Value)
+			x.symbol(";")
+			x.close("letStatement")
+
+		case *ast.IfStmt:
+			x.open("ifStatement")
+			x.keyword("if")
+			x.symbol("(")
+			x.expression(s.Cond)
+			x.symbol(")")
+			x.block(s.Then)
+			if s.HasElse {
+				x.keyword("else")
+				x.block(s.Else)
+			}
+			x.close("ifStatement")
+
+		case *ast.WhileStmt:
+			x.open("whileStatement")
+			x.keyword("while")
+			x.symbol("(")
+			x.expression(s.Cond)
+			x.symbol(")")
+			x.block(s.Body)
+			x.close("whileStatement")
+
+		case *ast.DoStmt:
+			x.open("doStatement")
+			x.keyword("do")
+			x.call(s.Call)
+			x.symbol(";")
+			x.close("doStatement")
+
+		case *ast.ReturnStmt:
+			x.open("returnStatement")
+			x.keyword("return")
+			if s.Value != nil {
+				x.expression(s.Value)
+			}
+			x.symbol(";")
+			x.close("returnStatement")
+		}
+	}
+
+	x.close("statements")
+}
+
+func (x *writer) block(stmts []ast.Stmt) {
+	x.symbol("{")
+	x.statements(stmts)
+	x.symbol("}")
+}
+
+// expression: term (op term)*. The left-leaning chain of binary expressions
+// is flattened back.
+func (x *writer) expression(expr ast.Expr) {
+	var terms []ast.Expr
+	var ops []string
+	for {
+		bin, ok := expr.(*ast.BinaryExpr)
+		if !ok {
+			break
+		}
+		terms = append(terms, bin.Right)
+		ops = append(ops, bin.Op)
+		expr = bin.Left
+	}
+
+	x.open("expression")
+	x.term(expr)
+	for i := len(terms) - 1; i >= 0; i-- {
+		x.symbol(ops[i])
+		x.term(terms[i])
+	}
+	x.close("expression")
+}
+
+func (x *writer) term(expr ast.Expr) {
+	x.open("term")
+
+	switch e := expr.(type) {
+	case *ast.IntConst:
+		writeLeaf(x.out, x.depth, "integerConstant", strconv.Itoa(e.Value))
+	case *ast.StringConst:
+		writeLeaf(x.out, x.depth, "stringConstant", e.Value)
+	case *ast.KeywordConst:
+		x.keyword(e.Value)
+	case *ast.VarRef:
+		x.identifier(e.Name)
+	case *ast.IndexExpr:
+		x.identifier(e.Name)
+		x.symbol("[")
+		x.expression(e.Index)
+		x.symbol("]")
+	case *ast.CallExpr:
+		x.call(e)
+	case *ast.ParenExpr:
+		x.symbol("(")
+		x.expression(e.Inner)
+		x.symbol(")")
+	case *ast.UnaryExpr:
+		x.symbol(e.Op)
+		x.term(e.Operand)
+	}
+
+	x.close("term")
+}
+
+// subroutineCall, written inline in the enclosing element
+func (x *writer) call(call *ast.CallExpr) {
+	if call.Receiver != "" {
+		x.identifier(call.Receiver)
+		x.symbol(".")
+	}
+	x.identifier(call.Name)
+
+	x.symbol("(")
+	x.open("expressionList")
+	for i, arg := range call.Args {
+		if i != 0 {
+			x.symbol(",")
+		}
+		x.expression(arg)
+	}
+	x.close("expressionList")
+	x.symbol(")")
+}
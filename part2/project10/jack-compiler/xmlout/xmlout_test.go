@@ -0,0 +1,87 @@
+package xmlout
+
+import (
+	"bytes"
+	"flag"
+	"nand2tetris/jack-compiler/parser"
+	"nand2tetris/jack-compiler/tokenizer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata")
+
+// Compares the XML files of every class in testdata with the ones next to
+// them, as the course's TextComparer does
+func TestGolden(t *testing.T) {
+	paths, err := filepath.Glob("../testdata/*/*.jack")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no Jack files in testdata: %v", err)
+	}
+
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens, err := tokenizer.Tokenize(path, string(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		class, err := parser.ParseTokens(path, tokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var tokensXML, classXML bytes.Buffer
+		if err := WriteTokens(&tokensXML, tokens); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteClass(&classXML, class); err != nil {
+			t.Fatal(err)
+		}
+
+		base := strings.TrimSuffix(path, ".jack")
+		compare(t, base+"T.xml", tokensXML.Bytes())
+		compare(t, base+".xml", classXML.Bytes())
+	}
+}
+
+func compare(t *testing.T, path string, got []byte) {
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The official files differ in indentation and line endings only
+	if strings.Join(strings.Fields(string(got)), " ") != strings.Join(strings.Fields(string(want)), " ") {
+		t.Errorf("output differs from %s, run with -update to inspect", path)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	tokens, err := tokenizer.Tokenize("Main.jack", `if (a < b & c > "d") {}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := WriteTokens(&out, tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"<symbol> &lt; </symbol>", "<symbol> &amp; </symbol>", "<symbol> &gt; </symbol>", "<stringConstant> d </stringConstant>"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -1,16 +1,31 @@
 package constants
 
+import "strings"
+
 const (
 	HelpMsg = `Hack VM Translator
 Usage:
-	vm-translator [-h/--help] BYTECODE
+	vm-translator [-h/--help] [-a/--annotate] [-c/--checked] [-p/--prune]
+	              BYTECODE
 
 Flags:
 	-h/--help            Shows this help message and exits.
+	-a/--annotate        Precedes every translated block with a comment holding
+	                     its VM instruction and source position, and writes a
+	                     source map (.asm.map) next to the output, giving the
+	                     VM position of every instruction by ROM address and
+	                     .asm line. (Default: off)
+	-c/--checked         Guards every instruction with run-time checks, for
+	                     debugging: see below. (Default: off)
+	-p/--prune           Drops the functions no call can reach from the entry
+	                     point, and lists them. (Default: off)
 
 Positional Argument:
-	BYTECODE             File containing byte code for the Hack virtual machine.
-							File is expected to have ".vm" extension. Required.
+	BYTECODE             File containing byte code for the Hack virtual machine,
+	                     or a directory of such files. Files are expected to have
+	                     ".vm" extension. A directory "Xxx" is translated to
+	                     "Xxx/Xxx.asm", starting with bootstrap code that calls
+	                     Sys.init. Required.
 
 Description:
 	The Hack virtual machine translator reads an Hack virtual machine bytecode
@@ -18,6 +33,30 @@ Description:
 	bytecode and assembly code follow the Hack computer architecture and language
 	specification defined in the Nand2Tetris courseware.
 
+	Command keywords and segment names are case-insensitive. Function and label
+	names are case-sensitive, and labels are scoped to the function declaring them.
+	A function or label named like a symbol of the assembler (SP, R0-R15, SCREEN,
+	KBD...), a static variable ("Xxx.0") or another label is rejected.
+
+	Pops go through the scratch register R15, so the only RAM variables are the
+	static variables, allocated from RAM[16]. Programs whose variables would
+	overflow into the stack at RAM[256] are rejected.
+
+	Checked code halts at the first instruction popping below the stack base
+	RAM[256], pushing past the heap base RAM[2048], or accessing this or that
+	while THIS or THAT is 0. It leaves the error code in RAM[254] and the VM
+	line of the instruction in RAM[255]:
+	    1  stack underflow     3  null this
+	    2  stack overflow      4  null that
+	The indexes of temp and pointer are checked when translating. The static
+	variables of checked code have to end below RAM[254].
+
+	Pruning starts from Sys.init in a directory, or else from the first
+	instruction of the first file, and keeps every function called by kept
+	code, or reached by falling off the end of the function before it. The
+	code outside of any function is always kept. The unused functions of a
+	whole library, such as the Jack OS, are dropped this way.
+
 	This translator is project #7 of the Nand2Tetris (https://www.nand2tetris.org)
 	courseware and book "The Elements of Computing Systems" by Noam Nisan and
 	Shimon Schocken. This implementation is written in GO by
@@ -31,10 +70,64 @@ Description:
 
 	StackBaseAdr = 256
 
+	EntryFunction = "Sys.init" // Called by the bootstrap code
+
 	ThisPtrAdr = 3 // Address where the "THIS" pointer is stored
 	ThatPtrAdr = 4 // Address where the "THAT" pointer is stored
+
+	MaxPointerNum = 2     // pointer 0 is THIS, pointer 1 is THAT
+	MaxConstant   = 32767 // Largest value that fits in an A instruction
+
+	FrameReg   = "R13" // Holds the frame (saved LCL) of the returning function
+	RetAdrReg  = "R14" // Holds the return address of the returning function
+	PopAdrReg  = "R15" // Holds the target address of a pop
+	FrameSize  = 5     // Return address, LCL, ARG, THIS and THAT
+	LabelSep   = "$"   // Separates a function name from its label names
+	GenMark    = "$"   // Starts the generated labels, which no VM identifier can
+	ReturnMark = "ret" // Return labels are named "$ret.Function.N"
+
+	CompareMark = "cmp" // Comparison labels are named "$cmp.File.N"
+	CheckMark   = "chk" // Labels past the guards of checked code, "$chk.File.N"
+
+	VarBaseAdr = 16 // The assembler allocates variables from RAM[16]
+
+	HeapBaseAdr = 2048 // The stack ends where the heap of the Jack OS begins
+)
+
+// Checked code: the traps its guards jump to, and the words they leave in RAM
+const (
+	TrapLabel   = GenMark + "trap" // Traps are labeled "$trap.underflow", etc.
+	TrapCodeAdr = StackBaseAdr - 2 // Error code of the failed check
+	TrapLineAdr = StackBaseAdr - 1 // VM line number of the failed check
+
+	TrapUnderflow = 1 // Popping below the stack base
+	TrapOverflow  = 2 // Pushing into the heap
+	TrapNullThis  = 3 // Accessing this with THIS at 0
+	TrapNullThat  = 4 // Accessing that with THAT at 0
 )
 
+// Trap names by error code
+var Traps = []string{"", "underflow", "overflow", "null.this", "null.that"}
+
+// Commands that operate on the stack only and take no arguments
+var ArithmeticCmds = []string{
+	"ADD", "SUB", "NEG", "EQ", "GT", "LT", "AND", "OR", "NOT",
+}
+
+// Commands that take a memory segment and an index
+var MemoryCmds = []string{"PUSH", "POP"}
+
+// Commands that take a label name
+var FlowCmds = []string{"LABEL", "GOTO", "IF-GOTO"}
+
+// "FUNCTION" and "CALL" take a function name and a count, "RETURN" takes
+// nothing
+var FunctionCmds = []string{"FUNCTION", "CALL", "RETURN"}
+
+var Segments = []string{
+	"LOCAL", "ARGUMENT", "THIS", "THAT", "CONSTANT", "STATIC", "TEMP", "POINTER",
+}
+
 var PtrWithOffset = map[string]string{
 	"LOCAL":    "@LCL",
 	"ARGUMENT": "@ARG",
@@ -43,3 +136,17 @@ var PtrWithOffset = map[string]string{
 	"TEMP":     "@",
 	"POINTER":  "@",
 }
+
+// Maps the lower case spelling of every keyword (commands and segments) to the
+// canonical upper case form used by the translator. Keywords are matched
+// case-insensitively through this table, while identifiers such as function
+// and label names are kept exactly as written.
+var Keywords = map[string]string{}
+
+func init() {
+	for _, list := range [][]string{ArithmeticCmds, MemoryCmds, FlowCmds, FunctionCmds, Segments} {
+		for _, k := range list {
+			Keywords[strings.ToLower(k)] = k
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package helpers
 
 import (
-	"math/rand"
 	"nand2tetris/vm-translator/constants"
 	"runtime"
 	"strings"
@@ -27,19 +26,9 @@ func GetStaticLabel(fileName string) string {
 
 	i := strings.LastIndex(fileName, sep)
 	j := strings.LastIndex(fileName, ".vm")
-
-	return fileName[i+1 : j]
-}
-
-// Based on Paul Hankin's solution from this stack overflow question
-// https://stackoverflow.com/questions/22892120/how-to-generate-a-random-string-of-a-fixed-length-in-go/22892986#22892986
-func GetRandomString(length int) string {
-	alphaNumeric := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
-
-	out := make([]rune, length)
-	for i := range out {
-		out[i] = alphaNumeric[rand.Intn(len(alphaNumeric))]
+	if j <= i {
+		j = len(fileName)
 	}
 
-	return string(out)
+	return fileName[i+1 : j]
 }
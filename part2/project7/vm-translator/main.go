@@ -1,14 +1,17 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"nand2tetris/vm-translator/constants"
-	"nand2tetris/vm-translator/helpers"
 	"nand2tetris/vm-translator/translator"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -18,61 +21,154 @@ func main() {
 		os.Exit(0)
 	}
 
+	var annotate bool
+	flag.BoolVar(&annotate, "annotate", false, "Annotate output with VM source")
+	flag.BoolVar(&annotate, "a", false, "Annotate output with VM source")
+
+	var checked bool
+	flag.BoolVar(&checked, "checked", false, "Guard the output with run-time checks")
+	flag.BoolVar(&checked, "c", false, "Guard the output with run-time checks")
+
+	var prune bool
+	flag.BoolVar(&prune, "prune", false, "Drop the unreachable functions")
+	flag.BoolVar(&prune, "p", false, "Drop the unreachable functions")
+
 	flag.Parse()
 	if flag.NArg() != 1 {
 		flag.Usage()
 	}
 
-	inPath := flag.Arg(0)
-	if !strings.HasSuffix(inPath, ".vm") {
-		log.Fatalln("[!] Error: expected Hack VM (.vm) file")
+	inPath := filepath.Clean(flag.Arg(0))
+	inPaths, outPath, isDir := resolvePaths(inPath)
+
+	files := map[string]io.Reader{}
+	for _, p := range inPaths {
+		inFile, err := os.Open(p)
+		if err != nil {
+			log.Fatalf("[!] Unable to open %q: %s", p, err)
+		}
+		defer inFile.Close()
+
+		files[filepath.Base(p)] = inFile
+	}
+
+	outFile, err := os.Create(outPath)
+	if err != nil {
+		log.Fatalf("[!] Error: Unable to create %q: %s", outPath, err)
 	}
-	staticLabel := helpers.GetStaticLabel(inPath)
+	defer outFile.Close()
 
-	var bufIn []string
-	readFile(&bufIn, inPath)
+	var srcMap []translator.SourcePos
+	var stats translator.Stats
+	var removed []translator.RemovedFunction
+	opts := translator.Options{
+		Annotate:  annotate,
+		SourceMap: &srcMap,
+		// Whole programs (directories) start from Sys.init
+		Bootstrap: isDir,
+		Stats:     &stats,
+		Checked:   checked,
+		Prune:     prune,
+		Removed:   &removed,
+	}
 
-	var bufOut []string
+	// The output is kept to find the ROM address of its lines in the source map
+	var asm bytes.Buffer
+	err = translator.Translate(files, io.MultiWriter(outFile, &asm), opts)
 
-	tr := translator.Translator{}
-	tr.Setup(&bufIn, &bufOut, staticLabel)
-	tr.TranslateAll()
+	var syntaxErrs translator.SyntaxErrors
+	if errors.As(err, &syntaxErrs) {
+		for _, err := range syntaxErrs {
+			log.Printf("[!] Error: %s", err)
+		}
+		outFile.Close()
+		os.Remove(outPath)
+		log.Fatalf("[!] %d error(s) found in %q, aborting", len(syntaxErrs), inPath)
+	}
+	if err != nil {
+		outFile.Close()
+		os.Remove(outPath)
+		log.Fatalf("[!] Error: %s", err)
+	}
 
-	outPath := strings.Split(inPath, ".vm")[0] + ".asm"
-	writeFile(&bufOut, outPath)
+	if prune {
+		size := 0
+		for _, f := range removed {
+			log.Printf("[i] Removed %s (%s:%d), %d VM instruction(s)", f.Name, f.File, f.Line, f.Instructions)
+			size += f.Instructions
+		}
+		log.Printf("[i] %d unreachable function(s) removed, %d VM instruction(s)", len(removed), size)
+	}
 
+	if annotate {
+		writeSourceMap(&srcMap, strings.Split(asm.String(), "\n"), filepath.Base(outPath), outPath+".map")
+	}
+
+	log.Printf("[i] %d instructions, %d labels, %d variable(s) in RAM[%d-%d]", stats.Instructions, stats.Labels,
+		len(stats.Variables), constants.VarBaseAdr, constants.StackBaseAdr-1)
+	if checked {
+		log.Printf("[i] Checks failing halt with their error code in RAM[%d] and VM line in RAM[%d]",
+			constants.TrapCodeAdr, constants.TrapLineAdr)
+	}
 	log.Printf("[i] Translator output %q successful\n", outPath)
 }
 
-func readFile(buf *[]string, filePath string) {
-	inFile, err := os.Open(filePath)
+// Returns the VM files to translate and the output path. A single file
+// "Xxx.vm" is translated to "Xxx.asm", and a directory "Xxx" to "Xxx/Xxx.asm".
+func resolvePaths(inPath string) ([]string, string, bool) {
+	info, err := os.Stat(inPath)
 	if err != nil {
-		log.Fatalf("[!] Unable to open %q: %s", filePath, err)
+		log.Fatalf("[!] Unable to open %q: %s", inPath, err)
 	}
 
-	scanner := bufio.NewScanner(inFile)
-	for scanner.Scan() {
-		in := scanner.Text()
-		in = strings.TrimSpace(in)
-		in = strings.ToUpper(in)
-		in = helpers.RemoveInlineComments(in)
-
-		if len(in) == 0 {
-			continue
+	if !info.IsDir() {
+		if !strings.HasSuffix(inPath, ".vm") {
+			log.Fatalln("[!] Error: expected Hack VM (.vm) file or directory")
 		}
 
-		*buf = append(*buf, in)
+		return []string{inPath}, strings.TrimSuffix(inPath, ".vm") + ".asm", false
 	}
+
+	inPaths, _ := filepath.Glob(filepath.Join(inPath, "*.vm"))
+	if len(inPaths) == 0 {
+		log.Fatalf("[!] Error: no Hack VM (.vm) files found in %q", inPath)
+	}
+
+	return inPaths, filepath.Join(inPath, filepath.Base(inPath)+".asm"), true
 }
 
-func writeFile(buf *[]string, filePath string) {
-	outFile, err := os.Create(filePath)
+// Write the source map of an annotated translation as JSON. Every entry maps
+// an instruction, by ROM address and by line of the .asm file (1-based), to
+// its VM file and line. Labels and comments have no entry.
+func writeSourceMap(srcMap *[]translator.SourcePos, asmLines []string, asmName string, filePath string) {
+	type mapping struct {
+		ROM int `json:"rom"` // Address of the instruction
+		Asm int `json:"asm"` // Line of the instruction in the .asm file
+		translator.SourcePos
+	}
+
+	out := struct {
+		File  string    `json:"file"`
+		Lines []mapping `json:"lines"`
+	}{File: asmName, Lines: []mapping{}}
+
+	// Labels and comments take no ROM word
+	rom := 0
+	for i, pos := range *srcMap {
+		line := strings.TrimSpace(asmLines[i])
+		if len(line) == 0 || strings.HasPrefix(line, "(") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		out.Lines = append(out.Lines, mapping{ROM: rom, Asm: i + 1, SourcePos: pos})
+		rom++
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
 	if err != nil {
-		log.Fatalf("[!] Error: Unable to create %q: %s", filePath, err)
+		log.Fatalf("[!] Error: Unable to encode source map: %s", err)
 	}
-	defer outFile.Close()
 
-	for _, code := range *buf {
-		outFile.WriteString(code + "\n")
+	if err := os.WriteFile(filePath, append(data, '\n'), 0644); err != nil {
+		log.Fatalf("[!] Error: Unable to create %q: %s", filePath, err)
 	}
 }
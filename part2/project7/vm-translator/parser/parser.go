@@ -1,34 +1,237 @@
 package parser
 
 import (
-	"log"
+	"fmt"
+	"nand2tetris/vm-translator/constants"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
 type instruction struct {
-	Operator string
-	Segment  string
-	Dest     int
+	Operator string // Canonical (upper case) command keyword
+	Segment  string // Canonical (upper case) segment keyword
+	Dest     int    // Segment index, or the count of function/call
+	Symbol   string // Label or function name, as written in the source
 }
 
-func ParseIn(in string) instruction {
+// Label and function names: letters, digits, "_", "." and ":", not beginning
+// with a digit
+var identifier = regexp.MustCompile(`^[A-Za-z_.:][A-Za-z0-9_.:]*$`)
+
+// Describes a malformed VM instruction, with its position in the VM file, the
+// offending token and the values that would have been accepted in its place
+type SyntaxError struct {
+	File    string
+	Line    int
+	Token   string // Empty if the token is missing
+	Msg     string
+	Allowed []string // Empty if there's no fixed set of valid values
+}
+
+func (e *SyntaxError) Error() string {
+	out := fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+
+	if len(e.Token) != 0 {
+		out += fmt.Sprintf(" %q", e.Token)
+	}
+
+	if len(e.Allowed) != 0 {
+		allowed := make([]string, len(e.Allowed))
+		for i, v := range e.Allowed {
+			allowed[i] = strings.ToLower(v)
+		}
+		out += fmt.Sprintf(" (expected one of: %s)", strings.Join(allowed, ", "))
+	}
+
+	return out
+}
+
+// Splits a single instruction into its tokens. Any run of spaces or tabs
+// separates two tokens.
+func Tokenize(in string) []string {
+	return strings.Fields(in)
+}
+
+// Returns the canonical form of a keyword, matched case-insensitively
+func Keyword(token string) (string, bool) {
+	k, found := constants.Keywords[strings.ToLower(token)]
+	return k, found
+}
+
+// Parses a single instruction. Keywords are converted to their canonical form,
+// identifiers are kept as they are. Instructions that passed Validate always
+// parse; anything else returns the same error Validate would have.
+func ParseIn(in string) (instruction, *SyntaxError) {
 	out := instruction{Dest: -1}
 
-	s := strings.Split(in, " ")
+	if err := Validate(in); err != nil {
+		return out, err
+	}
+
+	s := Tokenize(in)
 
-	out.Operator = s[0]
+	out.Operator, _ = Keyword(s[0])
 
-	if len(s) == 3 {
-		out.Segment = s[1]
+	switch {
+	case contains(constants.MemoryCmds, out.Operator):
+		out.Segment, _ = Keyword(s[1])
+		out.Dest, _ = strconv.Atoi(s[2])
 
-		v, err := strconv.Atoi(s[2])
-		if err != nil {
-			log.Fatalf("[!] Error: unable to parse instruction %q: %s", in, err)
+	case contains(constants.FlowCmds, out.Operator):
+		out.Symbol = s[1]
+
+	case out.Operator == "FUNCTION" || out.Operator == "CALL":
+		out.Symbol = s[1]
+		out.Dest, _ = strconv.Atoi(s[2])
+	}
+
+	return out, nil
+}
+
+// Checks every instruction in buf and returns all of the problems found, so
+// they can be reported together. lines holds the line number of each
+// instruction in the VM file.
+func ValidateAll(file string, buf []string, lines []int) []*SyntaxError {
+	var errs []*SyntaxError
+
+	for i, in := range buf {
+		if err := Validate(in); err != nil {
+			err.File = file
+			err.Line = lines[i]
+			errs = append(errs, err)
 		}
+	}
 
-		out.Dest = v
+	return errs
+}
+
+// Checks a single instruction. The returned error has no file position, which
+// is left for the caller to fill in.
+func Validate(in string) *SyntaxError {
+	s := Tokenize(in)
+	if len(s) == 0 {
+		return &SyntaxError{Msg: "empty instruction"}
 	}
 
-	return out
+	operator, found := Keyword(s[0])
+	if !found || contains(constants.Segments, operator) {
+		var allowed []string
+		for _, list := range [][]string{constants.MemoryCmds, constants.ArithmeticCmds, constants.FlowCmds, constants.FunctionCmds} {
+			allowed = append(allowed, list...)
+		}
+
+		return &SyntaxError{Token: s[0], Msg: "unknown command", Allowed: allowed}
+	}
+	name := strings.ToLower(operator)
+
+	switch {
+	case contains(constants.ArithmeticCmds, operator), operator == "RETURN":
+		return checkArgCount(s, 1, name)
+
+	case contains(constants.FlowCmds, operator):
+		if len(s) < 2 {
+			return &SyntaxError{Msg: "missing label name after " + name}
+		}
+		if err := checkIdentifier(s[1], "label"); err != nil {
+			return err
+		}
+
+		return checkArgCount(s, 2, name)
+
+	case operator == "FUNCTION" || operator == "CALL":
+		if len(s) < 2 {
+			return &SyntaxError{Msg: "missing function name after " + name}
+		}
+		if err := checkIdentifier(s[1], "function"); err != nil {
+			return err
+		}
+		if len(s) < 3 {
+			return &SyntaxError{Msg: "missing count after " + name + " " + s[1]}
+		}
+		if _, err := checkIndex(s[2]); err != nil {
+			return err
+		}
+
+		return checkArgCount(s, 3, name)
+	}
+
+	// push and pop
+	if len(s) < 2 {
+		return &SyntaxError{Msg: "missing segment", Allowed: constants.Segments}
+	}
+
+	seg, found := Keyword(s[1])
+	if !found || !contains(constants.Segments, seg) {
+		return &SyntaxError{Token: s[1], Msg: "unknown segment", Allowed: constants.Segments}
+	}
+
+	if len(s) < 3 {
+		return &SyntaxError{Msg: "missing index after " + s[1]}
+	}
+	if err := checkArgCount(s, 3, name); err != nil {
+		return err
+	}
+
+	if operator == "POP" && seg == "CONSTANT" {
+		return &SyntaxError{Token: s[1], Msg: "cannot pop to segment"}
+	}
+
+	index, err := checkIndex(s[2])
+	if err != nil {
+		return err
+	}
+
+	switch seg {
+	case "TEMP":
+		if index >= constants.MaxTempNum {
+			return &SyntaxError{Token: s[2], Msg: fmt.Sprintf("temp index must be 0-%d, got", constants.MaxTempNum-1)}
+		}
+	case "POINTER":
+		if index >= constants.MaxPointerNum {
+			return &SyntaxError{Token: s[2], Msg: fmt.Sprintf("pointer index must be 0-%d, got", constants.MaxPointerNum-1)}
+		}
+	case "CONSTANT":
+		if index > constants.MaxConstant {
+			return &SyntaxError{Token: s[2], Msg: fmt.Sprintf("constant must be 0-%d, got", constants.MaxConstant)}
+		}
+	}
+
+	return nil
+}
+
+// Reports the first token beyond the expected number of tokens, if any
+func checkArgCount(s []string, n int, name string) *SyntaxError {
+	if len(s) > n {
+		return &SyntaxError{Token: s[n], Msg: "unexpected argument to " + name}
+	}
+
+	return nil
+}
+
+func checkIdentifier(token string, kind string) *SyntaxError {
+	if !identifier.MatchString(token) {
+		return &SyntaxError{Token: token, Msg: "invalid " + kind + " name"}
+	}
+
+	return nil
+}
+
+func checkIndex(token string) (int, *SyntaxError) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, &SyntaxError{Token: token, Msg: "expected a non-negative integer, got"}
+	}
+
+	return index, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
 }
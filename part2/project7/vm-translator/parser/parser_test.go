@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseIn(t *testing.T) {
+	tests := []struct {
+		in   string
+		want instruction
+	}{
+		{"push constant 7", instruction{Operator: "PUSH", Segment: "CONSTANT", Dest: 7}},
+		{"pop local 2", instruction{Operator: "POP", Segment: "LOCAL", Dest: 2}},
+		{"push\tthat  5", instruction{Operator: "PUSH", Segment: "THAT", Dest: 5}},
+		{"PUSH Argument 1", instruction{Operator: "PUSH", Segment: "ARGUMENT", Dest: 1}},
+		{"add", instruction{Operator: "ADD", Dest: -1}},
+		{"Not", instruction{Operator: "NOT", Dest: -1}},
+		{"label Loop", instruction{Operator: "LABEL", Dest: -1, Symbol: "Loop"}},
+		{"goto END_1", instruction{Operator: "GOTO", Dest: -1, Symbol: "END_1"}},
+		{"if-goto a.b:c", instruction{Operator: "IF-GOTO", Dest: -1, Symbol: "a.b:c"}},
+		{"function Main.main 3", instruction{Operator: "FUNCTION", Dest: 3, Symbol: "Main.main"}},
+		{"CALL Math.multiply 2", instruction{Operator: "CALL", Dest: 2, Symbol: "Math.multiply"}},
+		{"return", instruction{Operator: "RETURN", Dest: -1}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseIn(tt.in)
+		if err != nil {
+			t.Errorf("ParseIn(%q) returned error: %s", tt.in, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("ParseIn(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		in      string
+		token   string // Expected offending token
+		msg     string // Expected part of the message
+		allowed bool   // Whether allowed values are expected
+	}{
+		{"psuh constant 1", "psuh", "unknown command", true},
+		{"constant", "constant", "unknown command", true},
+		{"push", "", "missing segment", true},
+		{"push constant", "", "missing index", false},
+		{"push heap 1", "heap", "unknown segment", true},
+		{"pop constant 3", "constant", "cannot pop", false},
+		{"push temp 8", "8", "temp index must be 0-7", false},
+		{"push pointer 2", "2", "pointer index must be 0-1", false},
+		{"push constant 32768", "32768", "constant must be 0-32767", false},
+		{"push local -1", "-1", "non-negative integer", false},
+		{"push local x", "x", "non-negative integer", false},
+		{"push local 1 2", "2", "unexpected argument", false},
+		{"add 1", "1", "unexpected argument", false},
+		{"return 0", "0", "unexpected argument", false},
+		{"label", "", "missing label name", false},
+		{"label 1abc", "1abc", "invalid label name", false},
+		{"goto a-b", "a-b", "invalid label name", false},
+		{"function Main.main", "", "missing count", false},
+		{"function Main.main -1", "-1", "non-negative integer", false},
+		{"call", "", "missing function name", false},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.in)
+		if err == nil {
+			t.Errorf("Validate(%q) returned nil, want error", tt.in)
+			continue
+		}
+
+		if err.Token != tt.token {
+			t.Errorf("Validate(%q) token = %q, want %q", tt.in, err.Token, tt.token)
+		}
+		if !strings.Contains(err.Msg, tt.msg) {
+			t.Errorf("Validate(%q) message = %q, want it to contain %q", tt.in, err.Msg, tt.msg)
+		}
+		if (len(err.Allowed) != 0) != tt.allowed {
+			t.Errorf("Validate(%q) allowed = %v, want allowed values: %t", tt.in, err.Allowed, tt.allowed)
+		}
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	buf := []string{"push constant 1", "psuh constant 2", "add", "pop constant 1"}
+	lines := []int{1, 3, 4, 7}
+
+	errs := ValidateAll("Main.vm", buf, lines)
+	if len(errs) != 2 {
+		t.Fatalf("ValidateAll returned %d errors, want 2", len(errs))
+	}
+
+	want := []string{
+		`Main.vm:3: unknown command "psuh" (expected one of: push, pop,`,
+		`Main.vm:7: cannot pop to segment "constant"`,
+	}
+	for i, err := range errs {
+		if !strings.HasPrefix(err.Error(), want[i]) {
+			t.Errorf("error %d = %q, want prefix %q", i, err, want[i])
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package translator
+
+// This file contains the guards of checked code, and the traps they jump to
+// when a check fails.
+
+import (
+	"nand2tetris/vm-translator/constants"
+	"strconv"
+)
+
+// Turn on checked output. Every instruction is preceded by guards checking
+// the stack holds the values it pops, has room below the heap for the values
+// it pushes, and the this and that segments it accesses aren't null. A failed
+// check jumps to a trap, which stores the error code
+// at constants.TrapCodeAdr and the VM line at constants.TrapLineAdr, then
+// halts. The traps are written by Traps.
+//
+// The indexes of temp and pointer are constants, checked when translating, so
+// they need no guard.
+func (tr *Translator) EnableChecks() {
+	tr.checked = true
+}
+
+// Write the guards run before an instruction: its operands on the stack, the
+// room for what it pushes, and the this or that pointer it dereferences. The
+// frame of a call and the locals of a function are checked by call and
+// function, before they are pushed.
+func (tr *Translator) checkBefore(operator string, seg string, n int) {
+	switch operator {
+	case "PUSH":
+		tr.checkRoom(1)
+	case "POP", "IF-GOTO", "RETURN", "NEG", "NOT":
+		tr.checkStack(1)
+	case "ADD", "SUB", "EQ", "GT", "LT", "AND", "OR":
+		tr.checkStack(2)
+	case "CALL":
+		// The arguments
+		tr.checkStack(n)
+	}
+
+	if seg == "THIS" || seg == "THAT" {
+		tr.checkPointer(seg)
+	}
+}
+
+// Check the stack holds at least n values
+func (tr *Translator) checkStack(n int) {
+	if n == 0 {
+		return
+	}
+
+	// SP - n >= constants.StackBaseAdr
+	*tr.bufOut = append(*tr.bufOut, "@SP")
+	*tr.bufOut = append(*tr.bufOut, "D=M")
+	*tr.bufOut = append(*tr.bufOut, "@"+strconv.Itoa(constants.StackBaseAdr+n))
+	*tr.bufOut = append(*tr.bufOut, "D=D-A")
+	tr.guard("JGE", constants.TrapUnderflow)
+}
+
+// Check n values can be pushed without writing into the heap
+func (tr *Translator) checkRoom(n int) {
+	if n == 0 {
+		return
+	}
+
+	// SP + n <= constants.HeapBaseAdr
+	*tr.bufOut = append(*tr.bufOut, "@SP")
+	*tr.bufOut = append(*tr.bufOut, "D=M")
+	*tr.bufOut = append(*tr.bufOut, "@"+strconv.Itoa(constants.HeapBaseAdr-n))
+	*tr.bufOut = append(*tr.bufOut, "D=D-A")
+	tr.guard("JLE", constants.TrapOverflow)
+}
+
+// Check the THIS or THAT pointer isn't null
+func (tr *Translator) checkPointer(seg string) {
+	code := constants.TrapNullThis
+	if seg == "THAT" {
+		code = constants.TrapNullThat
+	}
+
+	*tr.bufOut = append(*tr.bufOut, "@"+seg)
+	*tr.bufOut = append(*tr.bufOut, "D=M")
+	tr.guard("JNE", code)
+}
+
+// Continue if D passes the jump condition cond, e.g. "JGE", or jump to the
+// trap of code with the VM line in D
+func (tr *Translator) guard(cond string, code int) {
+	ok := tr.uniqueName(constants.CheckMark)
+
+	*tr.bufOut = append(*tr.bufOut, "@"+ok)
+	*tr.bufOut = append(*tr.bufOut, "D;"+cond)
+	*tr.bufOut = append(*tr.bufOut, "@"+strconv.Itoa(tr.line))
+	*tr.bufOut = append(*tr.bufOut, "D=A")
+	*tr.bufOut = append(*tr.bufOut, "@"+trapLabel(code))
+	*tr.bufOut = append(*tr.bufOut, "0;JMP")
+
+	tr.writeLabel(ok)
+}
+
+// Returns the label of the trap of code, e.g. "$trap.underflow"
+func trapLabel(code int) string {
+	return constants.TrapLabel + "." + constants.Traps[code]
+}
+
+// Write the traps of checked code, at the end of the program. Every trap
+// stores its error code and the VM line held in D, then halts. Falling off
+// the end of the program halts too.
+func (tr *Translator) Traps() {
+	halt := constants.TrapLabel + ".halt"
+
+	if tr.annotate {
+		*tr.bufOut = append(*tr.bufOut, "// traps")
+	}
+
+	tr.writeLabel(halt)
+	*tr.bufOut = append(*tr.bufOut, "@"+halt)
+	*tr.bufOut = append(*tr.bufOut, "0;JMP")
+
+	for code := 1; code < len(constants.Traps); code++ {
+		tr.writeLabel(trapLabel(code))
+		*tr.bufOut = append(*tr.bufOut, "@"+strconv.Itoa(constants.TrapLineAdr))
+		*tr.bufOut = append(*tr.bufOut, "M=D")
+		*tr.bufOut = append(*tr.bufOut, "@"+strconv.Itoa(code))
+		*tr.bufOut = append(*tr.bufOut, "D=A")
+		*tr.bufOut = append(*tr.bufOut, "@"+strconv.Itoa(constants.TrapCodeAdr))
+		*tr.bufOut = append(*tr.bufOut, "M=D")
+		*tr.bufOut = append(*tr.bufOut, "@"+halt)
+		*tr.bufOut = append(*tr.bufOut, "0;JMP")
+	}
+	tr.currentLoc = ""
+}
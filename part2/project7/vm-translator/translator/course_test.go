@@ -0,0 +1,44 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Translates the course's test programs in testdata and runs them: they have
+// to do what the course's .cmp files expect, with and without checks
+func TestCoursePrograms(t *testing.T) {
+	tests := []struct {
+		name string
+		ram  map[int]int
+		want map[int]int
+	}{
+		{"BasicTest", testRAM,
+			map[int]int{256: 472, 300: 10, 401: 21, 402: 22, 3006: 36, 3012: 42, 3015: 45, 11: 510}},
+		{"StackTest", map[int]int{0: 256},
+			map[int]int{0: 266, 256: -1, 257: 0, 258: 0, 259: 0, 260: -1, 261: 0, 262: -1, 263: 0, 264: 0, 265: -91}},
+		{"PointerTest", map[int]int{0: 256},
+			map[int]int{256: 6084, 3: 3030, 4: 3040, 3032: 32, 3046: 46}},
+		{"StaticTest", map[int]int{0: 256},
+			map[int]int{256: 1110}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm, err := os.ReadFile(filepath.Join("..", "testdata", tt.name+".vm"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, opts := range []Options{{}, {Checked: true}} {
+				ram := runHack(t, translateString(t, string(vm), opts), tt.ram, 20000)
+				for adr, want := range tt.want {
+					if got := ram[adr]; int(got) != want {
+						t.Errorf("checked %v: RAM[%d] = %d, want %d", opts.Checked, adr, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package translator
+
+import (
+	"fmt"
+	"nand2tetris/vm-translator/parser"
+	"strings"
+)
+
+// Returned when a VM source can't be read
+type ReadError struct {
+	File string
+	Err  error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("unable to read %q: %s", e.File, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// Returned when the translated program can't be written
+type WriteError struct {
+	Err error
+}
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("unable to write output: %s", e.Err)
+}
+
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+// Every syntax error found in the VM sources, in file and line order
+type SyntaxErrors []*parser.SyntaxError
+
+func (e SyntaxErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Returned when a valid instruction can't be translated
+type TranslateError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *TranslateError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err)
+}
+
+func (e *TranslateError) Unwrap() error {
+	return e.Err
+}
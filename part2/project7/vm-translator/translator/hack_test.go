@@ -0,0 +1,119 @@
+package translator
+
+// This file contains a minimal Hack assembly interpreter, so the translator
+// tests can check what the generated code does rather than how it is spelled.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Assembles asm and runs it from address 0 with the given RAM contents, until
+// the program counter leaves the program or maxSteps instructions have run.
+// Returns the RAM afterwards.
+func runHack(t *testing.T, asm []string, ram map[int]int, maxSteps int) []int16 {
+	t.Helper()
+
+	symbols := map[string]int{
+		"SP": 0, "LCL": 1, "ARG": 2, "THIS": 3, "THAT": 4,
+		"SCREEN": 16384, "KBD": 24576,
+	}
+	for i := 0; i < 16; i++ {
+		symbols[fmt.Sprintf("R%d", i)] = i
+	}
+
+	var rom []string
+	for _, line := range asm {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, "//"); i != -1 {
+			line = strings.TrimSpace(line[:i])
+		}
+
+		switch {
+		case len(line) == 0:
+		case strings.HasPrefix(line, "("):
+			label := strings.Trim(line, "()")
+			if _, found := symbols[label]; found {
+				t.Fatalf("label %q declared twice", label)
+			}
+			symbols[label] = len(rom)
+		default:
+			rom = append(rom, line)
+		}
+	}
+
+	nextVar := 16
+	mem := make([]int16, 32768)
+	for adr, v := range ram {
+		mem[adr] = int16(v)
+	}
+
+	var a, d int16
+	pc := 0
+	for step := 0; step < maxSteps && pc >= 0 && pc < len(rom); step++ {
+		in := rom[pc]
+		pc++
+
+		if strings.HasPrefix(in, "@") {
+			v, err := strconv.Atoi(in[1:])
+			if err != nil {
+				adr, found := symbols[in[1:]]
+				if !found {
+					adr = nextVar
+					symbols[in[1:]] = adr
+					nextVar++
+				}
+				v = adr
+			}
+			a = int16(v)
+			continue
+		}
+
+		dest, comp, jump := "", in, ""
+		if i := strings.Index(comp, "="); i != -1 {
+			dest, comp = comp[:i], comp[i+1:]
+		}
+		if i := strings.Index(comp, ";"); i != -1 {
+			comp, jump = comp[:i], comp[i+1:]
+		}
+
+		m := mem[uint16(a)%32768]
+		x := map[string]int16{
+			"0": 0, "1": 1, "-1": -1, "D": d, "A": a, "M": m,
+			"!D": ^d, "!A": ^a, "!M": ^m, "-D": -d, "-A": -a, "-M": -m,
+			"D+1": d + 1, "A+1": a + 1, "M+1": m + 1,
+			"D-1": d - 1, "A-1": a - 1, "M-1": m - 1,
+			"D+A": d + a, "D+M": d + m, "M+D": m + d, "A+D": a + d,
+			"D-A": d - a, "D-M": d - m, "A-D": a - d, "M-D": m - d,
+			"D&A": d & a, "D&M": d & m, "D|A": d | a, "D|M": d | m,
+		}
+		out, found := x[comp]
+		if !found {
+			t.Fatalf("unknown computation %q in %q", comp, in)
+		}
+
+		adr := uint16(a) % 32768
+		if strings.Contains(dest, "M") {
+			mem[adr] = out
+		}
+		if strings.Contains(dest, "A") {
+			a = out
+		}
+		if strings.Contains(dest, "D") {
+			d = out
+		}
+
+		jumps := map[string]bool{
+			"": false, "JMP": true,
+			"JGT": out > 0, "JEQ": out == 0, "JGE": out >= 0,
+			"JLT": out < 0, "JNE": out != 0, "JLE": out <= 0,
+		}
+		if jumps[jump] {
+			pc = int(adr)
+		}
+	}
+
+	return mem
+}
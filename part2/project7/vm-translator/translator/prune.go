@@ -0,0 +1,123 @@
+package translator
+
+// This file contains the dead-function elimination: the whole-program pass
+// dropping the functions no call can reach.
+
+import (
+	"nand2tetris/vm-translator/parser"
+)
+
+// A function dropped by Options.Prune
+type RemovedFunction struct {
+	Name         string
+	File         string
+	Line         int // VM line of its "function" instruction
+	Instructions int // VM instructions dropped with it
+}
+
+// A function of the program, or the code of a file outside of any function
+type vmFunction struct {
+	name       string // Empty for the code outside of any function
+	src        *source
+	start, end int // Instructions of src.buf
+	calls      []string
+	next       *vmFunction // The function it falls through to, if any
+}
+
+// Drops the functions unreachable from the entry point, and returns them in
+// file and line order. The program enters at function entry, or at the first
+// instruction of the first file if entry is empty or declared nowhere. A
+// function is reached by a call from reached code, or by falling off the end
+// of the code before it, when that doesn't end with a return or a goto. The
+// code outside of any function is always kept.
+func prune(sources []source, entry string) []RemovedFunction {
+	var all []*vmFunction
+	byName := map[string][]*vmFunction{}
+
+	for i := range sources {
+		src := &sources[i]
+
+		var prev *vmFunction
+		for j, s := range src.buf {
+			in, _ := parser.ParseIn(s)
+
+			if in.Operator == "FUNCTION" || j == 0 {
+				f := &vmFunction{src: src, start: j}
+				if in.Operator == "FUNCTION" {
+					f.name = in.Symbol
+					byName[f.name] = append(byName[f.name], f)
+				}
+				if prev != nil {
+					prev.end = j
+					if last, _ := parser.ParseIn(src.buf[j-1]); last.Operator != "RETURN" && last.Operator != "GOTO" {
+						prev.next = f
+					}
+				}
+				all = append(all, f)
+				prev = f
+			}
+			if in.Operator == "CALL" {
+				prev.calls = append(prev.calls, in.Symbol)
+			}
+		}
+		if prev != nil {
+			prev.end = len(src.buf)
+		}
+	}
+
+	reached := map[*vmFunction]bool{}
+	var reach func(f *vmFunction)
+	reach = func(f *vmFunction) {
+		if reached[f] {
+			return
+		}
+		reached[f] = true
+
+		for _, name := range f.calls {
+			for _, callee := range byName[name] {
+				reach(callee)
+			}
+		}
+		if f.next != nil {
+			reach(f.next)
+		}
+	}
+
+	if len(byName[entry]) != 0 {
+		for _, f := range byName[entry] {
+			reach(f)
+		}
+	} else if len(all) != 0 {
+		reach(all[0])
+	}
+	for _, f := range all {
+		if f.name == "" {
+			reach(f)
+		}
+	}
+
+	// Rebuilds every file without the unreached functions
+	var removed []RemovedFunction
+	for i := range sources {
+		src := &sources[i]
+
+		var buf []string
+		var lines []int
+		for _, f := range all {
+			if f.src != src {
+				continue
+			}
+			if !reached[f] {
+				removed = append(removed, RemovedFunction{
+					Name: f.name, File: src.name, Line: src.lines[f.start], Instructions: f.end - f.start,
+				})
+				continue
+			}
+			buf = append(buf, src.buf[f.start:f.end]...)
+			lines = append(lines, src.lines[f.start:f.end]...)
+		}
+		src.buf, src.lines = buf, lines
+	}
+
+	return removed
+}
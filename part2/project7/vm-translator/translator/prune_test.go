@@ -0,0 +1,171 @@
+package translator
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Translates files with dead-function elimination, returning the assembly
+// and the functions removed
+func translatePruned(t *testing.T, srcs map[string]string, bootstrap bool) ([]string, []RemovedFunction) {
+	t.Helper()
+
+	files := map[string]io.Reader{}
+	for name, src := range srcs {
+		files[name] = strings.NewReader(src)
+	}
+
+	var out bytes.Buffer
+	var removed []RemovedFunction
+	if err := Translate(files, &out, Options{Bootstrap: bootstrap, Prune: true, Removed: &removed}); err != nil {
+		t.Fatalf("Translate returned error: %s", err)
+	}
+
+	return strings.Split(strings.TrimSpace(out.String()), "\n"), removed
+}
+
+func TestPrune(t *testing.T) {
+	srcs := map[string]string{
+		"Sys.vm": `function Sys.init 0
+			call Main.main 0
+			label HALT
+			goto HALT`,
+		"Main.vm": `function Main.main 0
+			push constant 6
+			push constant 7
+			call Math.multiply 2
+			pop static 0
+			push constant 0
+			return
+			function Main.unused 0
+			call Math.divide 2
+			return`,
+		"Math.vm": `function Math.multiply 0
+			push argument 0
+			push argument 1
+			call Math.add 2
+			return
+			function Math.divide 0
+			call Math.helper 0
+			return
+			function Math.helper 0
+			push constant 0
+			return
+			// Falls through to Math.add
+			function Math.shortcut 0
+			push argument 0
+			pop temp 0
+			function Math.add 0
+			push argument 0
+			push argument 1
+			add
+			return`,
+	}
+
+	asm, removed := translatePruned(t, srcs, true)
+
+	// Math.divide and Math.helper are only reached from unreached code
+	want := []RemovedFunction{
+		{Name: "Main.unused", File: "Main.vm", Line: 8, Instructions: 3},
+		{Name: "Math.divide", File: "Math.vm", Line: 6, Instructions: 3},
+		{Name: "Math.helper", File: "Math.vm", Line: 9, Instructions: 3},
+		{Name: "Math.shortcut", File: "Math.vm", Line: 13, Instructions: 3},
+	}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed %+v, want %+v", removed, want)
+	}
+
+	out := strings.Join(asm, "\n")
+	for _, label := range []string{"(Sys.init)", "(Main.main)", "(Math.multiply)", "(Math.add)"} {
+		if !strings.Contains(out, label) {
+			t.Errorf("output does not contain %q", label)
+		}
+	}
+
+	ram := runHack(t, asm, nil, 10000)
+	if ram[16] != 13 {
+		t.Errorf("Main.0 = %d, want 13", ram[16])
+	}
+}
+
+func TestPruneFallThrough(t *testing.T) {
+	srcs := map[string]string{
+		"Sys.vm": `function Sys.init 0
+			call Sys.first 0
+			label HALT
+			goto HALT
+			function Sys.first 0
+			push constant 1
+			pop temp 0
+			function Sys.second 0
+			push constant 2
+			return
+			function Sys.third 0
+			push constant 3
+			return`,
+	}
+
+	_, removed := translatePruned(t, srcs, true)
+	if len(removed) != 1 || removed[0].Name != "Sys.third" {
+		t.Errorf("removed %+v, want Sys.third only", removed)
+	}
+}
+
+func TestPruneEntryPoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		srcs      map[string]string
+		bootstrap bool
+		removed   []string
+	}{
+		{"first function of the first file",
+			map[string]string{
+				"A.vm": "function A.main 0\ncall B.used 0\nreturn",
+				"B.vm": "function B.used 0\npush constant 1\nreturn\nfunction B.unused 0\npush constant 2\nreturn",
+			},
+			false, []string{"B.unused"}},
+		{"code outside of functions",
+			map[string]string{
+				"Test.vm": "push constant 1\ncall Test.g 1\nlabel END\ngoto END\nfunction Test.f 0\nreturn\nfunction Test.g 0\nreturn",
+			},
+			false, []string{"Test.f"}},
+		{"no Sys.init",
+			map[string]string{
+				"Main.vm": "function Main.main 0\ncall Main.g 0\nreturn\nfunction Main.f 0\nreturn\nfunction Main.g 0\nreturn",
+			},
+			true, []string{"Main.f"}},
+		{"Sys.init in the last file",
+			map[string]string{
+				"A.vm":   "function A.unused 0\nreturn",
+				"Sys.vm": "function Sys.init 0\nlabel L\ngoto L",
+			},
+			true, []string{"A.unused"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, removed := translatePruned(t, tt.srcs, tt.bootstrap)
+
+			var names []string
+			for _, f := range removed {
+				names = append(names, f.Name)
+			}
+			if !reflect.DeepEqual(names, tt.removed) {
+				t.Errorf("removed %v, want %v", names, tt.removed)
+			}
+		})
+	}
+}
+
+func TestPruneOff(t *testing.T) {
+	vm := "function Test.f 0\nreturn\nfunction Test.g 0\nreturn"
+	removed := []RemovedFunction{{Name: "sentinel"}}
+	asm := translateString(t, vm, Options{Removed: &removed})
+
+	if !strings.Contains(strings.Join(asm, "\n"), "(Test.g)") || len(removed) != 1 {
+		t.Errorf("functions removed without Prune")
+	}
+}
@@ -0,0 +1,98 @@
+package translator
+
+// This file contains the statistics of a translated program, and the check of
+// the RAM its variables take.
+
+import (
+	"fmt"
+	"nand2tetris/vm-translator/constants"
+	"strconv"
+	"strings"
+)
+
+// Statistics of a translated program
+type Stats struct {
+	Instructions int // Assembly instructions, labels excluded
+	Labels       int
+	// Symbols the assembler allocates RAM for, from constants.VarBaseAdr, in
+	// the order it does: the static variables ("File.N") of the program's
+	// files. Call targets and return labels of other files are labels, not
+	// variables, and are left out.
+	Variables []string
+}
+
+// Returned when the variables of a program don't fit below the stack, or
+// below the trap words of checked code
+type RAMError struct {
+	Variables []string
+	End       int // First address past the variables' RAM, 0 for the stack base
+}
+
+func (e *RAMError) Error() string {
+	end, what := constants.StackBaseAdr, "the stack base"
+	if e.End != 0 && e.End != constants.StackBaseAdr {
+		end, what = e.End, "the trap words"
+	}
+
+	return fmt.Sprintf("%d variables need RAM[%d-%d], past %s RAM[%d]",
+		len(e.Variables), constants.VarBaseAdr, constants.VarBaseAdr+len(e.Variables)-1, what, end)
+}
+
+// Symbols the assembler knows without a declaration
+var builtinSymbols = map[string]bool{
+	"SP": true, "LCL": true, "ARG": true, "THIS": true, "THAT": true,
+	"SCREEN": true, "KBD": true,
+}
+
+// Counts the instructions, labels and variables of a translated program, whose
+// files have the static labels statics
+func computeStats(bufOut []string, statics map[string]bool) Stats {
+	var stats Stats
+	labels := map[string]bool{}
+
+	for _, code := range bufOut {
+		if strings.HasPrefix(code, "(") {
+			labels[strings.Trim(code, "()")] = true
+			stats.Labels++
+		} else if !strings.HasPrefix(code, "//") {
+			stats.Instructions++
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, code := range bufOut {
+		if !strings.HasPrefix(code, "@") {
+			continue
+		}
+
+		symbol := code[1:]
+		if labels[symbol] || !isStaticOf(symbol, statics) || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		stats.Variables = append(stats.Variables, symbol)
+	}
+
+	return stats
+}
+
+// Returns whether symbol is one of R0-R15
+func isRegister(symbol string) bool {
+	n, err := strconv.Atoi(strings.TrimPrefix(symbol, "R"))
+
+	return strings.HasPrefix(symbol, "R") && err == nil && n >= 0 && n < 16 && symbol == "R"+strconv.Itoa(n)
+}
+
+// Returns a *RAMError if the variables overflow into the stack
+func (s Stats) CheckRAM() error {
+	return s.checkRAM(constants.StackBaseAdr)
+}
+
+// Returns a *RAMError if the variables overflow past address end
+func (s Stats) checkRAM(end int) error {
+	if constants.VarBaseAdr+len(s.Variables) > end {
+		return &RAMError{Variables: s.Variables, End: end}
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package translator
+
+import (
+	"bufio"
+	"io"
+	"nand2tetris/vm-translator/constants"
+	"nand2tetris/vm-translator/helpers"
+	"nand2tetris/vm-translator/parser"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Options struct {
+	// Interleave VM source comments with the output, see EnableAnnotation
+	Annotate bool
+	// If not nil and Annotate is on, receives the VM position of every line
+	// written, in order
+	SourceMap *[]SourcePos
+	// Start with the bootstrap code: set SP to constants.StackBaseAdr and call
+	// Sys.init
+	Bootstrap bool
+	// If not nil, receives the statistics of the output
+	Stats *Stats
+	// Guard every instruction with run-time checks, see EnableChecks. The
+	// variables then have to end below constants.TrapCodeAdr.
+	Checked bool
+	// Drop the functions unreachable from constants.EntryFunction with
+	// Bootstrap, or else from the first instruction of the first file
+	Prune bool
+	// If not nil and Prune is on, receives the functions dropped
+	Removed *[]RemovedFunction
+}
+
+// A VM file read into memory, without comments and blank lines
+type source struct {
+	name  string   // e.g. "Main.vm"
+	buf   []string // Instructions
+	lines []int    // VM line number of every instruction
+}
+
+// Translate the VM files in files, keyed by file name (e.g. "Main.vm"), into a
+// single Hack assembly program written to w. Files are translated in name
+// order. Every file is checked before anything is translated, and all syntax
+// errors are returned together as SyntaxErrors. Other failures are returned as
+// *ReadError, *TranslateError or *WriteError, or *RAMError if the program's
+// variables would overflow into the stack (or the trap words of checked code),
+// in which case nothing is written.
+func Translate(files map[string]io.Reader, w io.Writer, opts Options) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sources []source
+	var syntaxErrs SyntaxErrors
+	for _, name := range names {
+		src, err := readSource(name, files[name])
+		if err != nil {
+			return err
+		}
+
+		syntaxErrs = append(syntaxErrs, parser.ValidateAll(name, src.buf, src.lines)...)
+
+		sources = append(sources, src)
+	}
+
+	if len(syntaxErrs) != 0 {
+		return syntaxErrs
+	}
+
+	if opts.Prune {
+		entry := ""
+		if opts.Bootstrap {
+			entry = constants.EntryFunction
+		}
+
+		removed := prune(sources, entry)
+		if opts.Removed != nil {
+			*opts.Removed = removed
+		}
+	}
+
+	var bufOut []string
+	var srcMap []SourcePos
+
+	tr := Translator{}
+	if opts.Annotate {
+		tr.EnableAnnotation(&srcMap)
+	}
+	if opts.Checked {
+		tr.EnableChecks()
+	}
+
+	if opts.Bootstrap {
+		tr.Setup(&[]string{}, &bufOut, "Bootstrap")
+		tr.bootstrap()
+
+		if opts.Annotate {
+			tr.recordSource(0, SourcePos{})
+		}
+	}
+
+	tr.statics = map[string]bool{}
+	for _, src := range sources {
+		tr.statics[helpers.GetStaticLabel(src.name)] = true
+	}
+
+	for i := range sources {
+		src := &sources[i]
+
+		tr.Setup(&src.buf, &bufOut, helpers.GetStaticLabel(src.name))
+		tr.SetSource(src.name, &src.lines)
+		if err := tr.TranslateAll(); err != nil {
+			return err
+		}
+	}
+
+	if opts.Checked {
+		start := len(bufOut)
+		tr.Setup(&[]string{}, &bufOut, constants.TrapLabel)
+		tr.Traps()
+
+		if opts.Annotate {
+			tr.recordSource(start, SourcePos{})
+		}
+	}
+
+	if opts.Annotate && opts.SourceMap != nil {
+		*opts.SourceMap = srcMap
+	}
+
+	stats := computeStats(bufOut, tr.statics)
+	if opts.Stats != nil {
+		*opts.Stats = stats
+	}
+	ramEnd := constants.StackBaseAdr
+	if opts.Checked {
+		ramEnd = constants.TrapCodeAdr
+	}
+	if err := stats.checkRAM(ramEnd); err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	for _, code := range bufOut {
+		if _, err := out.WriteString(code + "\n"); err != nil {
+			return &WriteError{Err: err}
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return &WriteError{Err: err}
+	}
+
+	return nil
+}
+
+// Read a VM file line-by-line, skipping comments and blank lines
+func readSource(name string, r io.Reader) (source, error) {
+	src := source{name: name}
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		in := scanner.Text()
+		in = helpers.RemoveInlineComments(in)
+		in = strings.TrimSpace(in)
+
+		if len(in) == 0 {
+			continue
+		}
+
+		src.buf = append(src.buf, in)
+		src.lines = append(src.lines, lineNum)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return src, &ReadError{File: name, Err: err}
+	}
+
+	return src, nil
+}
+
+// Point SP at the base of the stack and call Sys.init
+func (tr *Translator) bootstrap() {
+	if tr.annotate {
+		*tr.bufOut = append(*tr.bufOut, "// bootstrap")
+	}
+
+	*tr.bufOut = append(*tr.bufOut, "@"+strconv.Itoa(constants.StackBaseAdr))
+	*tr.bufOut = append(*tr.bufOut, "D=A")
+	*tr.bufOut = append(*tr.bufOut, "@SP")
+	*tr.bufOut = append(*tr.bufOut, "M=D")
+	tr.currentLoc = "SP"
+
+	tr.call(constants.EntryFunction, 0)
+}
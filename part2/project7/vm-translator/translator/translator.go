@@ -1,11 +1,11 @@
 package translator
 
 import (
-	"log"
+	"fmt"
 	"nand2tetris/vm-translator/constants"
-	"nand2tetris/vm-translator/helpers"
 	"nand2tetris/vm-translator/parser"
 	"strconv"
+	"strings"
 )
 
 type Translator struct {
@@ -14,7 +14,29 @@ type Translator struct {
 
 	currentLoc   string // Current @ location
 	staticLabel  string // Label for static variables
-	variableName string // Name of the temporary variable
+	functionName string // Function currently being translated, scopes labels
+	returnCount  int    // Number of calls made so far by the current function
+	uniqueCount  int    // Number of generated labels and variables so far
+
+	declared map[string]bool // Functions and labels declared so far
+	statics  map[string]bool // Static labels of every file of the program
+
+	annotate bool         // Interleave VM source comments with the output
+	srcName  string       // Name of the VM file, e.g. "Main.vm"
+	srcLines *[]int       // VM line number of every instruction in bufIn
+	srcMap   *[]SourcePos // VM position of every line in bufOut
+
+	checked bool // Guard the instructions, see EnableChecks
+	line    int  // VM line of the instruction being translated
+
+	err error // First error met during translation
+}
+
+// Position in the VM source that a translated assembly line came from. Lines
+// that don't come from any VM instruction (e.g. bootstrap code) are left empty.
+type SourcePos struct {
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
 }
 
 // Specify the input buffer where the source instructions are stored, the
@@ -25,13 +47,52 @@ func (tr *Translator) Setup(in *[]string, out *[]string, staticLabel string) {
 	tr.bufIn = in
 	tr.bufOut = out
 	tr.staticLabel = staticLabel
+
+	// Every file starts outside of any function
+	tr.functionName = ""
+	tr.currentLoc = ""
+	tr.srcName = ""
+	tr.srcLines = nil
+}
+
+// Specify the name of the VM file the input buffer was read from, and the VM
+// line number of each instruction in it. Used for annotations and errors.
+func (tr *Translator) SetSource(srcName string, srcLines *[]int) {
+	tr.srcName = srcName
+	tr.srcLines = srcLines
+}
+
+// Turn on annotated output. Every translated block is preceded by a comment
+// holding the VM instruction and its position, e.g.
+// "// push local 2  (Main.vm:17)". srcMap will receive the VM position of each
+// line written to the output buffer.
+func (tr *Translator) EnableAnnotation(srcMap *[]SourcePos) {
+	tr.annotate = true
+	tr.srcMap = srcMap
 }
 
 // Translate all of the instructions stored in the input buffer. Results will be
-// stored in the output buffer.
-func (tr *Translator) TranslateAll() {
-	for _, s := range *tr.bufIn {
-		in := parser.ParseIn(s)
+// stored in the output buffer. Stops at the first instruction that can't be
+// translated and returns a *TranslateError.
+func (tr *Translator) TranslateAll() error {
+	for i, s := range *tr.bufIn {
+		in, err := parser.ParseIn(s)
+		if err != nil {
+			err.File = tr.srcName
+			err.Line = tr.lineOf(i)
+			return err
+		}
+
+		start := len(*tr.bufOut)
+		if tr.annotate {
+			comment := fmt.Sprintf("// %s  (%s:%d)", strings.Join(parser.Tokenize(s), " "), tr.srcName, tr.lineOf(i))
+			*tr.bufOut = append(*tr.bufOut, comment)
+		}
+
+		tr.line = tr.lineOf(i)
+		if tr.checked {
+			tr.checkBefore(in.Operator, in.Segment, in.Dest)
+		}
 
 		switch in.Operator {
 		case "PUSH":
@@ -44,17 +105,77 @@ func (tr *Translator) TranslateAll() {
 			// Fetch data from main stack and then write to target segment and
 			// offset
 
-			if in.Segment != "TEMP" && in.Segment != "STATIC" {
+			if constants.PtrWithOffset[in.Segment] != "@" && in.Segment != "STATIC" {
 				tr.resolveOffsetAdr(in.Segment, in.Dest) // Move to target adr
 				*tr.bufOut = append(*tr.bufOut, "D=A")   // Store the address as data
-				tr.createAsVariable()                    // Address is now stored as @tr.variableName for quick access
+				tr.storePopAdr()                         // Address is now stored in the scratch register
 			}
 
 			tr.fetchFrom("STACK", -1, true)
 			tr.writeTo(in.Segment, in.Dest, true)
+
+		case "LABEL":
+			tr.declare(tr.scopedLabel(in.Symbol))
+
+		case "GOTO":
+			*tr.bufOut = append(*tr.bufOut, "@"+tr.scopedLabel(in.Symbol))
+			*tr.bufOut = append(*tr.bufOut, "0;JMP")
+			tr.currentLoc = ""
+
+		case "IF-GOTO":
+			// Jump if the popped value is anything but false (0)
+			tr.fetchFrom("STACK", -1, true)
+			*tr.bufOut = append(*tr.bufOut, "@"+tr.scopedLabel(in.Symbol))
+			*tr.bufOut = append(*tr.bufOut, "D;JNE")
+			tr.currentLoc = ""
+
+		case "FUNCTION":
+			tr.function(in.Symbol, in.Dest)
+
+		case "CALL":
+			tr.call(in.Symbol, in.Dest)
+
+		case "RETURN":
+			tr.ret()
+
 		default:
 			tr.arithmetic(in.Operator)
 		}
+
+		if tr.err != nil {
+			return &TranslateError{File: tr.srcName, Line: tr.lineOf(i), Err: tr.err}
+		}
+
+		if tr.annotate {
+			tr.recordSource(start, SourcePos{File: tr.srcName, Line: tr.lineOf(i)})
+		}
+	}
+
+	return nil
+}
+
+// Returns the VM line number of the ith instruction of the input buffer, or 0
+// if unknown
+func (tr *Translator) lineOf(i int) int {
+	if tr.srcLines == nil || i >= len(*tr.srcLines) {
+		return 0
+	}
+
+	return (*tr.srcLines)[i]
+}
+
+// Map every output line from start onwards to pos
+func (tr *Translator) recordSource(start int, pos SourcePos) {
+	for j := start; j < len(*tr.bufOut); j++ {
+		*tr.srcMap = append(*tr.srcMap, pos)
+	}
+}
+
+// Record the first error met. The translation stops after the current
+// instruction.
+func (tr *Translator) fail(err error) {
+	if tr.err == nil {
+		tr.err = err
 	}
 }
 
@@ -163,7 +284,7 @@ func (tr *Translator) writeTo(seg string, offset int, writeD bool) {
 		return
 	}
 
-	if seg == "TEMP" {
+	if seg == "TEMP" || seg == "POINTER" {
 		tr.resolveOffsetAdr(seg, offset)
 
 		if writeD {
@@ -173,16 +294,11 @@ func (tr *Translator) writeTo(seg string, offset int, writeD bool) {
 		return
 	}
 
-	// local, arg, this, and that
-	if len(tr.variableName) == 0 {
-		// The regular user of the VM translator probably won't understand this
-		// error
-		log.Fatalln("[!] Variable required for pointer access not found, aborting")
-	}
-
-	if tr.currentLoc != tr.variableName {
-		tr.currentLoc = tr.variableName
-		*tr.bufOut = append(*tr.bufOut, "@"+tr.variableName)
+	// local, arg, this, and that: the resolved address was stored beforehand
+	// by storePopAdr
+	if tr.currentLoc != constants.PopAdrReg {
+		tr.currentLoc = constants.PopAdrReg
+		*tr.bufOut = append(*tr.bufOut, "@"+constants.PopAdrReg)
 	}
 	*tr.bufOut = append(*tr.bufOut, "A=M") // Go to the stored resolved address
 
@@ -198,7 +314,7 @@ func (tr *Translator) arithmetic(operator string) {
 	// Nested function to generate a single pair of condition-jump branch
 	// Nested because it is not used anywhere else, for now
 	generateBranchPair := func(conT string, conF string) {
-		labelName := helpers.GetRandomString(6)
+		labelName := tr.uniqueName(constants.CompareMark)
 		labelNameNot := labelName + "_NOT"
 		labelNameEnd := labelName + "_END"
 
@@ -325,7 +441,8 @@ func (tr *Translator) resolveOffsetAdr(seg string, offset int) {
 				ptr += strconv.Itoa(constants.ThatPtrAdr)
 
 			default:
-				log.Fatalln("[!] Unrecognised pointer offset: ", offset)
+				tr.fail(fmt.Errorf("unrecognised pointer offset %d", offset))
+				return
 			}
 		}
 
@@ -348,14 +465,188 @@ func (tr *Translator) resolveOffsetAdr(seg string, offset int) {
 	*tr.bufOut = append(*tr.bufOut, "A=M+D")
 }
 
-// Create a new variable, and write the D register into its M value. The name of
-// the created variable can be fetched from tr.variableName
-func (tr *Translator) createAsVariable() {
-	tr.variableName = helpers.GetRandomString(6)
-	adr := "@" + tr.variableName
+// Write the D register, the resolved address of a pop, into the scratch
+// register constants.PopAdrReg. The VM reserves R13-R15 for such uses, so pops
+// don't take any RAM of their own.
+func (tr *Translator) storePopAdr() {
+	tr.currentLoc = constants.PopAdrReg
+	*tr.bufOut = append(*tr.bufOut, "@"+constants.PopAdrReg)
+	*tr.bufOut = append(*tr.bufOut, "M=D")
+}
+
+// Returns a new name for a generated label, e.g. "$cmp.Main.3".
+// Names are numbered in order, so the same program always translates to the
+// same output, and start with "$" so they can't clash with VM identifiers,
+// which can't contain it, nor with the labels scoped to functions.
+func (tr *Translator) uniqueName(mark string) string {
+	name := fmt.Sprintf("%s%s.%s.%d", constants.GenMark, mark, tr.staticLabel, tr.uniqueCount)
+	tr.uniqueCount++
+
+	return name
+}
+
+// Labels are scoped to the function they are declared in, i.e. label "LOOP" in
+// function "Main.main" becomes "Main.main$LOOP"
+func (tr *Translator) scopedLabel(label string) string {
+	if len(tr.functionName) == 0 {
+		return label
+	}
+
+	return tr.functionName + constants.LabelSep + label
+}
+
+// Declare a label. As the label can be jumped to from anywhere, nothing is
+// known about the A register afterwards.
+func (tr *Translator) writeLabel(label string) {
+	*tr.bufOut = append(*tr.bufOut, "("+label+")")
+	tr.currentLoc = ""
+}
+
+// Declare a label of the VM code: a function, or a label of a flow command.
+// Names the assembler would take for another symbol are rejected: labels
+// declared twice, its predefined symbols, and the static variables.
+func (tr *Translator) declare(name string) {
+	switch {
+	case tr.declared[name]:
+		tr.fail(fmt.Errorf("label %q declared twice", name))
+	case builtinSymbols[name] || isRegister(name):
+		tr.fail(fmt.Errorf("label %q is a predefined symbol of the assembler", name))
+	case tr.isStatic(name):
+		tr.fail(fmt.Errorf("label %q is the name of a static variable", name))
+	}
+
+	if tr.declared == nil {
+		tr.declared = map[string]bool{}
+	}
+	tr.declared[name] = true
+	tr.writeLabel(name)
+}
+
+// Returns whether name has the form of a static variable, "File.N", of a file
+// of the program
+func (tr *Translator) isStatic(name string) bool {
+	return isStaticOf(name, tr.statics) || isStaticOf(name, map[string]bool{tr.staticLabel: true})
+}
+
+// Returns whether name has the form "File.N", with File one of files
+func isStaticOf(name string, files map[string]bool) bool {
+	i := strings.LastIndex(name, ".")
+	if i < 0 || !files[name[:i]] {
+		return false
+	}
+	_, err := strconv.Atoi(name[i+1:])
+
+	return err == nil
+}
+
+// Declare the function entry point and initialise its local variables to 0
+func (tr *Translator) function(name string, nVars int) {
+	tr.functionName = name
+	tr.returnCount = 0
+
+	tr.declare(name)
+
+	if tr.checked {
+		tr.checkRoom(nVars)
+	}
+	for i := 0; i < nVars; i++ {
+		tr.fetchFrom("CONSTANT", 0, true)
+		tr.writeTo("STACK", -1, true)
+	}
+}
+
+// Save the caller's frame, reposition ARG and LCL for the callee, and jump to
+// it. The callee returns to the label written right after the jump.
+func (tr *Translator) call(name string, nArgs int) {
+	caller := tr.functionName
+	if len(caller) == 0 {
+		caller = tr.staticLabel
+	}
+	retLabel := fmt.Sprintf("%s%s.%s.%d", constants.GenMark, constants.ReturnMark, caller, tr.returnCount)
+	tr.returnCount++
+
+	if tr.checked {
+		tr.checkRoom(constants.FrameSize)
+	}
+
+	// Push return address
+	*tr.bufOut = append(*tr.bufOut, "@"+retLabel)
+	*tr.bufOut = append(*tr.bufOut, "D=A")
+	tr.currentLoc = ""
+	tr.writeTo("STACK", -1, true)
+
+	// Push the caller's LCL, ARG, THIS and THAT
+	for _, ptr := range []string{"LCL", "ARG", "THIS", "THAT"} {
+		*tr.bufOut = append(*tr.bufOut, "@"+ptr)
+		*tr.bufOut = append(*tr.bufOut, "D=M")
+		tr.currentLoc = ptr
+		tr.writeTo("STACK", -1, true)
+	}
+
+	// ARG = SP - 5 - nArgs
+	*tr.bufOut = append(*tr.bufOut, "@SP")
+	*tr.bufOut = append(*tr.bufOut, "D=M")
+	*tr.bufOut = append(*tr.bufOut, "@"+strconv.Itoa(constants.FrameSize+nArgs))
+	*tr.bufOut = append(*tr.bufOut, "D=D-A")
+	*tr.bufOut = append(*tr.bufOut, "@ARG")
+	*tr.bufOut = append(*tr.bufOut, "M=D")
+
+	// LCL = SP
+	*tr.bufOut = append(*tr.bufOut, "@SP")
+	*tr.bufOut = append(*tr.bufOut, "D=M")
+	*tr.bufOut = append(*tr.bufOut, "@LCL")
+	*tr.bufOut = append(*tr.bufOut, "M=D")
+
+	*tr.bufOut = append(*tr.bufOut, "@"+name)
+	*tr.bufOut = append(*tr.bufOut, "0;JMP")
+
+	tr.writeLabel(retLabel)
+}
+
+// Copy the return value to the caller's stack top, restore the caller's frame
+// and jump back to the return address
+func (tr *Translator) ret() {
+	frame := "@" + constants.FrameReg
+	retAdr := "@" + constants.RetAdrReg
+
+	// frame = LCL
+	*tr.bufOut = append(*tr.bufOut, "@LCL")
+	*tr.bufOut = append(*tr.bufOut, "D=M")
+	*tr.bufOut = append(*tr.bufOut, frame)
+	*tr.bufOut = append(*tr.bufOut, "M=D")
+
+	// Return address = *(frame - 5). Fetched first, as the return value may
+	// overwrite it when there are no arguments.
+	*tr.bufOut = append(*tr.bufOut, "@"+strconv.Itoa(constants.FrameSize))
+	*tr.bufOut = append(*tr.bufOut, "A=D-A")
+	*tr.bufOut = append(*tr.bufOut, "D=M")
+	*tr.bufOut = append(*tr.bufOut, retAdr)
+	*tr.bufOut = append(*tr.bufOut, "M=D")
+
+	// *ARG = pop()
+	tr.currentLoc = ""
+	tr.fetchFrom("STACK", -1, true)
+	*tr.bufOut = append(*tr.bufOut, "@ARG")
+	*tr.bufOut = append(*tr.bufOut, "A=M")
+	*tr.bufOut = append(*tr.bufOut, "M=D")
 
-	// No need to check for currentLoc because it surely won't be at the
-	// location you just created
-	*tr.bufOut = append(*tr.bufOut, adr)
+	// SP = ARG + 1
+	*tr.bufOut = append(*tr.bufOut, "@ARG")
+	*tr.bufOut = append(*tr.bufOut, "D=M+1")
+	*tr.bufOut = append(*tr.bufOut, "@SP")
 	*tr.bufOut = append(*tr.bufOut, "M=D")
+
+	// Restore THAT, THIS, ARG and LCL, walking down from the frame
+	for _, ptr := range []string{"THAT", "THIS", "ARG", "LCL"} {
+		*tr.bufOut = append(*tr.bufOut, frame)
+		*tr.bufOut = append(*tr.bufOut, "AM=M-1")
+		*tr.bufOut = append(*tr.bufOut, "D=M")
+		*tr.bufOut = append(*tr.bufOut, "@"+ptr)
+		*tr.bufOut = append(*tr.bufOut, "M=D")
+	}
+
+	*tr.bufOut = append(*tr.bufOut, retAdr)
+	*tr.bufOut = append(*tr.bufOut, "A=M")
+	*tr.bufOut = append(*tr.bufOut, "0;JMP")
+	tr.currentLoc = ""
 }
@@ -0,0 +1,443 @@
+package translator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"nand2tetris/vm-translator/constants"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Translates a single file "Test.vm" and returns the assembly lines
+func translateString(t *testing.T, vm string, opts Options) []string {
+	t.Helper()
+
+	var out bytes.Buffer
+	files := map[string]io.Reader{"Test.vm": strings.NewReader(vm)}
+	if err := Translate(files, &out, opts); err != nil {
+		t.Fatalf("Translate(%q) returned error: %s", vm, err)
+	}
+
+	return strings.Split(strings.TrimSpace(out.String()), "\n")
+}
+
+// Initial RAM used by most tests: stack at 256 and every other segment pointer
+// well apart from each other
+var testRAM = map[int]int{0: 256, 1: 300, 2: 400, 3: 3000, 4: 3010}
+
+func TestTranslatorPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		vm   string
+		want map[int]int // Expected RAM contents after running
+	}{
+		{"push constant", "push constant 7\npush constant 32767",
+			map[int]int{0: 258, 256: 7, 257: 32767}},
+
+		{"push pop local", "push constant 5\npop local 2\npush local 2",
+			map[int]int{0: 257, 302: 5, 256: 5}},
+		{"push pop argument", "push constant 6\npop argument 1\npush argument 1",
+			map[int]int{0: 257, 401: 6, 256: 6}},
+		{"push pop this", "push constant 7\npop this 3\npush this 3",
+			map[int]int{0: 257, 3003: 7, 256: 7}},
+		{"push pop that", "push constant 8\npop that 0\npush that 0",
+			map[int]int{0: 257, 3010: 8, 256: 8}},
+		{"push pop temp", "push constant 9\npop temp 7\npush temp 7",
+			map[int]int{0: 257, 12: 9, 256: 9}},
+		{"push pop pointer", "push constant 4000\npop pointer 0\npush constant 5000\npop pointer 1\npush pointer 0\npush pointer 1",
+			map[int]int{0: 258, 3: 4000, 4: 5000, 256: 4000, 257: 5000}},
+		{"push pop static", "push constant 10\npop static 0\npush constant 11\npop static 1\npush static 0\npush static 1",
+			map[int]int{0: 258, 256: 10, 257: 11}},
+
+		{"add", "push constant 3\npush constant 4\nadd", map[int]int{0: 257, 256: 7}},
+		{"sub", "push constant 3\npush constant 4\nsub", map[int]int{0: 257, 256: -1}},
+		{"neg", "push constant 3\nneg", map[int]int{0: 257, 256: -3}},
+		{"eq true", "push constant 3\npush constant 3\neq", map[int]int{0: 257, 256: -1}},
+		{"eq false", "push constant 3\npush constant 4\neq", map[int]int{0: 257, 256: 0}},
+		{"gt true", "push constant 4\npush constant 3\ngt", map[int]int{0: 257, 256: -1}},
+		{"gt false", "push constant 3\npush constant 3\ngt", map[int]int{0: 257, 256: 0}},
+		{"lt true", "push constant 3\npush constant 4\nlt", map[int]int{0: 257, 256: -1}},
+		{"lt false", "push constant 4\npush constant 3\nlt", map[int]int{0: 257, 256: 0}},
+		{"and", "push constant 12\npush constant 10\nand", map[int]int{0: 257, 256: 8}},
+		{"or", "push constant 12\npush constant 10\nor", map[int]int{0: 257, 256: 14}},
+		{"not", "push constant 0\nnot", map[int]int{0: 257, 256: -1}},
+
+		{"if-goto loop",
+			`push constant 0
+			pop local 0
+			push constant 5
+			pop local 1
+			label LOOP
+			push local 0
+			push local 1
+			add
+			pop local 0
+			push local 1
+			push constant 1
+			sub
+			pop local 1
+			push local 1
+			if-goto LOOP
+			goto END
+			push constant 99
+			label END
+			push local 0`,
+			map[int]int{0: 257, 256: 15, 300: 15, 301: 0}},
+
+		{"keywords are case-insensitive", "PUSH Constant 2\nPush CONSTANT 3\nADD",
+			map[int]int{0: 257, 256: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The guards of checked code change nothing in valid programs
+			for _, opts := range []Options{{}, {Checked: true}} {
+				asm := translateString(t, tt.vm, opts)
+				ram := runHack(t, asm, testRAM, 10000)
+
+				for adr, want := range tt.want {
+					if got := ram[adr]; int(got) != want {
+						t.Errorf("checked %v: RAM[%d] = %d, want %d", opts.Checked, adr, got, want)
+					}
+				}
+				if ram[constants.TrapCodeAdr] != 0 {
+					t.Errorf("checked %v: trap %d at line %d", opts.Checked, ram[constants.TrapCodeAdr], ram[constants.TrapLineAdr])
+				}
+			}
+		})
+	}
+}
+
+func TestTranslatorChecked(t *testing.T) {
+	tests := []struct {
+		name string
+		vm   string
+		ram  map[int]int
+		code int // Expected trap, 0 for none
+		line int
+		// Words the instruction must not write before its check fails
+		kept []int
+	}{
+		{"pop", "push constant 1\npop local 0\npop local 1", nil, constants.TrapUnderflow, 3, nil},
+		{"binary", "push constant 1\nadd", nil, constants.TrapUnderflow, 2, nil},
+		{"unary", "neg", nil, constants.TrapUnderflow, 1, nil},
+		{"if-goto", "label L\nif-goto L", nil, constants.TrapUnderflow, 2, nil},
+		{"return", "function Test.f 0\nreturn", nil, constants.TrapUnderflow, 2, nil},
+		{"call arguments", "push constant 1\ncall Test.f 2\nfunction Test.f 0", nil, constants.TrapUnderflow, 2, nil},
+		{"push into the heap", "push constant 1\npush constant 2", map[int]int{0: 2047, 2048: 7}, constants.TrapOverflow, 2, []int{2048}},
+		{"locals into the heap", "function Test.f 3", map[int]int{0: 2046, 2046: 7, 2047: 7, 2048: 7}, constants.TrapOverflow, 1, []int{2046, 2047, 2048}},
+		{"call into the heap", "call Test.f 0\nfunction Test.f 0", map[int]int{0: 2044, 2044: 7}, constants.TrapOverflow, 1, []int{2044}},
+		{"null this", "push constant 1\npop this 2", map[int]int{3: 0}, constants.TrapNullThis, 2, nil},
+		{"null that", "push that 1", map[int]int{4: 0}, constants.TrapNullThat, 1, nil},
+		{"last stack word", "push constant 1", map[int]int{0: 2047, 2048: 7}, 0, 0, []int{2048}},
+		{"last locals", "function Test.f 2", map[int]int{0: 2046, 2048: 7}, 0, 0, []int{2048}},
+		{"falls off the end", "push constant 1\npop temp 0", nil, 0, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ram := map[int]int{}
+			for adr, v := range testRAM {
+				ram[adr] = v
+			}
+			for adr, v := range tt.ram {
+				ram[adr] = v
+			}
+
+			asm := translateString(t, tt.vm, Options{Checked: true})
+			out := runHack(t, asm, ram, 1000)
+
+			if code, line := out[constants.TrapCodeAdr], out[constants.TrapLineAdr]; int(code) != tt.code || int(line) != tt.line {
+				t.Errorf("trap %d at line %d, want %d at line %d", code, line, tt.code, tt.line)
+			}
+			for _, adr := range tt.kept {
+				if int(out[adr]) != ram[adr] {
+					t.Errorf("RAM[%d] = %d, want %d", adr, out[adr], ram[adr])
+				}
+			}
+		})
+	}
+}
+
+func TestTranslatorFunctions(t *testing.T) {
+	srcs := map[string]string{
+		"Sys.vm": `
+			function Sys.init 0
+			push constant 4
+			call Main.fibonacci 1
+			label WHILE
+			goto WHILE`,
+		"Main.vm": `
+			function Main.fibonacci 0
+			push argument 0
+			push constant 2
+			lt
+			if-goto IF_TRUE
+			goto IF_FALSE
+			label IF_TRUE
+			push argument 0
+			return
+			label IF_FALSE
+			push argument 0
+			push constant 2
+			sub
+			call Main.fibonacci 1
+			push argument 0
+			push constant 1
+			sub
+			call Main.fibonacci 1
+			add
+			return`,
+	}
+
+	for _, opts := range []Options{{Bootstrap: true}, {Bootstrap: true, Checked: true}} {
+		files := map[string]io.Reader{}
+		for name, src := range srcs {
+			files[name] = strings.NewReader(src)
+		}
+
+		var out bytes.Buffer
+		if err := Translate(files, &out, opts); err != nil {
+			t.Fatalf("Translate returned error: %s", err)
+		}
+
+		asm := strings.Split(strings.TrimSpace(out.String()), "\n")
+		ram := runHack(t, asm, nil, 10000)
+
+		// Same as the course's FibonacciElement.cmp
+		if ram[0] != 262 || ram[261] != 3 {
+			t.Errorf("checked %v: RAM[0] = %d, RAM[261] = %d, want 262 and 3", opts.Checked, ram[0], ram[261])
+		}
+		if ram[constants.TrapCodeAdr] != 0 {
+			t.Errorf("checked %v: trap %d at line %d", opts.Checked, ram[constants.TrapCodeAdr], ram[constants.TrapLineAdr])
+		}
+	}
+}
+
+func TestTranslatorLocalsAreZeroed(t *testing.T) {
+	vm := `function Test.f 3
+		push local 0
+		push local 1
+		push local 2
+		add
+		add`
+
+	asm := translateString(t, vm, Options{})
+	// Pretend Test.f was called with garbage on the stack
+	ram := runHack(t, asm, map[int]int{0: 256, 1: 256, 256: 7, 257: 7, 258: 7}, 1000)
+
+	if ram[0] != 260 || ram[259] != 0 {
+		t.Errorf("RAM[0] = %d, RAM[259] = %d, want 260 and 0", ram[0], ram[259])
+	}
+}
+
+func TestTranslatorLabelsAreCaseSensitive(t *testing.T) {
+	vm := "function Main.main 0\nlabel loop\nlabel Loop\ngoto loop"
+	asm := strings.Join(translateString(t, vm, Options{}), "\n")
+
+	for _, want := range []string{"(Main.main)", "(Main.main$loop)", "(Main.main$Loop)", "@Main.main$loop"} {
+		if !strings.Contains(asm, want) {
+			t.Errorf("output does not contain %q:\n%s", want, asm)
+		}
+	}
+}
+
+func TestTranslatorAnnotate(t *testing.T) {
+	var srcMap []SourcePos
+	vm := "// comment\npush constant 1\n\npush   constant 2 // two\nadd"
+	asm := translateString(t, vm, Options{Annotate: true, SourceMap: &srcMap})
+
+	wantComments := map[string]int{
+		"// push constant 1  (Test.vm:2)": 2,
+		"// push constant 2  (Test.vm:4)": 4,
+		"// add  (Test.vm:5)":             5,
+	}
+	for i, line := range asm {
+		if !strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		vmLine, found := wantComments[line]
+		if !found {
+			t.Errorf("unexpected comment %q", line)
+			continue
+		}
+		delete(wantComments, line)
+
+		if srcMap[i].Line != vmLine || srcMap[i].File != "Test.vm" {
+			t.Errorf("source map for %q = %+v, want Test.vm:%d", line, srcMap[i], vmLine)
+		}
+	}
+	for comment := range wantComments {
+		t.Errorf("missing comment %q", comment)
+	}
+
+	if len(srcMap) != len(asm) {
+		t.Errorf("source map has %d entries, want one per line (%d)", len(srcMap), len(asm))
+	}
+}
+
+func TestTranslatorIsDeterministic(t *testing.T) {
+	vm := "push constant 1\npush constant 2\neq\npush constant 3\npop local 0"
+
+	first := strings.Join(translateString(t, vm, Options{}), "\n")
+	second := strings.Join(translateString(t, vm, Options{}), "\n")
+
+	if first != second {
+		t.Errorf("translations differ:\n%s\n---\n%s", first, second)
+	}
+}
+
+func TestTranslateSyntaxErrors(t *testing.T) {
+	files := map[string]io.Reader{
+		"A.vm": strings.NewReader("push constant 1\npsuh constant 2\npop constant 3"),
+		"B.vm": strings.NewReader("push temp 9"),
+	}
+
+	err := Translate(files, io.Discard, Options{})
+
+	var syntaxErrs SyntaxErrors
+	if !errors.As(err, &syntaxErrs) {
+		t.Fatalf("Translate returned %v, want SyntaxErrors", err)
+	}
+
+	want := []string{"A.vm:2", "A.vm:3", "B.vm:1"}
+	if len(syntaxErrs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %s", len(syntaxErrs), len(want), err)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(syntaxErrs[i].Error(), prefix+":") {
+			t.Errorf("error %d = %q, want prefix %q", i, syntaxErrs[i], prefix)
+		}
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("disk on fire")
+}
+
+func TestTranslateReadError(t *testing.T) {
+	files := map[string]io.Reader{"A.vm": failingReader{}}
+
+	err := Translate(files, io.Discard, Options{})
+
+	var readErr *ReadError
+	if !errors.As(err, &readErr) || readErr.File != "A.vm" {
+		t.Errorf("Translate returned %v, want *ReadError for A.vm", err)
+	}
+}
+
+// Pops go through a scratch register, so they take no RAM however many there
+// are
+func TestTranslatePopsTakeNoRAM(t *testing.T) {
+	var vm strings.Builder
+	vm.WriteString("function Test.f 0\n")
+	for i := 0; i < 5000; i++ {
+		for _, seg := range []string{"local", "argument", "this", "that"} {
+			vm.WriteString("push constant 1\npop " + seg + " 3\n")
+		}
+	}
+	vm.WriteString("push constant 2\npop static 0\npush constant 3\npop static 7\n")
+
+	var stats Stats
+	translateString(t, vm.String(), Options{Stats: &stats})
+
+	if len(stats.Variables) != 2 || stats.Variables[0] != "Test.0" || stats.Variables[1] != "Test.7" {
+		t.Errorf("variables = %v, want the 2 statics", stats.Variables)
+	}
+	if stats.Labels != 1 || stats.Instructions == 0 {
+		t.Errorf("stats = %d instructions, %d labels", stats.Instructions, stats.Labels)
+	}
+}
+
+// Functions of other files, such as the OS, are labels the assembler finds
+// when the program is linked, not variables
+func TestTranslateStatsCallTargets(t *testing.T) {
+	vm := "function Main.main 0\npush constant 2\npush constant 3\ncall Math.multiply 2\n" +
+		"pop static 0\ncall Sys.halt 0\nreturn\n"
+
+	var stats Stats
+	translateString(t, vm, Options{Stats: &stats})
+
+	if len(stats.Variables) != 1 || stats.Variables[0] != "Test.0" {
+		t.Errorf("variables = %v, want [Test.0]", stats.Variables)
+	}
+}
+
+func TestTranslateRAMCheck(t *testing.T) {
+	statics := func(n int) string {
+		var vm strings.Builder
+		for i := 0; i < n; i++ {
+			vm.WriteString("push static " + strconv.Itoa(i) + "\n")
+		}
+		return vm.String()
+	}
+
+	// RAM[16-255] holds 240 variables
+	var stats Stats
+	translateString(t, statics(240), Options{Stats: &stats})
+	if len(stats.Variables) != 240 {
+		t.Errorf("got %d variables, want 240", len(stats.Variables))
+	}
+
+	var out bytes.Buffer
+	files := map[string]io.Reader{"Test.vm": strings.NewReader(statics(241))}
+	err := Translate(files, &out, Options{})
+
+	var ramErr *RAMError
+	if !errors.As(err, &ramErr) || len(ramErr.Variables) != 241 {
+		t.Fatalf("Translate returned %v, want *RAMError", err)
+	}
+	if out.Len() != 0 {
+		t.Error("output written despite the error")
+	}
+	if want := "241 variables need RAM[16-256], past the stack base RAM[256]"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+
+	// Checked code keeps RAM[254-255] for its traps
+	translateString(t, statics(238), Options{Checked: true})
+	files = map[string]io.Reader{"Test.vm": strings.NewReader(statics(239))}
+	err = Translate(files, &out, Options{Checked: true})
+	if want := "239 variables need RAM[16-254], past the trap words RAM[254]"; err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+}
+
+func TestTranslateLabelClashes(t *testing.T) {
+	// Generated labels can't clash with the labels of the VM code
+	vm := "function Main 0\nlabel cmp.0\nlabel ret.0\npush constant 1\npush constant 1\neq\ncall Main 0\nlabel Trap\n"
+	declared := map[string]bool{}
+	for _, line := range translateString(t, vm, Options{Checked: true}) {
+		if strings.HasPrefix(line, "(") {
+			if declared[line] {
+				t.Errorf("label %s declared twice", line)
+			}
+			declared[line] = true
+		}
+	}
+
+	tests := []struct {
+		vm   string
+		want string
+	}{
+		{"label SP", `Test.vm:1: label "SP" is a predefined symbol of the assembler`},
+		{"label R15", `Test.vm:1: label "R15" is a predefined symbol of the assembler`},
+		{"label L\nlabel L", `Test.vm:2: label "L" declared twice`},
+		{"function F 0\nlabel L\nlabel L", `Test.vm:3: label "F$L" declared twice`},
+		{"label Test.f\nfunction Test.f 0", `Test.vm:2: label "Test.f" declared twice`},
+		{"function Test.0 0", `Test.vm:1: label "Test.0" is the name of a static variable`},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		files := map[string]io.Reader{"Test.vm": strings.NewReader(tt.vm)}
+		if err := Translate(files, &out, Options{}); err == nil || err.Error() != tt.want {
+			t.Errorf("Translate(%q) error = %v, want %q", tt.vm, err, tt.want)
+		}
+	}
+}